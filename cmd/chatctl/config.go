@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// Config is read from a JSON file:
+//
+//	{"url": "http://localhost:8080", "token": "..."}
+//
+// CHATCTL_URL and CHATCTL_TOKEN override the file, and the -url and -token
+// flags override both.
+type Config struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/chatctl/config.json or its platform
+// equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chatctl", "config.json")
+}
+
+// loadConfig reads path, which may be missing unless explicit is set, and
+// applies the environment on top.
+func loadConfig(path string, explicit bool) (*Config, error) {
+	cfg := Config{URL: defaultURL}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+			}
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		default:
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	if url := os.Getenv("CHATCTL_URL"); url != "" {
+		cfg.URL = url
+	}
+	if token := os.Getenv("CHATCTL_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+
+	return &cfg, nil
+}
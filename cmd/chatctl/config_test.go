@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"url": "http://chat.example", "token": "file"}`), 0o600))
+
+	cfg, err := loadConfig(path, true)
+	require.NoError(t, err)
+	assert.Equal(t, Config{URL: "http://chat.example", Token: "file"}, *cfg)
+
+	t.Setenv("CHATCTL_TOKEN", "env")
+	cfg, err = loadConfig(path, true)
+	require.NoError(t, err)
+	assert.Equal(t, "env", cfg.Token)
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+
+	cfg, err = loadConfig(missing, false)
+	require.NoError(t, err)
+	assert.Equal(t, defaultURL, cfg.URL)
+
+	_, err = loadConfig(missing, true)
+	assert.Error(t, err)
+}
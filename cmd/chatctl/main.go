@@ -0,0 +1,223 @@
+// Command chatctl manages chats and messages through the HTTP API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/AlGrushino/chat/pkg/client"
+)
+
+const usage = `Usage: chatctl [flags] <command> [arguments]
+
+Commands:
+  chats list [-limit N] [-offset N]   list chats
+  chats create <title>                create a group chat
+  chats delete <id>                   delete a chat
+  send <chat-id> [text]               send a message; reads stdin without text or with "-"
+  tail [-n N] <chat-id>               print the latest messages and follow new ones
+
+Flags:
+`
+
+// errUsage makes main print the usage and exit with status 2.
+var errUsage = errors.New("invalid usage")
+
+type app struct {
+	client *client.Client
+	out    *printer
+	stdin  io.Reader
+}
+
+func main() {
+	flags := flag.NewFlagSet("chatctl", flag.ExitOnError)
+	configPath := flags.String("config", "", "config file (default "+defaultConfigPath()+")")
+	url := flags.String("url", "", "server URL, overrides the config file")
+	token := flags.String("token", "", "API token, overrides the config file")
+	format := flags.String("o", formatTable, "output format: table or json")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "chatctl: unknown output format %q\n", *format)
+		os.Exit(2)
+	}
+
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	cfg, err := loadConfig(path, explicit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chatctl:", err)
+		os.Exit(1)
+	}
+	if *url != "" {
+		cfg.URL = *url
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+
+	c, err := client.New(cfg.URL, client.WithToken(cfg.Token))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chatctl:", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a := &app{
+		client: c,
+		out:    &printer{out: os.Stdout, format: *format},
+		stdin:  os.Stdin,
+	}
+
+	if err := a.run(ctx, flags.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "chatctl:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "chats":
+		if len(args) < 2 {
+			return errUsage
+		}
+		switch args[1] {
+		case "list":
+			return a.listChats(ctx, args[2:])
+		case "create":
+			return a.createChat(ctx, args[2:])
+		case "delete":
+			return a.deleteChat(ctx, args[2:])
+		}
+	case "send":
+		return a.send(ctx, args[1:])
+	case "tail":
+		return a.tail(ctx, args[1:])
+	}
+
+	return errUsage
+}
+
+func (a *app) listChats(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("chats list", flag.ContinueOnError)
+	limit := flags.Int("limit", 0, "maximum number of chats (server default when 0)")
+	offset := flags.Int("offset", 0, "number of chats to skip")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	chats, err := a.client.ListChats(ctx, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	return a.out.chats(chats)
+}
+
+func (a *app) createChat(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	chat, err := a.client.CreateChat(ctx, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	return a.out.chat(chat)
+}
+
+func (a *app) deleteChat(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %q", args[0])
+	}
+
+	return a.client.DeleteChat(ctx, id)
+}
+
+func (a *app) send(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %q", args[0])
+	}
+
+	text := strings.Join(args[1:], " ")
+	if text == "" || text == "-" {
+		data, err := io.ReadAll(a.stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		text = strings.TrimRight(string(data), "\n")
+	}
+
+	msg, err := a.client.AddMessage(ctx, id, client.NewMessage{Text: text})
+	if err != nil {
+		return err
+	}
+	return a.out.message(msg)
+}
+
+func (a *app) tail(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
+	n := flags.Int("n", 10, "number of earlier messages to print first")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid chat ID %q", flags.Arg(0))
+	}
+
+	if *n > 0 {
+		history, err := a.client.GetMessages(ctx, id, *n)
+		if err != nil {
+			return err
+		}
+		if err := a.out.history(history); err != nil {
+			return err
+		}
+	}
+
+	err = a.client.Subscribe(ctx, id, func(msg client.LiveMessage) {
+		if err := a.out.live(msg); err != nil {
+			fmt.Fprintln(os.Stderr, "chatctl:", err)
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlGrushino/chat/pkg/client"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p *printer) json(v any) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// table writes rows under header with aligned columns.
+func (p *printer) table(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func (p *printer) chats(chats []client.Chat) error {
+	if p.format == formatJSON {
+		return p.json(chats)
+	}
+
+	rows := make([][]string, 0, len(chats))
+	for _, chat := range chats {
+		title := chat.Title
+		if chat.Peer != nil {
+			title = "@" + chat.Peer.Username
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(chat.ID),
+			chat.Type,
+			title,
+			chat.Topic,
+			chat.CreatedAt.Local().Format(time.DateTime),
+		})
+	}
+	return p.table([]string{"ID", "TYPE", "TITLE", "TOPIC", "CREATED"}, rows)
+}
+
+func (p *printer) chat(chat *client.CreateChatResponse) error {
+	if p.format == formatJSON {
+		return p.json(chat)
+	}
+	return p.table([]string{"ID", "TITLE"}, [][]string{{strconv.Itoa(chat.ID), chat.Title}})
+}
+
+func (p *printer) message(msg *client.Message) error {
+	if p.format == formatJSON {
+		return p.json(msg)
+	}
+
+	if msg.Ephemeral {
+		_, err := fmt.Fprintln(p.out, msg.Text)
+		return err
+	}
+	return p.table([]string{"ID", "TEXT"}, [][]string{{strconv.Itoa(msg.ID), msg.Text}})
+}
+
+// history prints the texts returned by GetMessages, which carry no metadata.
+func (p *printer) history(texts []string) error {
+	if p.format == formatJSON {
+		for _, text := range texts {
+			if err := json.NewEncoder(p.out).Encode(map[string]string{"text": text}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, text := range texts {
+		if _, err := fmt.Fprintln(p.out, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// live prints one streamed message per line; JSON output is one object per
+// line so it can be piped.
+func (p *printer) live(msg client.LiveMessage) error {
+	if p.format == formatJSON {
+		return json.NewEncoder(p.out).Encode(msg)
+	}
+
+	_, err := fmt.Fprintf(p.out, "%s  %s: %s\n", msg.CreatedAt.Local().Format(time.TimeOnly), msg.Sender(), msg.Text)
+	return err
+}
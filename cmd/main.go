@@ -2,23 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"expvar"
 	"flag"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/AlGrushino/chat/internal/handlers"
 	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/rpc"
 	"github.com/AlGrushino/chat/internal/service"
+	"github.com/AlGrushino/chat/internal/service/idempotency"
+	"github.com/AlGrushino/chat/internal/service/importer"
+	"github.com/AlGrushino/chat/internal/service/pin"
+	"github.com/AlGrushino/chat/internal/service/retention"
 	"github.com/AlGrushino/chat/pkg/db"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
+	"github.com/AlGrushino/chat/pkg/storage"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
 	migrateOnly := flag.Bool("migrate", false, "Run migrations only and exit")
+	importPath := flag.String("import", "", "Import chats from a JSON archive or a Slack export (directory or zip) and exit")
+	importUsers := flag.String("import-users", "", "JSON object mapping archive user IDs or names to usernames, used with -import")
+	dryRun := flag.Bool("dry-run", false, "With -import, report what would be imported without writing anything")
 	flag.Parse()
 
 	log := logrus.New()
@@ -69,20 +84,80 @@ func main() {
 		return
 	}
 
+	if *importPath != "" {
+		if err := runImport(log, repository.NewRepository(gormDB), *importPath, *importUsers, *dryRun); err != nil {
+			log.Error("Import failed: ", err)
+			fmt.Fprintln(os.Stderr, "Import failed:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	store, err := storage.NewLocalStore(log, storage.GetConfig(log))
+	if err != nil {
+		log.Fatal("Failed to init blob storage:", err)
+	}
+
+	rateLimits := ratelimit.GetConfig(log)
+	limiter, err := ratelimit.NewLimiter(log, rateLimits, gormDB)
+	if err != nil {
+		log.Fatal("Failed to init rate limiter:", err)
+	}
+
 	repo := repository.NewRepository(gormDB)
-	svc := service.NewService(log, repo)
+	svc := service.NewService(log, repo, store, limiter)
+	svc.Idempotency.SetTTL(idempotency.GetConfig(log).TTL)
+	svc.Retention.SetConfig(retention.GetConfig(log))
+	svc.Pins.SetMaxPins(pin.GetConfig(log).MaxPins)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+	workers.Go(func() { svc.Thumbnails.Run(workerCtx) })
+	workers.Go(func() { svc.Webhooks.Run(workerCtx) })
+	workers.Go(func() { svc.Idempotency.Run(workerCtx) })
+	workers.Go(func() { svc.Exports.Run(workerCtx) })
+	workers.Go(func() { svc.Retention.Run(workerCtx) })
+	workers.Go(func() { svc.Sweeper.Run(workerCtx) })
+	workers.Go(func() { svc.Scheduler.Run(workerCtx) })
 	handler := handlers.NewHandler(svc, log)
 
+	handler.SetRateLimits(rateLimits.Routes)
 	handler.InitRoutes()
 
 	server := &http.Server{
 		Addr:         ":8080",
-		Handler:      handler.GetMux(),
+		Handler:      handler.Router(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	grpcCfg := rpc.GetConfig(log)
+	grpcServer := rpc.NewServer(svc, log)
+
+	grpcListener, err := net.Listen("tcp", grpcCfg.Addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", grpcCfg.Addr, err)
+	}
+
+	// Metrics are served on a separate, typically internal, address.
+	var metricsServer *http.Server
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /debug/vars", expvar.Handler())
+		metricsServer = &http.Server{Addr: addr, Handler: metricsMux}
+
+		go func() {
+			log.WithField("address", addr).Info("Metrics server starting")
+
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+	}
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
@@ -99,6 +174,14 @@ func main() {
 		}
 	}()
 
+	go func() {
+		log.WithField("address", grpcCfg.Addr).Info("gRPC server starting")
+
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("gRPC server failed to start: %v", err)
+		}
+	}()
+
 	sig := <-stop
 	log.WithField("signal", sig.String()).Info("Shutdown signal received")
 
@@ -117,5 +200,61 @@ func main() {
 	}
 
 	log.Info("HTTP server stopped successfully")
+
+	log.Info("Shutting down gRPC server gracefully...")
+
+	// GracefulStop waits for open streams such as Subscribe, which only end
+	// when the client goes away, so it gets the rest of the shutdown budget.
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		log.Warn("Forcing gRPC server closure...")
+		grpcServer.Stop()
+	}
+
+	log.Info("gRPC server stopped successfully")
+
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Errorf("Metrics server shutdown error: %v", err)
+		}
+	}
+
+	log.Info("Stopping background workers...")
+	stopWorkers()
+	workers.Wait()
 	log.Info("Application shutdown complete")
 }
+
+// runImport imports an archive and prints the summary to stdout. The log goes
+// to a file, so this is what the operator sees.
+func runImport(log *logrus.Logger, repo *repository.Repository, path, usersPath string, dryRun bool) error {
+	archive, err := importer.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	opts := importer.Options{DryRun: dryRun}
+	if usersPath != "" {
+		data, err := os.ReadFile(usersPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &opts.UserMap); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", usersPath, err)
+		}
+	}
+
+	summary, err := importer.NewImporter(log, repo).Import(context.Background(), archive, opts)
+	if err != nil {
+		return err
+	}
+
+	return summary.Write(os.Stdout)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+)
+
+var ErrUnauthenticated = errors.New("authentication required")
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the authenticated user, or nil for anonymous requests.
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(contextKey{}).(*models.User)
+	return user
+}
+
+type membership interface {
+	IsMember(ctx context.Context, chatID, userID int) (bool, error)
+}
+
+// CanAccess reports whether the user in ctx may read and post in chat.
+// Public group chats are open to everyone, private ones to their owner and
+// members, and direct chats only to their participants.
+func CanAccess(ctx context.Context, members membership, chat *models.Chat) (bool, error) {
+	if !chat.IsDirect() && chat.Visibility != models.VisibilityPrivate {
+		return true, nil
+	}
+
+	user := UserFromContext(ctx)
+	if user == nil {
+		return false, nil
+	}
+
+	if chat.OwnerID != nil && *chat.OwnerID == user.ID {
+		return true, nil
+	}
+
+	return members.IsMember(ctx, chat.ID, user.ID)
+}
+
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MessageCreated = "message.created"
+	MessageExpired = "message.expired"
+	ChatCreated    = "chat.created"
+	ChatUpdated    = "chat.updated"
+	ChatDeleted    = "chat.deleted"
+)
+
+type Event struct {
+	Type       string
+	Chat       *models.Chat
+	Message    *models.Message
+	OccurredAt time.Time
+}
+
+// Handler is called synchronously by Publish. It must not block for long:
+// it runs inside the request that produced the event.
+type Handler func(ctx context.Context, event Event)
+
+// Bus fans domain events out to in-process consumers. Handlers see every
+// event before Publish returns; subscribers get them over a buffered channel
+// and miss events while their buffer is full.
+type Bus struct {
+	mu          sync.RWMutex
+	handlers    []Handler
+	subscribers map[int]chan Event
+	nextID      int
+	log         *logrus.Logger
+}
+
+func NewBus(log *logrus.Logger) *Bus {
+	return &Bus{
+		subscribers: make(map[int]chan Event),
+		log:         log,
+	}
+}
+
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+// Subscribe returns a channel of future events and a function that stops
+// the subscription and closes the channel.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers, id)
+			close(ch)
+		})
+	}
+}
+
+// Publish is safe to call on a nil Bus, which drops the event.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.log.Warnf("Event subscriber %d is too slow, dropping %s event", id, event.Type)
+		}
+	}
+}
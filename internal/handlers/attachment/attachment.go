@@ -0,0 +1,181 @@
+package attachment
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	attachmentService "github.com/AlGrushino/chat/internal/service/attachment"
+	"github.com/sirupsen/logrus"
+)
+
+type Attachment struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewAttachment(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Attachment {
+	return &Attachment{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Attachment) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	// Leave some room for the multipart envelope around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, attachmentService.MaxUploadSize+1<<20)
+	defer r.Body.Close()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			log.WithError(err).Warn("Attachment too large")
+			http.Error(w, "Attachment is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.WithError(err).Warn("Invalid multipart form")
+		http.Error(w, "Invalid multipart form: file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	log = log.WithField("filename", header.Filename)
+
+	attachment, err := h.service.Attachment.Upload(r.Context(), id, header.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachmentService.ErrChatNotFound):
+			log.WithError(err).Error("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		case errors.Is(err, attachmentService.ErrTooLarge):
+			log.WithError(err).Warn("Attachment too large")
+			http.Error(w, "Attachment is too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, attachmentService.ErrImageTooLarge):
+			log.WithError(err).Warn("Image too large")
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, attachmentService.ErrEmpty):
+			log.WithError(err).Warn("Attachment is empty")
+			http.Error(w, "Attachment is empty", http.StatusBadRequest)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to upload attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.UploadAttachmentResponse{
+		Status:     "success",
+		Attachment: models.NewAttachment(attachment),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Attachment) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	h.serveBlob(w, r, false)
+}
+
+func (h *Attachment) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveBlob(w, r, true)
+}
+
+func (h *Attachment) serveBlob(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	rc, contentType, err := h.service.Attachment.Open(r.Context(), id, thumbnail)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachmentService.ErrNotFound):
+			log.WithError(err).Warn("Attachment does not exist")
+			http.Error(w, "Attachment does not exist", http.StatusNotFound)
+		case errors.Is(err, attachmentService.ErrThumbnailNotReady):
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Thumbnail is not ready yet", http.StatusNotFound)
+		case errors.Is(err, attachmentService.ErrNoThumbnail):
+			http.Error(w, "Attachment has no thumbnail", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to get attachment", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if !strings.HasPrefix(contentType, "image/") {
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+
+	if _, err := io.Copy(w, rc); err != nil {
+		log.WithError(err).Error("Failed to write attachment")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	botService "github.com/AlGrushino/chat/internal/service/bot"
+	userService "github.com/AlGrushino/chat/internal/service/user"
+	"github.com/sirupsen/logrus"
+)
+
+type Bot struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewBot(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Bot {
+	return &Bot{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Bot) CreateBot(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CreateBot
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	log = log.WithField("username", req.Username)
+
+	commands := make([]botService.NewCommand, 0, len(req.Commands))
+	for _, c := range req.Commands {
+		commands = append(commands, botService.NewCommand{Name: c.Name, Description: c.Description})
+	}
+
+	bot, token, err := h.service.Bot.CreateBot(r.Context(), req.Username, req.CallbackURL, commands)
+	if err != nil {
+		switch {
+		case errors.Is(err, userService.ErrInvalidUsername),
+			errors.Is(err, botService.ErrInvalidURL),
+			errors.Is(err, botService.ErrInvalidCommands):
+			log.WithError(err).Warn("Invalid bot")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, botService.ErrBotOwner):
+			log.WithError(err).Warn("Bot tried to create a bot")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, userService.ErrUsernameTaken), errors.Is(err, botService.ErrCommandTaken):
+			log.WithError(err).Warn("Bot conflicts with an existing one")
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create bot", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.CreateBotResponse{
+		Status:         "success",
+		Bot:            models.NewBot(bot.User, bot.Commands),
+		Token:          token,
+		CallbackSecret: *bot.User.CallbackSecret,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Bot) ListBots(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bots, err := h.service.Bot.ListBots(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list bots", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]models.Bot, 0, len(bots))
+	for _, bot := range bots {
+		items = append(items, models.NewBot(bot.User, bot.Commands))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListBotsResponse{
+		Status: "success",
+		Bots:   items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Bot) DeleteBot(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid bot ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Bot.DeleteBot(r.Context(), id); err != nil {
+		if errors.Is(err, botService.ErrNotFound) {
+			log.WithError(err).Warn("Bot does not exist")
+			http.Error(w, "Bot does not exist", http.StatusNotFound)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to delete bot", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
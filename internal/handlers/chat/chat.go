@@ -2,12 +2,16 @@ package chat
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/AlGrushino/chat/internal/auth"
 	"github.com/AlGrushino/chat/internal/handlers/models"
 	"github.com/AlGrushino/chat/internal/service"
+	chatService "github.com/AlGrushino/chat/internal/service/chat"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
 	"github.com/sirupsen/logrus"
 )
 
@@ -53,10 +57,19 @@ func (h *Chat) CreateChat(w http.ResponseWriter, r *http.Request) {
 
 	log = log.WithField("title", req.Title)
 
-	title, err := h.service.Chat.CreateChat(r.Context(), req.Title)
+	chat, err := h.service.Chat.CreateChat(r.Context(), req.Title)
 	if err != nil {
-		log.WithError(err).Error("Service error")
-		http.Error(w, "Failed to create chat", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, chatService.ErrInvalidTitle):
+			log.WithError(err).Warn("Invalid chat title")
+			http.Error(w, chatService.ErrInvalidTitle.Error(), http.StatusBadRequest)
+		case errors.Is(err, chatService.ErrChatExists):
+			log.WithError(err).Warn("Chat already exists")
+			http.Error(w, "Chat already exists", http.StatusConflict)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create chat", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -65,7 +78,8 @@ func (h *Chat) CreateChat(w http.ResponseWriter, r *http.Request) {
 
 	resp := models.CreateChatReposnse{
 		Status: "success",
-		Title:  title,
+		ID:     chat.ID,
+		Title:  chat.Title,
 	}
 
 	encoder := json.NewEncoder(w)
@@ -106,6 +120,11 @@ func (h *Chat) DeleteChat(w http.ResponseWriter, r *http.Request) {
 
 	err = h.service.DeleteChat(r.Context(), id)
 	if err != nil {
+		if err.Error() == "chat does not exist" {
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+			return
+		}
 		log.WithError(err).Warn("Failed to delete chat")
 		http.Error(w, "Failed to delete chat", http.StatusInternalServerError)
 		return
@@ -115,3 +134,549 @@ func (h *Chat) DeleteChat(w http.ResponseWriter, r *http.Request) {
 
 	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
 }
+
+func (h *Chat) ListChats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset := 0, 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		var err error
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			log.WithError(err).Warn("Invalid offset parameter")
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summaries, err := h.service.Chat.ListChats(r.Context(), limit, offset)
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list chats", http.StatusInternalServerError)
+		return
+	}
+
+	chats := make([]models.ChatInfo, 0, len(summaries))
+	for _, summary := range summaries {
+		chats = append(chats, models.NewChatInfo(summary.Chat, summary.Peer))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListChatsResponse{
+		Status: "success",
+		Chats:  chats,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) CreateDirect(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDStr := r.PathValue("userID")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		log.WithError(err).Warn("Invalid user ID")
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	log = log.WithField("peer_id", userID)
+
+	summary, created, err := h.service.Chat.CreateDirect(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrUserNotFound):
+			log.WithError(err).Warn("User does not exist")
+			http.Error(w, "User does not exist", http.StatusNotFound)
+		case errors.Is(err, chatService.ErrSelfDirect):
+			log.WithError(err).Warn("Direct chat with self")
+			http.Error(w, "Cannot start a direct chat with yourself", http.StatusBadRequest)
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create direct chat", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := models.CreateDirectResponse{
+		Status:  "success",
+		Created: created,
+		Chat:    models.NewChatInfo(summary.Chat, summary.Peer),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) SetSlowMode(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPut {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetSlowMode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	chat, err := h.service.Chat.SetSlowMode(r.Context(), id, req.Seconds)
+	if err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrInvalidSlowMode):
+			log.WithError(err).Warn("Invalid slow mode")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, chatService.ErrNotOwner):
+			log.WithError(err).Warn("Not the chat owner")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to set slow mode", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ChatResponse{
+		Status: "success",
+		Chat:   models.NewChatInfo(chat, nil),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) SetRetention(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPut {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetRetention
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	chat, err := h.service.Chat.SetRetention(r.Context(), id, req.Days)
+	if err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrInvalidRetention):
+			log.WithError(err).Warn("Invalid retention")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, chatService.ErrNotOwner):
+			log.WithError(err).Warn("Not the chat owner")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to set retention", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ChatResponse{
+		Status: "success",
+		Chat:   models.NewChatInfo(chat, nil),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) UpdateChat(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPatch {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateChat
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	chat, err := h.service.Chat.UpdateChat(r.Context(), id, chatService.Update{
+		Title:       req.Title,
+		Description: req.Description,
+		Topic:       req.Topic,
+		AvatarID:    req.AvatarID,
+		Visibility:  req.Visibility,
+		Settings:    req.Settings,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrInvalidTitle),
+			errors.Is(err, chatService.ErrInvalidDescription),
+			errors.Is(err, chatService.ErrInvalidTopic),
+			errors.Is(err, chatService.ErrInvalidVisibility),
+			errors.Is(err, chatService.ErrInvalidAvatar),
+			errors.Is(err, chatService.ErrInvalidSettings):
+			log.WithError(err).Warn("Invalid chat update")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, chatService.ErrChatExists):
+			log.WithError(err).Warn("Chat already exists")
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, chatService.ErrNotOwner):
+			log.WithError(err).Warn("Not the chat owner")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to update chat", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ChatResponse{
+		Status: "success",
+		Chat:   models.NewChatInfo(chat, nil),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) ListPublicChats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset := 0, 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		var err error
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			log.WithError(err).Warn("Invalid offset parameter")
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summaries, err := h.service.Chat.ListPublic(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list chats", http.StatusInternalServerError)
+		return
+	}
+
+	chats := make([]models.PublicChat, 0, len(summaries))
+	for _, summary := range summaries {
+		chats = append(chats, models.PublicChat{
+			ChatInfo:    models.NewChatInfo(summary.Chat, nil),
+			MemberCount: summary.Members,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListPublicChatsResponse{
+		Status: "success",
+		Chats:  chats,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) JoinChat(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	chat, joined, err := h.service.Chat.JoinChat(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		case errors.Is(err, moderationService.ErrBanned):
+			log.WithError(err).Warn("User is banned")
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to join chat", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.JoinChatResponse{
+		Status: "success",
+		Joined: joined,
+		Chat:   models.NewChatInfo(chat, nil),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Chat) LeaveChat(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Chat.LeaveChat(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, chatService.ErrOwnerLeave):
+			log.WithError(err).Warn("Owner tried to leave")
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, chatService.ErrNotMember):
+			log.WithError(err).Warn("Not a member")
+			http.Error(w, "Not a member of the chat", http.StatusNotFound)
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to leave chat", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
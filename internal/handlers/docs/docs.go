@@ -0,0 +1,64 @@
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Spec is the OpenAPI document of the HTTP API. Keep it in sync with
+// Handler.InitRoutes and the DTOs in internal/handlers/models.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+type Docs struct {
+	log *logrus.Logger
+}
+
+func NewDocs(log *logrus.Logger) *Docs {
+	return &Docs{log: log}
+}
+
+func (h *Docs) GetSpec(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "application/json", Spec)
+}
+
+// GetUI serves a Swagger UI page for Spec. The page loads the Swagger UI
+// assets from a CDN.
+func (h *Docs) GetUI(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "text/html; charset=utf-8", swaggerUI)
+}
+
+func (h *Docs) serve(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		log.WithError(err).Error("Failed to write response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
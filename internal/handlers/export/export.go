@@ -0,0 +1,264 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	exportService "github.com/AlGrushino/chat/internal/service/export"
+	"github.com/sirupsen/logrus"
+)
+
+type Export struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewExport(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Export {
+	return &Export{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+// ExportChat streams the whole history of a chat. Errors after the first
+// byte can only be logged; the client sees a truncated body.
+func (h *Export) ExportChat(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportService.FormatJSON
+	}
+
+	transcript, err := h.service.Transcript(r.Context(), id, format)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to export chat")
+		return
+	}
+
+	// Large chats take longer than the server write timeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.WithError(err).Warn("Failed to clear write deadline")
+	}
+
+	w.Header().Set("Content-Type", transcript.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", transcript.Filename()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	count, err := transcript.Write(r.Context(), w)
+	if err != nil {
+		log.WithError(err).Error("Failed to write transcript")
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"messages":    count,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}).Info("Request completed")
+}
+
+func (h *Export) CreateExport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateExport
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Format == "" {
+		req.Format = exportService.FormatJSON
+	}
+
+	job, err := h.service.CreateExport(r.Context(), id, req.Format)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to create export")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/exports/%d", models.APIPrefix, job.ID))
+	h.writeExport(w, log, http.StatusAccepted, job)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Export) GetExport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.service.GetExport(r.Context(), id)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to get export")
+		return
+	}
+
+	h.writeExport(w, log, http.StatusOK, job)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Export) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid export ID", http.StatusBadRequest)
+		return
+	}
+
+	rc, job, err := h.service.OpenExport(r.Context(), id)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to download export")
+		return
+	}
+	defer rc.Close()
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.WithError(err).Warn("Failed to clear write deadline")
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(job.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chat-%d-export-%d.zip\"", job.ChatID, job.ID))
+
+	if _, err := io.Copy(w, rc); err != nil {
+		log.WithError(err).Error("Failed to write export archive")
+		return
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Export) writeError(w http.ResponseWriter, log *logrus.Entry, err error, fallback string) {
+	switch {
+	case errors.Is(err, exportService.ErrInvalidFormat):
+		log.WithError(err).Warn("Invalid export format")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, exportService.ErrChatNotFound):
+		log.WithError(err).Warn("Chat does not exist")
+		http.Error(w, "Chat does not exist", http.StatusNotFound)
+	case errors.Is(err, exportService.ErrNotFound):
+		log.WithError(err).Warn("Export does not exist")
+		http.Error(w, "Export does not exist", http.StatusNotFound)
+	case errors.Is(err, exportService.ErrNotReady):
+		log.WithError(err).Warn("Export is not finished")
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, auth.ErrUnauthenticated):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	default:
+		log.WithError(err).Error("Service error")
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func (h *Export) writeExport(w http.ResponseWriter, log *logrus.Entry, status int, job *repoModels.ExportJob) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := models.ExportResponse{
+		Status: "success",
+		Export: models.NewExport(job),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+}
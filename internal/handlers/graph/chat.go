@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/loader"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+)
+
+// chatResolver resolves a chat the viewer may access. Siblings are the chats
+// fetched together with it; batched fields are loaded for all of them at
+// once.
+type chatResolver struct {
+	chat     *repoModels.Chat
+	peer     *repoModels.User
+	siblings []*repoModels.Chat
+	log      *logrus.Logger
+}
+
+func (r *chatResolver) ID() graphql.ID {
+	return toID(r.chat.ID)
+}
+
+func (r *chatResolver) Title() string {
+	return r.chat.Title
+}
+
+func (r *chatResolver) Type() string {
+	return r.chat.Type
+}
+
+func (r *chatResolver) Topic() string {
+	return r.chat.Topic
+}
+
+func (r *chatResolver) Description() string {
+	return r.chat.Description
+}
+
+func (r *chatResolver) Visibility() *string {
+	if r.chat.IsDirect() {
+		return nil
+	}
+	return &r.chat.Visibility
+}
+
+func (r *chatResolver) AvatarURL() *string {
+	if r.chat.AvatarID == nil {
+		return nil
+	}
+	url := fmt.Sprintf("%s/attachments/%d", models.APIPrefix, *r.chat.AvatarID)
+	return &url
+}
+
+func (r *chatResolver) SlowModeSeconds() int32 {
+	return int32(r.chat.SlowModeSeconds)
+}
+
+func (r *chatResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.chat.CreatedAt}
+}
+
+func (r *chatResolver) Owner(ctx context.Context) (*userResolver, error) {
+	if r.chat.OwnerID == nil {
+		return nil, nil
+	}
+
+	users := loadersFrom(ctx).Users
+	for _, sibling := range r.siblings {
+		if sibling.OwnerID != nil {
+			users.Expect(*sibling.OwnerID)
+		}
+	}
+
+	user, err := users.Load(ctx, *r.chat.OwnerID)
+	if err != nil {
+		return nil, toError(r.log.WithField("resolver", "Chat.owner"), err, "Failed to load owner")
+	}
+
+	return newUser(user), nil
+}
+
+func (r *chatResolver) Peer(ctx context.Context) (*userResolver, error) {
+	if !r.chat.IsDirect() {
+		return nil, nil
+	}
+	if r.peer != nil {
+		return newUser(r.peer), nil
+	}
+
+	viewer := auth.UserFromContext(ctx)
+	if viewer == nil {
+		return nil, nil
+	}
+
+	members := loadersFrom(ctx).Members
+	for _, sibling := range r.siblings {
+		if sibling.IsDirect() {
+			members.Expect(loader.MemberPage{ChatID: sibling.ID, Limit: 2})
+		}
+	}
+
+	page, err := members.Load(ctx, loader.MemberPage{ChatID: r.chat.ID, Limit: 2})
+	if err != nil {
+		return nil, toError(r.log.WithField("resolver", "Chat.peer"), err, "Failed to load peer")
+	}
+
+	for _, member := range page {
+		if member.UserID != viewer.ID {
+			return newUser(&member.User), nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *chatResolver) LastMessage(ctx context.Context) (*messageResolver, error) {
+	messages := loadersFrom(ctx).Messages
+	for _, sibling := range r.siblings {
+		messages.Expect(loader.MessagePage{ChatID: sibling.ID, Limit: 1})
+	}
+
+	page, err := messages.Load(ctx, loader.MessagePage{ChatID: r.chat.ID, Limit: 1})
+	if err != nil {
+		return nil, toError(r.log.WithField("resolver", "Chat.lastMessage"), err, "Failed to load messages")
+	}
+
+	if len(page) == 0 {
+		return nil, nil
+	}
+
+	return &messageResolver{message: page[0], siblings: page, log: r.log}, nil
+}
+
+func (r *chatResolver) UnreadMentionCount(ctx context.Context) (int32, error) {
+	if auth.UserFromContext(ctx) == nil {
+		return 0, nil
+	}
+
+	counts := loadersFrom(ctx).UnreadMentions
+	for _, sibling := range r.siblings {
+		counts.Expect(sibling.ID)
+	}
+
+	count, err := counts.Load(ctx, r.chat.ID)
+	if err != nil {
+		return 0, toError(r.log.WithField("resolver", "Chat.unreadMentionCount"), err, "Failed to count mentions")
+	}
+
+	return int32(count), nil
+}
+
+func (r *chatResolver) Messages(ctx context.Context, args connectionArgs) (*connection[*messageResolver], error) {
+	log := r.log.WithField("resolver", "Chat.messages")
+
+	size, err := args.pageSize()
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	before, err := decodeCursor("message", args.After)
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	messages := loadersFrom(ctx).Messages
+	for _, sibling := range r.siblings {
+		messages.Expect(loader.MessagePage{ChatID: sibling.ID, Before: before, Limit: size + 1})
+	}
+
+	page, err := messages.Load(ctx, loader.MessagePage{ChatID: r.chat.ID, Before: before, Limit: size + 1})
+	if err != nil {
+		return nil, toError(log, err, "Failed to load messages")
+	}
+
+	return newConnection(page, size,
+		func(m *repoModels.Message) string { return encodeCursor("message", m.ID) },
+		func(m *repoModels.Message) *messageResolver {
+			return &messageResolver{message: m, siblings: page, log: r.log}
+		},
+	), nil
+}
+
+func (r *chatResolver) Members(ctx context.Context, args connectionArgs) (*connection[*memberResolver], error) {
+	log := r.log.WithField("resolver", "Chat.members")
+
+	size, err := args.pageSize()
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	after, err := decodeCursor("member", args.After)
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	members := loadersFrom(ctx).Members
+	for _, sibling := range r.siblings {
+		members.Expect(loader.MemberPage{ChatID: sibling.ID, After: after, Limit: size + 1})
+	}
+
+	page, err := members.Load(ctx, loader.MemberPage{ChatID: r.chat.ID, After: after, Limit: size + 1})
+	if err != nil {
+		return nil, toError(log, err, "Failed to load members")
+	}
+
+	return newConnection(page, size,
+		func(m *repoModels.ChatMember) string { return encodeCursor("member", m.UserID) },
+		func(m *repoModels.ChatMember) *memberResolver { return &memberResolver{member: m} },
+	), nil
+}
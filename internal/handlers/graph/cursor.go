@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MaxPageSize caps the first argument of every connection.
+const MaxPageSize = 50
+
+var (
+	errInvalidCursor   = errors.New("invalid cursor")
+	errInvalidPageSize = errors.New("first must be between 1 and 50")
+)
+
+// Cursors are opaque to clients: base64 of "<kind>:<position>". Chats are
+// paged by offset, messages by ID and members by user ID.
+func encodeCursor(kind string, position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + strconv.Itoa(position)))
+}
+
+// decodeCursor returns zero for a missing cursor.
+func decodeCursor(kind string, cursor *string) (int, error) {
+	if cursor == nil || *cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	value, ok := strings.CutPrefix(string(raw), kind+":")
+	if !ok {
+		return 0, errInvalidCursor
+	}
+
+	position, err := strconv.Atoi(value)
+	if err != nil || position < 0 {
+		return 0, errInvalidCursor
+	}
+
+	return position, nil
+}
+
+// connectionArgs are the paging arguments of a connection field. The schema
+// gives first a default.
+type connectionArgs struct {
+	First int32
+	After *string
+}
+
+func (a connectionArgs) pageSize() (int, error) {
+	if a.First < 1 || a.First > MaxPageSize {
+		return 0, errInvalidPageSize
+	}
+	return int(a.First), nil
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+type edge[T any] struct {
+	cursor string
+	node   T
+}
+
+func (e *edge[T]) Cursor() string {
+	return e.cursor
+}
+
+func (e *edge[T]) Node() T {
+	return e.node
+}
+
+type connection[T any] struct {
+	edges    []*edge[T]
+	pageInfo *pageInfo
+}
+
+func (c *connection[T]) Edges() []*edge[T] {
+	return c.edges
+}
+
+func (c *connection[T]) PageInfo() *pageInfo {
+	return c.pageInfo
+}
+
+// newConnection builds a page out of up to size+1 items; the extra item only
+// tells whether there is a next page.
+func newConnection[S, T any](items []S, size int, cursor func(S) string, node func(S) T) *connection[T] {
+	info := &pageInfo{hasNextPage: len(items) > size}
+	if info.hasNextPage {
+		items = items[:size]
+	}
+
+	edges := make([]*edge[T], 0, len(items))
+	for _, item := range items {
+		edges = append(edges, &edge[T]{cursor: cursor(item), node: node(item)})
+	}
+
+	if len(edges) > 0 {
+		info.endCursor = &edges[len(edges)-1].cursor
+	}
+
+	return &connection[T]{edges: edges, pageInfo: info}
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"errors"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	chatService "github.com/AlGrushino/chat/internal/service/chat"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	"github.com/sirupsen/logrus"
+)
+
+// queryError is returned by resolvers. Its code ends up in the extensions
+// of the GraphQL error.
+type queryError struct {
+	message string
+	code    string
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func (e *queryError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// toError maps service errors the way the HTTP handlers do. Unknown errors
+// are logged and hidden behind fallback.
+func toError(log *logrus.Entry, err error, fallback string) error {
+	switch {
+	case err.Error() == "chat does not exist":
+		return &queryError{message: "Chat does not exist", code: "NOT_FOUND"}
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return &queryError{message: "Authentication required", code: "UNAUTHENTICATED"}
+	case errors.Is(err, errInvalidCursor), errors.Is(err, errInvalidPageSize),
+		errors.Is(err, messageService.ErrInvalidLimit), errors.Is(err, chatService.ErrInvalidTitle):
+		return &queryError{message: err.Error(), code: "BAD_USER_INPUT"}
+	default:
+		log.WithError(err).Error("Resolver error")
+		return &queryError{message: fallback, code: "INTERNAL_SERVER_ERROR"}
+	}
+}
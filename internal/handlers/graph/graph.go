@@ -0,0 +1,138 @@
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/service"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed schema.graphql
+var Schema string
+
+// MaxDepth limits how deeply queries may nest selections.
+const MaxDepth = 8
+
+type Graph struct {
+	service *service.Service
+	schema  *graphql.Schema
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewGraph(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Graph {
+	schema := graphql.MustParseSchema(Schema, &Resolver{service: service, log: log},
+		graphql.MaxDepth(MaxDepth),
+	)
+
+	return &Graph{
+		service: service,
+		schema:  schema,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Query executes a GraphQL request. Clients accepting text/event-stream get
+// the results as server-sent events, which is how subscriptions are served:
+// every result is a "next" event and a "complete" event ends the stream.
+func (h *Graph) Query(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	log = log.WithField("operation", req.OperationName)
+	ctx := withLoaders(r.Context(), h.service.Loaders.New(r.Context()))
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.stream(w, r.WithContext(ctx), log, req)
+		log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Stream closed")
+		return
+	}
+
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Graph) stream(w http.ResponseWriter, r *http.Request, log *logrus.Entry, req request) {
+	responses, err := h.schema.Subscribe(r.Context(), req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		log.WithError(err).Error("Failed to subscribe")
+		http.Error(w, "Failed to subscribe", http.StatusInternalServerError)
+		return
+	}
+
+	// Streams outlive the server write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.WithError(err).Warn("Failed to clear write deadline")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for resp := range responses {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			log.WithError(err).Error("Failed to encode response")
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+			log.WithError(err).Warn("Failed to write event")
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			log.WithError(err).Warn("Failed to flush event")
+			return
+		}
+	}
+
+	fmt.Fprint(w, "event: complete\ndata:\n\n")
+	rc.Flush()
+}
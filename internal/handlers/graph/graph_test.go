@@ -0,0 +1,221 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	"github.com/AlGrushino/chat/internal/service/chat"
+	"github.com/AlGrushino/chat/internal/service/loader"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChats struct {
+	service.Chat
+	chats []*models.Chat
+}
+
+func (f *fakeChats) ListChats(ctx context.Context, limit, offset int) ([]chat.Summary, error) {
+	summaries := make([]chat.Summary, 0, limit)
+	for i := offset; i < len(f.chats) && len(summaries) < limit; i++ {
+		summaries = append(summaries, chat.Summary{Chat: f.chats[i]})
+	}
+	return summaries, nil
+}
+
+type fakeMessages struct {
+	service.Message
+	stream chan *models.Message
+}
+
+func (f *fakeMessages) Subscribe(ctx context.Context, id int) (<-chan *models.Message, error) {
+	return f.stream, nil
+}
+
+// calls counts the queries made by the loaders.
+type calls struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *calls) add(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[name]++
+}
+
+type fakeUsers struct {
+	repository.User
+	*calls
+}
+
+type fakeMessageRepo struct {
+	repository.Message
+	*calls
+}
+
+type fakeMentions struct {
+	repository.Mention
+	*calls
+}
+
+func (s fakeUsers) GetByIDs(ctx context.Context, ids []int) ([]*models.User, error) {
+	s.add("users")
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, &models.User{ID: id, Username: "user" + toIDString(id)})
+	}
+	return users, nil
+}
+
+func (s fakeMessageRepo) GetLatestByChatIDs(ctx context.Context, chatIDs []int, beforeID, limit int) ([]*models.Message, error) {
+	s.add("messages")
+	messages := make([]*models.Message, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		authorID := chatID
+		messages = append(messages, &models.Message{ID: chatID * 100, ChatID: chatID, AuthorID: &authorID, Text: "hi"})
+	}
+	return messages, nil
+}
+
+func (s fakeMentions) CountUnreadByChatIDs(ctx context.Context, userID int, chatIDs []int) (map[int]int, error) {
+	s.add("mentions")
+	return map[int]int{1: 3}, nil
+}
+
+func toIDString(id int) string {
+	return string(toID(id))
+}
+
+func newTestGraph(t *testing.T, chats *fakeChats, messages *fakeMessages) (*Graph, *calls) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	counter := &calls{counts: make(map[string]int)}
+	svc := &service.Service{
+		Chat:    chats,
+		Message: messages,
+		Loaders: loader.NewLoaderService(log, &repository.Repository{
+			User:    fakeUsers{calls: counter},
+			Message: fakeMessageRepo{calls: counter},
+			Mention: fakeMentions{calls: counter},
+		}),
+	}
+
+	return NewGraph(svc, http.NewServeMux(), log), counter
+}
+
+func do(g *Graph, query string, accept string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request{Query: query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Accept", accept)
+	req = req.WithContext(auth.WithUser(req.Context(), testutil.Owner))
+
+	rec := httptest.NewRecorder()
+	g.Query(rec, req)
+	return rec
+}
+
+func TestChats_BatchesNestedFields(t *testing.T) {
+	chats := &fakeChats{}
+	for i := 1; i <= 5; i++ {
+		ownerID := i
+		chats.chats = append(chats.chats, &models.Chat{ID: i, Title: "chat", Type: models.ChatTypeGroup, OwnerID: &ownerID})
+	}
+	g, counter := newTestGraph(t, chats, &fakeMessages{})
+
+	rec := do(g, `{
+		chats(first: 3) {
+			edges { node { id owner { username } unreadMentionCount lastMessage { text author { id } } } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`, "application/json")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Data struct {
+			Chats struct {
+				Edges []struct {
+					Node struct {
+						ID                 string
+						Owner              struct{ Username string }
+						UnreadMentionCount int
+						LastMessage        struct{ Text string }
+					}
+				}
+				PageInfo struct {
+					HasNextPage bool
+					EndCursor   string
+				}
+			}
+		}
+		Errors []any
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Empty(t, resp.Errors)
+
+	edges := resp.Data.Chats.Edges
+	require.Len(t, edges, 3)
+	assert.Equal(t, "1", edges[0].Node.ID)
+	assert.Equal(t, "user1", edges[0].Node.Owner.Username)
+	assert.Equal(t, 3, edges[0].Node.UnreadMentionCount)
+	assert.Equal(t, 0, edges[1].Node.UnreadMentionCount)
+	assert.Equal(t, "hi", edges[2].Node.LastMessage.Text)
+	assert.True(t, resp.Data.Chats.PageInfo.HasNextPage)
+
+	// Owners and message authors are users 1-3 either way, but come from
+	// different levels of the query.
+	assert.Equal(t, 1, counter.counts["messages"])
+	assert.Equal(t, 1, counter.counts["mentions"])
+	assert.LessOrEqual(t, counter.counts["users"], 2)
+
+	rec = do(g, `{ chats(first: 3, after: "`+resp.Data.Chats.PageInfo.EndCursor+`") { edges { node { id } } pageInfo { hasNextPage } } }`, "application/json")
+	assert.Contains(t, rec.Body.String(), `"id": "4"`)
+	assert.Contains(t, rec.Body.String(), `"hasNextPage": false`)
+}
+
+func TestChats_InvalidCursor(t *testing.T) {
+	g, _ := newTestGraph(t, &fakeChats{}, &fakeMessages{})
+
+	rec := do(g, `{ chats(after: "bogus") { edges { cursor } } }`, "application/json")
+
+	assert.Contains(t, rec.Body.String(), "invalid cursor")
+	assert.Contains(t, rec.Body.String(), "BAD_USER_INPUT")
+}
+
+func TestMessageAdded_Stream(t *testing.T) {
+	messages := &fakeMessages{stream: make(chan *models.Message, 1)}
+	g, _ := newTestGraph(t, &fakeChats{}, messages)
+
+	messages.stream <- &models.Message{ID: 1, ChatID: 2, Text: "hello", CreatedAt: time.Now()}
+	close(messages.stream)
+
+	rec := do(g, `subscription { messageAdded(chatId: "2") { id text } }`, "text/event-stream")
+
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+
+	var events []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	assert.Equal(t, []string{"next", "complete"}, events)
+	assert.Contains(t, body, `"text":"hello"`)
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+)
+
+// messageResolver resolves a message. Siblings are the messages fetched
+// together with it, whose authors are loaded in one batch.
+type messageResolver struct {
+	message  *repoModels.Message
+	siblings []*repoModels.Message
+	log      *logrus.Logger
+}
+
+func (r *messageResolver) ID() graphql.ID {
+	return toID(r.message.ID)
+}
+
+func (r *messageResolver) ChatID() graphql.ID {
+	return toID(r.message.ChatID)
+}
+
+func (r *messageResolver) Text() string {
+	return r.message.Text
+}
+
+func (r *messageResolver) Author(ctx context.Context) (*userResolver, error) {
+	if r.message.AuthorID == nil {
+		return nil, nil
+	}
+	if r.message.Author != nil {
+		return newUser(r.message.Author), nil
+	}
+
+	users := loadersFrom(ctx).Users
+	for _, sibling := range r.siblings {
+		if sibling.AuthorID != nil {
+			users.Expect(*sibling.AuthorID)
+		}
+	}
+
+	user, err := users.Load(ctx, *r.message.AuthorID)
+	if err != nil {
+		return nil, toError(r.log.WithField("resolver", "Message.author"), err, "Failed to load author")
+	}
+
+	return newUser(user), nil
+}
+
+func (r *messageResolver) AuthorName() *string {
+	return r.message.AuthorName
+}
+
+func (r *messageResolver) ClientMsgID() *string {
+	return r.message.ClientMsgID
+}
+
+func (r *messageResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.message.CreatedAt}
+}
+
+func (r *messageResolver) ExpiresAt() *graphql.Time {
+	if r.message.ExpiresAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.message.ExpiresAt}
+}
+
+func (r *messageResolver) Pinned() bool {
+	return r.message.PinnedAt != nil
+}
+
+func (r *messageResolver) PinnedAt() *graphql.Time {
+	if r.message.PinnedAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.message.PinnedAt}
+}
+
+func (r *messageResolver) Attachments() []*attachmentResolver {
+	attachments := make([]*attachmentResolver, 0, len(r.message.Attachments))
+	for _, attachment := range models.NewAttachments(r.message.Attachments) {
+		attachments = append(attachments, &attachmentResolver{attachment: attachment})
+	}
+	return attachments
+}
+
+func (r *messageResolver) Mentions() []*mentionResolver {
+	mentions := make([]*mentionResolver, 0, len(r.message.Mentions))
+	for _, mention := range r.message.Mentions {
+		mentions = append(mentions, &mentionResolver{mention: mention})
+	}
+	return mentions
+}
+
+type expiredMessageResolver struct {
+	message *repoModels.Message
+}
+
+func (r *expiredMessageResolver) ID() graphql.ID {
+	return toID(r.message.ID)
+}
+
+func (r *expiredMessageResolver) ChatID() graphql.ID {
+	return toID(r.message.ChatID)
+}
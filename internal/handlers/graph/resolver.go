@@ -0,0 +1,250 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	"github.com/AlGrushino/chat/internal/service/chat"
+	"github.com/AlGrushino/chat/internal/service/loader"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+)
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, loaders *loader.Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+func loadersFrom(ctx context.Context) *loader.Loaders {
+	return ctx.Value(loadersKey{}).(*loader.Loaders)
+}
+
+type Resolver struct {
+	service *service.Service
+	log     *logrus.Logger
+}
+
+func (r *Resolver) Viewer(ctx context.Context) *userResolver {
+	return newUser(auth.UserFromContext(ctx))
+}
+
+func (r *Resolver) Chats(ctx context.Context, args connectionArgs) (*connection[*chatResolver], error) {
+	log := r.log.WithField("resolver", "Query.chats")
+
+	size, err := args.pageSize()
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	offset, err := decodeCursor("chat", args.After)
+	if err != nil {
+		return nil, toError(log, err, "")
+	}
+
+	summaries, err := r.service.Chat.ListChats(ctx, size+1, offset)
+	if err != nil {
+		return nil, toError(log, err, "Failed to list chats")
+	}
+
+	// The cursor of a chat is the offset right after it.
+	type item struct {
+		offset  int
+		summary chat.Summary
+	}
+
+	items := make([]item, 0, len(summaries))
+	siblings := make([]*repoModels.Chat, 0, len(summaries))
+	for i, summary := range summaries {
+		items = append(items, item{offset: offset + i + 1, summary: summary})
+		siblings = append(siblings, summary.Chat)
+	}
+
+	return newConnection(items, size,
+		func(it item) string { return encodeCursor("chat", it.offset) },
+		func(it item) *chatResolver {
+			return &chatResolver{chat: it.summary.Chat, peer: it.summary.Peer, siblings: siblings, log: r.log}
+		},
+	), nil
+}
+
+func (r *Resolver) Chat(ctx context.Context, args struct{ ID graphql.ID }) (*chatResolver, error) {
+	log := r.log.WithField("resolver", "Query.chat")
+
+	id, err := strconv.Atoi(string(args.ID))
+	if err != nil {
+		return nil, nil
+	}
+
+	chat, err := r.service.Chat.GetChat(ctx, id)
+	if err != nil {
+		if err.Error() == "chat does not exist" {
+			return nil, nil
+		}
+		return nil, toError(log, err, "Failed to get chat")
+	}
+
+	return &chatResolver{chat: chat, siblings: []*repoModels.Chat{chat}, log: r.log}, nil
+}
+
+func (r *Resolver) MessageAdded(ctx context.Context, args struct{ ChatID graphql.ID }) (<-chan *messageResolver, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"resolver": "Subscription.messageAdded",
+		"chat_id":  args.ChatID,
+	})
+
+	id, err := strconv.Atoi(string(args.ChatID))
+	if err != nil {
+		return nil, &queryError{message: "Chat does not exist", code: "NOT_FOUND"}
+	}
+
+	messages, err := r.service.Message.Subscribe(ctx, id)
+	if err != nil {
+		return nil, toError(log, err, "Failed to subscribe")
+	}
+
+	resolvers := make(chan *messageResolver)
+	go func() {
+		defer close(resolvers)
+
+		for message := range messages {
+			select {
+			case resolvers <- &messageResolver{message: message, log: r.log}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resolvers, nil
+}
+
+func (r *Resolver) MessageExpired(ctx context.Context, args struct{ ChatID graphql.ID }) (<-chan *expiredMessageResolver, error) {
+	log := r.log.WithFields(logrus.Fields{
+		"resolver": "Subscription.messageExpired",
+		"chat_id":  args.ChatID,
+	})
+
+	id, err := strconv.Atoi(string(args.ChatID))
+	if err != nil {
+		return nil, &queryError{message: "Chat does not exist", code: "NOT_FOUND"}
+	}
+
+	messages, err := r.service.Message.SubscribeExpired(ctx, id)
+	if err != nil {
+		return nil, toError(log, err, "Failed to subscribe")
+	}
+
+	resolvers := make(chan *expiredMessageResolver)
+	go func() {
+		defer close(resolvers)
+
+		for message := range messages {
+			select {
+			case resolvers <- &expiredMessageResolver{message: message}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resolvers, nil
+}
+
+type userResolver struct {
+	user *repoModels.User
+}
+
+func newUser(user *repoModels.User) *userResolver {
+	if user == nil {
+		return nil
+	}
+	return &userResolver{user: user}
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return toID(r.user.ID)
+}
+
+func (r *userResolver) Username() string {
+	return r.user.Username
+}
+
+func (r *userResolver) IsBot() bool {
+	return r.user.IsBot
+}
+
+type attachmentResolver struct {
+	attachment models.Attachment
+}
+
+func (r *attachmentResolver) ID() graphql.ID {
+	return toID(r.attachment.ID)
+}
+
+func (r *attachmentResolver) Filename() string {
+	return r.attachment.Filename
+}
+
+func (r *attachmentResolver) ContentType() string {
+	return r.attachment.ContentType
+}
+
+func (r *attachmentResolver) Size() float64 {
+	return float64(r.attachment.Size)
+}
+
+func (r *attachmentResolver) Width() int32 {
+	return int32(r.attachment.Width)
+}
+
+func (r *attachmentResolver) Height() int32 {
+	return int32(r.attachment.Height)
+}
+
+func (r *attachmentResolver) URL() string {
+	return r.attachment.URL
+}
+
+func (r *attachmentResolver) ThumbnailURL() *string {
+	if r.attachment.ThumbnailURL == "" {
+		return nil
+	}
+	return &r.attachment.ThumbnailURL
+}
+
+type mentionResolver struct {
+	mention repoModels.Mention
+}
+
+func (r *mentionResolver) User() *userResolver {
+	return newUser(r.mention.User)
+}
+
+func (r *mentionResolver) Offset() int32 {
+	return int32(r.mention.Offset)
+}
+
+func (r *mentionResolver) Length() int32 {
+	return int32(r.mention.Length)
+}
+
+type memberResolver struct {
+	member *repoModels.ChatMember
+}
+
+func (r *memberResolver) User() *userResolver {
+	return newUser(&r.member.User)
+}
+
+func (r *memberResolver) JoinedAt() graphql.Time {
+	return graphql.Time{Time: r.member.JoinedAt}
+}
+
+func toID(id int) graphql.ID {
+	return graphql.ID(strconv.Itoa(id))
+}
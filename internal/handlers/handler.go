@@ -2,16 +2,42 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/AlGrushino/chat/internal/handlers/attachment"
+	"github.com/AlGrushino/chat/internal/handlers/bot"
 	"github.com/AlGrushino/chat/internal/handlers/chat"
+	"github.com/AlGrushino/chat/internal/handlers/docs"
+	"github.com/AlGrushino/chat/internal/handlers/export"
+	"github.com/AlGrushino/chat/internal/handlers/graph"
+	"github.com/AlGrushino/chat/internal/handlers/hook"
+	"github.com/AlGrushino/chat/internal/handlers/invite"
+	"github.com/AlGrushino/chat/internal/handlers/mention"
 	"github.com/AlGrushino/chat/internal/handlers/message"
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/handlers/moderation"
+	"github.com/AlGrushino/chat/internal/handlers/pin"
+	"github.com/AlGrushino/chat/internal/handlers/schedule"
+	"github.com/AlGrushino/chat/internal/handlers/user"
+	"github.com/AlGrushino/chat/internal/handlers/webhook"
 	"github.com/AlGrushino/chat/internal/service"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
 )
 
 type Chat interface {
 	CreateChat(w http.ResponseWriter, r *http.Request)
 	DeleteChat(w http.ResponseWriter, r *http.Request)
+	ListChats(w http.ResponseWriter, r *http.Request)
+	CreateDirect(w http.ResponseWriter, r *http.Request)
+	SetSlowMode(w http.ResponseWriter, r *http.Request)
+	SetRetention(w http.ResponseWriter, r *http.Request)
+	UpdateChat(w http.ResponseWriter, r *http.Request)
+	ListPublicChats(w http.ResponseWriter, r *http.Request)
+	JoinChat(w http.ResponseWriter, r *http.Request)
+	LeaveChat(w http.ResponseWriter, r *http.Request)
 }
 
 type Message interface {
@@ -19,12 +45,108 @@ type Message interface {
 	GetMessages(w http.ResponseWriter, r *http.Request)
 }
 
+type Attachment interface {
+	UploadAttachment(w http.ResponseWriter, r *http.Request)
+	GetAttachment(w http.ResponseWriter, r *http.Request)
+	GetThumbnail(w http.ResponseWriter, r *http.Request)
+}
+
+type User interface {
+	CreateUser(w http.ResponseWriter, r *http.Request)
+}
+
+type Mention interface {
+	GetMentions(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+}
+
+type Webhook interface {
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+}
+
+type Hook interface {
+	CreateHook(w http.ResponseWriter, r *http.Request)
+	ListHooks(w http.ResponseWriter, r *http.Request)
+	RotateHook(w http.ResponseWriter, r *http.Request)
+	RevokeHook(w http.ResponseWriter, r *http.Request)
+	PostHook(w http.ResponseWriter, r *http.Request)
+}
+
+type Bot interface {
+	CreateBot(w http.ResponseWriter, r *http.Request)
+	ListBots(w http.ResponseWriter, r *http.Request)
+	DeleteBot(w http.ResponseWriter, r *http.Request)
+}
+
+type Export interface {
+	ExportChat(w http.ResponseWriter, r *http.Request)
+	CreateExport(w http.ResponseWriter, r *http.Request)
+	GetExport(w http.ResponseWriter, r *http.Request)
+	DownloadExport(w http.ResponseWriter, r *http.Request)
+}
+
+type Schedule interface {
+	ListScheduled(w http.ResponseWriter, r *http.Request)
+	CancelScheduled(w http.ResponseWriter, r *http.Request)
+}
+
+type Pin interface {
+	PinMessage(w http.ResponseWriter, r *http.Request)
+	UnpinMessage(w http.ResponseWriter, r *http.Request)
+	ListPins(w http.ResponseWriter, r *http.Request)
+}
+
+type Invite interface {
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	ListInvites(w http.ResponseWriter, r *http.Request)
+	RevokeInvite(w http.ResponseWriter, r *http.Request)
+	AcceptInvite(w http.ResponseWriter, r *http.Request)
+}
+
+type Moderation interface {
+	KickMember(w http.ResponseWriter, r *http.Request)
+	BanUser(w http.ResponseWriter, r *http.Request)
+	UnbanUser(w http.ResponseWriter, r *http.Request)
+	ListBans(w http.ResponseWriter, r *http.Request)
+	MuteUser(w http.ResponseWriter, r *http.Request)
+	UnmuteUser(w http.ResponseWriter, r *http.Request)
+	ListMutes(w http.ResponseWriter, r *http.Request)
+	GetModerationLog(w http.ResponseWriter, r *http.Request)
+}
+
+type Graph interface {
+	Query(w http.ResponseWriter, r *http.Request)
+}
+
+type Docs interface {
+	GetSpec(w http.ResponseWriter, r *http.Request)
+	GetUI(w http.ResponseWriter, r *http.Request)
+}
+
 type Handler struct {
-	service *service.Service
-	chat    Chat
-	message Message
-	log     *logrus.Logger
-	mux     *http.ServeMux
+	service    *service.Service
+	chat       Chat
+	message    Message
+	attachment Attachment
+	user       User
+	mention    Mention
+	webhook    Webhook
+	hook       Hook
+	bot        Bot
+	export     Export
+	schedule   Schedule
+	pin        Pin
+	invite     Invite
+	moderation Moderation
+	graph      Graph
+	docs       Docs
+	log        *logrus.Logger
+	mux        *http.ServeMux
+	rateLimits map[string]ratelimit.Limit
+	routes     []string
 }
 
 func NewHandler(service *service.Service, log *logrus.Logger) *Handler {
@@ -37,36 +159,172 @@ func NewHandler(service *service.Service, log *logrus.Logger) *Handler {
 
 	chatHandler := chat.NewChat(service, mux, log)
 	messageHandler := message.NewMessage(service, mux, log)
+	attachmentHandler := attachment.NewAttachment(service, mux, log)
+	userHandler := user.NewUser(service, mux, log)
+	mentionHandler := mention.NewMention(service, mux, log)
+	webhookHandler := webhook.NewWebhook(service, mux, log)
+	hookHandler := hook.NewHook(service, mux, log)
+	botHandler := bot.NewBot(service, mux, log)
+	exportHandler := export.NewExport(service, mux, log)
+	scheduleHandler := schedule.NewSchedule(service, mux, log)
+	pinHandler := pin.NewPin(service, mux, log)
+	inviteHandler := invite.NewInvite(service, mux, log)
+	moderationHandler := moderation.NewModeration(service, mux, log)
+	graphHandler := graph.NewGraph(service, mux, log)
+	docsHandler := docs.NewDocs(log)
 
 	return &Handler{
-		service: service,
-		chat:    chatHandler,
-		message: messageHandler,
-		log:     log,
-		mux:     mux,
+		service:    service,
+		chat:       chatHandler,
+		message:    messageHandler,
+		attachment: attachmentHandler,
+		user:       userHandler,
+		mention:    mentionHandler,
+		webhook:    webhookHandler,
+		hook:       hookHandler,
+		bot:        botHandler,
+		export:     exportHandler,
+		schedule:   scheduleHandler,
+		pin:        pinHandler,
+		invite:     inviteHandler,
+		moderation: moderationHandler,
+		graph:      graphHandler,
+		docs:       docsHandler,
+		log:        log,
+		mux:        mux,
+		rateLimits: ratelimit.DefaultRoutes,
 	}
 }
 
+// SetRateLimits replaces the per-route limits, keyed by route pattern.
+func (h *Handler) SetRateLimits(routes map[string]ratelimit.Limit) {
+	h.rateLimits = routes
+}
+
+// Unversioned paths predate /api/v1. They stay as aliases of v1 until
+// LegacySunset and announce it with Deprecation and Sunset headers.
+var (
+	LegacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	LegacySunset       = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
+// route is an endpoint of one API version. The pattern excludes the version
+// prefix.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
 func (h *Handler) InitRoutes() {
 	h.log.WithFields(logrus.Fields{
 		"layer":  "handler",
 		"method": "InitRoutes",
 	}).Info("Initing routes")
 
-	h.mux.HandleFunc("POST /chats", h.chat.CreateChat)
-	h.mux.HandleFunc("POST /chats/{id}/messages", h.message.AddMessage)
-	h.mux.HandleFunc("GET /chats/{id}", h.message.GetMessages)
-	h.mux.HandleFunc("DELETE /chats/{id}/delete", h.chat.DeleteChat)
+	// Each API version is a route table mounted under its own prefix on top
+	// of the shared services. A new version gets its own table, reusing the
+	// handlers whose contract did not change.
+	v1 := h.v1Routes()
+	h.mount(models.APIPrefix, v1)
+
+	deprecated := middleware.Deprecated(LegacyDeprecatedAt, LegacySunset, models.APIPrefix)
+	for _, rt := range v1 {
+		h.mux.HandleFunc(rt.pattern, deprecated(rt.handler))
+	}
+
+	// GraphQL evolves its schema in place instead of by URL version.
+	h.handle("POST /graphql", h.graph.Query)
+
+	h.handle("GET /openapi.json", h.docs.GetSpec)
+	h.handle("GET /docs", h.docs.GetUI)
 
 	h.log.Info("Routes initialized successfully")
 }
 
+func (h *Handler) v1Routes() []route {
+	idempotent := middleware.Idempotent(h.service.Idempotency, h.log)
+
+	return []route{
+		{"POST /users", h.user.CreateUser},
+		{"POST /chats", idempotent(h.chat.CreateChat)},
+		{"GET /chats", h.chat.ListChats},
+		{"GET /chats/public", h.chat.ListPublicChats},
+		{"POST /chats/{id}/join", middleware.RequireUser(h.chat.JoinChat)},
+		{"POST /chats/{id}/leave", middleware.RequireUser(h.chat.LeaveChat)},
+		{"POST /dms/{userID}", middleware.RequireUser(idempotent(h.chat.CreateDirect))},
+		{"POST /chats/{id}/messages", idempotent(h.message.AddMessage)},
+		{"GET /chats/{id}", h.message.GetMessages},
+		{"PATCH /chats/{id}", middleware.RequireUser(h.chat.UpdateChat)},
+		{"DELETE /chats/{id}/delete", h.chat.DeleteChat},
+		{"PUT /chats/{id}/slow-mode", middleware.RequireUser(h.chat.SetSlowMode)},
+		{"PUT /chats/{id}/retention", middleware.RequireUser(h.chat.SetRetention)},
+		{"POST /chats/{id}/attachments", h.attachment.UploadAttachment},
+		{"GET /attachments/{id}", h.attachment.GetAttachment},
+		{"GET /attachments/{id}/thumbnail", h.attachment.GetThumbnail},
+		{"GET /me/mentions", middleware.RequireUser(h.mention.GetMentions)},
+		{"POST /me/mentions/read", middleware.RequireUser(h.mention.MarkRead)},
+		{"POST /webhooks", middleware.RequireUser(h.webhook.CreateWebhook)},
+		{"GET /webhooks", middleware.RequireUser(h.webhook.ListWebhooks)},
+		{"DELETE /webhooks/{id}", middleware.RequireUser(h.webhook.DeleteWebhook)},
+		{"GET /webhooks/{id}/deliveries", middleware.RequireUser(h.webhook.GetDeliveries)},
+		{"POST /chats/{id}/hooks", middleware.RequireUser(h.hook.CreateHook)},
+		{"GET /chats/{id}/hooks", middleware.RequireUser(h.hook.ListHooks)},
+		{"POST /chats/{id}/hooks/{hookID}/rotate", middleware.RequireUser(h.hook.RotateHook)},
+		{"DELETE /chats/{id}/hooks/{hookID}", middleware.RequireUser(h.hook.RevokeHook)},
+		{"POST /hooks/{token}", h.hook.PostHook},
+		{"POST /bots", middleware.RequireUser(h.bot.CreateBot)},
+		{"GET /bots", middleware.RequireUser(h.bot.ListBots)},
+		{"DELETE /bots/{id}", middleware.RequireUser(h.bot.DeleteBot)},
+		{"GET /chats/{id}/export", h.export.ExportChat},
+		{"POST /chats/{id}/exports", middleware.RequireUser(idempotent(h.export.CreateExport))},
+		{"GET /exports/{id}", middleware.RequireUser(h.export.GetExport)},
+		{"GET /exports/{id}/download", middleware.RequireUser(h.export.DownloadExport)},
+		{"GET /me/scheduled-messages", middleware.RequireUser(h.schedule.ListScheduled)},
+		{"DELETE /me/scheduled-messages/{id}", middleware.RequireUser(h.schedule.CancelScheduled)},
+		{"GET /chats/{id}/pins", h.pin.ListPins},
+		{"PUT /chats/{id}/pins/{messageID}", middleware.RequireUser(h.pin.PinMessage)},
+		{"DELETE /chats/{id}/pins/{messageID}", middleware.RequireUser(h.pin.UnpinMessage)},
+		{"POST /chats/{id}/invites", middleware.RequireUser(idempotent(h.invite.CreateInvite))},
+		{"GET /chats/{id}/invites", middleware.RequireUser(h.invite.ListInvites)},
+		{"DELETE /chats/{id}/invites/{inviteID}", middleware.RequireUser(h.invite.RevokeInvite)},
+		{"POST /invites/{token}/accept", middleware.RequireUser(h.invite.AcceptInvite)},
+		{"POST /chats/{id}/members/{userID}/kick", middleware.RequireUser(h.moderation.KickMember)},
+		{"GET /chats/{id}/bans", middleware.RequireUser(h.moderation.ListBans)},
+		{"PUT /chats/{id}/bans/{userID}", middleware.RequireUser(h.moderation.BanUser)},
+		{"DELETE /chats/{id}/bans/{userID}", middleware.RequireUser(h.moderation.UnbanUser)},
+		{"GET /chats/{id}/mutes", middleware.RequireUser(h.moderation.ListMutes)},
+		{"PUT /chats/{id}/mutes/{userID}", middleware.RequireUser(h.moderation.MuteUser)},
+		{"DELETE /chats/{id}/mutes/{userID}", middleware.RequireUser(h.moderation.UnmuteUser)},
+		{"GET /chats/{id}/moderation-log", middleware.RequireUser(h.moderation.GetModerationLog)},
+	}
+}
+
+// mount registers routes under prefix, e.g. "GET /chats" as
+// "GET /api/v1/chats".
+func (h *Handler) mount(prefix string, routes []route) {
+	for _, rt := range routes {
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		h.handle(method+" "+prefix+path, rt.handler)
+	}
+}
+
+func (h *Handler) handle(pattern string, handler http.HandlerFunc) {
+	h.mux.HandleFunc(pattern, handler)
+	h.routes = append(h.routes, pattern)
+}
+
+// Routes returns the patterns registered by InitRoutes, without the legacy
+// aliases.
+func (h *Handler) Routes() []string {
+	return h.routes
+}
+
 func (h *Handler) RunServer(addr string) error {
 	h.log.WithField("address", addr).Info("Starting HTTP server")
 
 	server := &http.Server{
 		Addr:    addr,
-		Handler: h.mux,
+		Handler: h.Router(),
 	}
 
 	return server.ListenAndServe()
@@ -75,3 +333,13 @@ func (h *Handler) RunServer(addr string) error {
 func (h *Handler) GetMux() *http.ServeMux {
 	return h.mux
 }
+
+// Router returns the mux wrapped in middleware shared by every route.
+// Rate limiting is skipped when the service has no limiter.
+func (h *Handler) Router() http.Handler {
+	var next http.Handler = h.mux
+	if h.service.Limiter != nil {
+		next = middleware.RateLimit(h.service.Limiter, h.rateLimits, h.mux, h.log)(next)
+	}
+	return middleware.Authenticate(h.service.User, h.log)(next)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/docs"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/service"
+	inviteService "github.com/AlGrushino/chat/internal/service/invite"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
+	scheduleService "github.com/AlGrushino/chat/internal/service/schedule"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPISpec struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func newTestHandler() *Handler {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	handler := NewHandler(&service.Service{}, log)
+	handler.InitRoutes()
+	return handler
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec openAPISpec
+	require.NoError(t, json.Unmarshal(docs.Spec, &spec))
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	routes := newTestHandler().Routes()
+	require.NotEmpty(t, routes)
+
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		method, path, ok := strings.Cut(route, " ")
+		require.True(t, ok, "route %q has no method", route)
+
+		registered[route] = true
+		_, documented := spec.Paths[path][strings.ToLower(method)]
+		assert.True(t, documented, "route %q is missing from openapi.json", route)
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			route := strings.ToUpper(method) + " " + path
+			assert.True(t, registered[route], "openapi.json documents unregistered route %q", route)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := httptest.NewRecorder()
+	handler.GetMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(rec.Body.Bytes()))
+
+	rec = httptest.NewRecorder()
+	handler.GetMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/openapi.json")
+}
+
+func TestLegacyAliases(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := httptest.NewRecorder()
+	handler.GetMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/chats/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/chats/abc>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	rec = httptest.NewRecorder()
+	handler.GetMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/chats/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+}
+
+func TestExpiresInOutOfRange(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	repo := &repository.Repository{
+		Chat:   testutil.NewChats(testutil.GroupChat("general")),
+		Member: testutil.NewMembers(testutil.Owner.ID),
+	}
+	handler := NewHandler(&service.Service{
+		Message:    messageService.NewMessageService(log, repo, nil, nil),
+		Schedule:   scheduleService.NewScheduleService(log, repo),
+		Invite:     inviteService.NewInviteService(log, repo),
+		Moderation: moderationService.NewModerationService(log, repo),
+	}, log)
+	handler.InitRoutes()
+
+	// 2^63 / 10^9 + 1 seconds overflows a time.Duration.
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"message", http.MethodPost, "/api/v1/chats/1/messages", `{"text": "hi", "expires_in": 9223372037}`},
+		{"negative message", http.MethodPost, "/api/v1/chats/1/messages", `{"text": "hi", "expires_in": -1}`},
+		{"scheduled message", http.MethodPost, "/api/v1/chats/1/messages", `{"text": "hi", "expires_in": 9223372037, "send_at": "2030-01-01T00:00:00Z"}`},
+		{"invite", http.MethodPost, "/api/v1/chats/1/invites", `{"expires_in": 9223372037}`},
+		{"ban", http.MethodPut, "/api/v1/chats/1/bans/8", `{"expires_in": 9223372037}`},
+		{"mute", http.MethodPut, "/api/v1/chats/1/mutes/8", `{"expires_in": -1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r = r.WithContext(auth.WithUser(r.Context(), testutil.Owner))
+			rec := httptest.NewRecorder()
+			handler.GetMux().ServeHTTP(rec, r)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "expires_in")
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	hookService "github.com/AlGrushino/chat/internal/service/hook"
+	"github.com/sirupsen/logrus"
+)
+
+type Hook struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewHook(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Hook {
+	return &Hook{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Hook) CreateHook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateHook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	hook, token, err := h.service.Hook.CreateHook(r.Context(), id, req.Name, req.RateLimit)
+	if err != nil {
+		switch {
+		case errors.Is(err, hookService.ErrInvalidName), errors.Is(err, hookService.ErrInvalidRateLimit):
+			log.WithError(err).Warn("Invalid hook")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, hookService.ErrChatNotFound):
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create hook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeToken(w, log, http.StatusCreated, hook, token)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Hook) ListHooks(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	hooks, err := h.service.Hook.ListHooks(r.Context(), id)
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list hooks", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]models.Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		items = append(items, models.NewHook(hook))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListHooksResponse{
+		Status: "success",
+		Hooks:  items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Hook) RotateHook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, hookID, ok := hookPath(w, r)
+	if !ok {
+		return
+	}
+
+	hook, token, err := h.service.Hook.RotateHook(r.Context(), chatID, hookID)
+	if err != nil {
+		if errors.Is(err, hookService.ErrNotFound) {
+			log.WithError(err).Warn("Hook does not exist")
+			http.Error(w, "Hook does not exist", http.StatusNotFound)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to rotate hook token", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeToken(w, log, http.StatusOK, hook, token)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Hook) RevokeHook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, hookID, ok := hookPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Hook.RevokeHook(r.Context(), chatID, hookID); err != nil {
+		if errors.Is(err, hookService.ErrNotFound) {
+			log.WithError(err).Warn("Hook does not exist")
+			http.Error(w, "Hook does not exist", http.StatusNotFound)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to revoke hook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Hook) PostHook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	// The path carries the hook secret, so it is never logged.
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   "/hooks/{token}",
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.PostHook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	message, err := h.service.Hook.Post(r.Context(), r.PathValue("token"), req.Text)
+	if err != nil {
+		var rateLimitErr *hookService.RateLimitError
+		switch {
+		case errors.Is(err, hookService.ErrInvalidToken):
+			log.WithError(err).Warn("Invalid hook token")
+			http.Error(w, "Hook does not exist", http.StatusNotFound)
+		case errors.As(err, &rateLimitErr):
+			log.WithError(err).Warn("Hook rate limited")
+			middleware.TooManyRequests(w, rateLimitErr.RetryAfter)
+		case err.Error() == "chat does not exist":
+			log.WithError(err).Error("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to add message", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.NewCreateMessageResponse(message)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Hook) writeToken(w http.ResponseWriter, log *logrus.Entry, status int, hook *repoModels.IncomingHook, token string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := models.HookTokenResponse{
+		Status: "success",
+		Hook:   models.NewHook(hook),
+		Token:  token,
+		URL:    models.APIPrefix + "/hooks/" + token,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+}
+
+func hookPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	hookID, err := strconv.Atoi(r.PathValue("hookID"))
+	if err != nil {
+		http.Error(w, "Invalid hook ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return chatID, hookID, true
+}
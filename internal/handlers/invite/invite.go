@@ -0,0 +1,255 @@
+package invite
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	inviteService "github.com/AlGrushino/chat/internal/service/invite"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/sirupsen/logrus"
+)
+
+type Invite struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewInvite(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Invite {
+	return &Invite{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Invite) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	invite, token, err := h.service.Invite.CreateInvite(r.Context(), id, req.ExpiresIn, req.MaxUses)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to create invite")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.InviteTokenResponse{
+		Status: "success",
+		Invite: models.NewInvite(invite),
+		Token:  token,
+		URL:    models.APIPrefix + "/invites/" + token + "/accept",
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Invite) ListInvites(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	invites, err := h.service.Invite.ListInvites(r.Context(), id)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to list invites")
+		return
+	}
+
+	items := make([]models.Invite, 0, len(invites))
+	for _, invite := range invites {
+		items = append(items, models.NewInvite(invite))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListInvitesResponse{
+		Status:  "success",
+		Invites: items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Invite) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	inviteID, err := strconv.Atoi(r.PathValue("inviteID"))
+	if err != nil {
+		http.Error(w, "Invalid invite ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Invite.RevokeInvite(r.Context(), chatID, inviteID); err != nil {
+		h.writeError(w, log, err, "Failed to revoke invite")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Invite) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	// The path carries the invite token, so it is never logged.
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   "/invites/{token}/accept",
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chat, joined, err := h.service.Invite.AcceptInvite(r.Context(), r.PathValue("token"))
+	if err != nil {
+		h.writeError(w, log, err, "Failed to accept invite")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.JoinChatResponse{
+		Status: "success",
+		Joined: joined,
+		Chat:   models.NewChatInfo(chat, nil),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Invite) writeError(w http.ResponseWriter, log *logrus.Entry, err error, fallback string) {
+	switch {
+	case errors.Is(err, inviteService.ErrInvalidExpiresIn), errors.Is(err, inviteService.ErrInvalidMaxUses):
+		log.WithError(err).Warn("Invalid invite")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, inviteService.ErrChatNotFound):
+		log.WithError(err).Warn("Chat does not exist")
+		http.Error(w, "Chat does not exist", http.StatusNotFound)
+	case errors.Is(err, inviteService.ErrNotFound):
+		log.WithError(err).Warn("Invite does not exist")
+		http.Error(w, "Invite does not exist", http.StatusNotFound)
+	case errors.Is(err, inviteService.ErrExpired):
+		log.WithError(err).Warn("Invite is no longer valid")
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, inviteService.ErrNotOwner):
+		log.WithError(err).Warn("Not the chat owner")
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, moderationService.ErrBanned):
+		log.WithError(err).Warn("User is banned")
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, auth.ErrUnauthenticated):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	default:
+		log.WithError(err).Error("Service error")
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
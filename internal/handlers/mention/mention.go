@@ -0,0 +1,151 @@
+package mention
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+type Mention struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewMention(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Mention {
+	return &Mention{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Mention) GetMentions(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, offset := 0, 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		var err error
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			log.WithError(err).Warn("Invalid offset parameter")
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	unreadOnly := query.Get("unread") == "true"
+
+	mentions, err := h.service.Mention.GetMentions(r.Context(), unreadOnly, limit, offset)
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to get mentions", http.StatusInternalServerError)
+		return
+	}
+
+	inbox := make([]models.InboxMention, 0, len(mentions))
+	for _, mention := range mentions {
+		inbox = append(inbox, models.NewInboxMention(mention))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.GetMentionsResponse{
+		Status:   "success",
+		Mentions: inbox,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Mention) MarkRead(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The body is optional: an empty request marks the whole inbox as read.
+	var req models.MarkMentionsRead
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	marked, err := h.service.Mention.MarkRead(r.Context(), req.UpToID)
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to mark mentions as read", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.MarkMentionsReadResponse{
+		Status: "success",
+		Marked: marked,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
@@ -2,12 +2,18 @@ package message
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
 	"github.com/AlGrushino/chat/internal/handlers/models"
 	"github.com/AlGrushino/chat/internal/service"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
+	scheduleService "github.com/AlGrushino/chat/internal/service/schedule"
 	"github.com/sirupsen/logrus"
 )
 
@@ -61,25 +67,86 @@ func (h *Message) AddMessage(w http.ResponseWriter, r *http.Request) {
 
 	log = log.WithField("title", req.Text)
 
-	text, err := h.service.AddMessage(r.Context(), id, req.Text)
+	if req.SendAt != nil {
+		h.scheduleMessage(w, r, log, id, req)
+		log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+		return
+	}
+
+	message, err := h.service.AddMessage(r.Context(), id, messageService.NewMessage{
+		Text:          req.Text,
+		AttachmentIDs: req.AttachmentIDs,
+		ClientMsgID:   req.ClientMsgID,
+		ExpiresIn:     req.ExpiresIn,
+	})
 	if err != nil {
 		if "chat does not exist" == err.Error() {
 			log.WithError(err).Error("Chat does not exist")
 			http.Error(w, "Chat does not exist", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, messageService.ErrAttachmentUnavailable) {
+			log.WithError(err).Warn("Attachment unavailable")
+			http.Error(w, "Attachment does not exist or is already used", http.StatusUnprocessableEntity)
+			return
+		}
+		var slowModeErr *messageService.SlowModeError
+		if errors.As(err, &slowModeErr) {
+			log.WithError(err).Warn("Slow mode")
+			middleware.TooManyRequests(w, slowModeErr.RetryAfter)
+			return
+		}
+		if errors.Is(err, moderationService.ErrBanned) || errors.Is(err, moderationService.ErrMuted) {
+			log.WithError(err).Warn("Author is restricted")
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, messageService.ErrEmptyText) ||
+			errors.Is(err, messageService.ErrTextTooLong) ||
+			errors.Is(err, messageService.ErrTooManyAttachments) {
+			log.WithError(err).Warn("Invalid message")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, messageService.ErrInvalidExpiresIn) {
+			log.WithError(err).Warn("Invalid expiry")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, messageService.ErrInvalidClientMsgID) {
+			log.WithError(err).Warn("Invalid client message ID")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, messageService.ErrClientMsgIDConflict) {
+			log.WithError(err).Warn("Client message ID conflict")
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, messageService.ErrCommandAttachments) {
+			log.WithError(err).Warn("Attachments sent with a command")
+			http.Error(w, "Attachments cannot be sent with a command", http.StatusBadRequest)
+			return
+		}
 		log.WithError(err).Error("Service error")
 		http.Error(w, "Failed to add message", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	// Ephemeral command replies are not stored, so nothing is created.
+	status := http.StatusCreated
+	if message.Ephemeral {
+		status = http.StatusOK
+	}
 
-	resp := models.CreateMessageResponse{
-		Status: "success",
-		Text:   text,
+	w.Header().Set("Content-Type", "application/json")
+	if message.ExpiresAt != nil {
+		// Keeps idempotent replays from outliving the message.
+		w.Header().Set("Expires", message.ExpiresAt.UTC().Format(http.TimeFormat))
 	}
+	w.WriteHeader(status)
+
+	resp := models.NewCreateMessageResponse(message)
 
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
@@ -91,6 +158,62 @@ func (h *Message) AddMessage(w http.ResponseWriter, r *http.Request) {
 	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
 }
 
+// scheduleMessage stores a message with send_at to be posted later and
+// answers 202 Accepted with the scheduled message.
+func (h *Message) scheduleMessage(w http.ResponseWriter, r *http.Request, log *logrus.Entry, id int, req models.CreateMessage) {
+	scheduled, err := h.service.ScheduleMessage(r.Context(), id, messageService.NewMessage{
+		Text:          req.Text,
+		AttachmentIDs: req.AttachmentIDs,
+		ClientMsgID:   req.ClientMsgID,
+		ExpiresIn:     req.ExpiresIn,
+	}, *req.SendAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		case errors.Is(err, scheduleService.ErrChatNotFound):
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		case errors.Is(err, scheduleService.ErrTooMany):
+			log.WithError(err).Warn("Too many scheduled messages")
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, scheduleService.ErrInvalidSendAt),
+			errors.Is(err, scheduleService.ErrCommand),
+			errors.Is(err, messageService.ErrEmptyText),
+			errors.Is(err, messageService.ErrTextTooLong),
+			errors.Is(err, messageService.ErrTooManyAttachments),
+			errors.Is(err, messageService.ErrInvalidExpiresIn),
+			errors.Is(err, messageService.ErrInvalidClientMsgID):
+			log.WithError(err).Warn("Invalid scheduled message")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to schedule message", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if scheduled.ExpiresIn > 0 {
+		// The message expires no earlier than this once it is sent.
+		expiresAt := scheduled.SendAt.Add(time.Duration(scheduled.ExpiresIn) * time.Second)
+		w.Header().Set("Expires", expiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	resp := models.ScheduledMessageResponse{
+		Status:           "success",
+		ScheduledMessage: models.NewScheduledMessage(scheduled),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+}
+
 func (h *Message) GetMessages(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 	log := h.log.WithFields(
@@ -124,13 +247,18 @@ func (h *Message) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	messages, err := h.service.Message.GetMessages(r.Context(), id, limit)
+	messages, err := h.service.Message.ListMessages(r.Context(), id, limit)
 	if err != nil {
 		if "chat does not exist" == err.Error() {
 			log.WithError(err).Error("Chat does not exist")
 			http.Error(w, "Chat does not exist", http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, messageService.ErrInvalidLimit) {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
 		log.WithError(err).Error("Service error")
 		http.Error(w, "Failed to get messages", http.StatusInternalServerError)
 		return
@@ -139,10 +267,16 @@ func (h *Message) GetMessages(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
+	texts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		texts = append(texts, message.Text)
+	}
+
 	resp := models.GetMessagesResponse{
 		Status:   "success",
 		ID:       id,
-		Messages: messages,
+		Messages: texts,
+		Items:    models.NewMessageInfos(messages),
 	}
 
 	encoder := json.NewEncoder(w)
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	userService "github.com/AlGrushino/chat/internal/service/user"
+	"github.com/sirupsen/logrus"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*models.User, error)
+}
+
+// Authenticate resolves the bearer token of a request into a user and stores
+// it in the request context. Requests without credentials pass through as
+// anonymous; requests with bad credentials are rejected.
+func Authenticate(users Authenticator, log *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				unauthorized(w, "Invalid authorization header")
+				return
+			}
+
+			user, err := users.Authenticate(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				if errors.Is(err, userService.ErrUnauthorized) {
+					log.WithFields(logrus.Fields{
+						"path": r.URL.Path,
+						"ip":   r.RemoteAddr,
+					}).Warn("Invalid token")
+					unauthorized(w, "Invalid token")
+					return
+				}
+				log.WithError(err).Error("Failed to authenticate request")
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// RequireUser rejects anonymous requests.
+func RequireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.UserFromContext(r.Context()) == nil {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="chat"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
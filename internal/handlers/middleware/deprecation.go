@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Deprecated marks responses of a legacy route with the Deprecation (RFC
+// 9745) and Sunset (RFC 8594) headers and links to the same path under
+// successorPrefix.
+func Deprecated(deprecatedAt, sunset time.Time, successorPrefix string) func(http.HandlerFunc) http.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Set("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+			next(w, r)
+		}
+	}
+}
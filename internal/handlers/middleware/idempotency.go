@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	idempotencyService "github.com/AlGrushino/chat/internal/service/idempotency"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	ReplayedHeader       = "Idempotent-Replayed"
+
+	maxIdempotentBody = 1 << 20
+)
+
+type IdempotencyStore interface {
+	Begin(ctx context.Context, scope, key, requestHash string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Idempotent makes a handler honor the Idempotency-Key header. The first
+// response to a key is stored and replayed for retries with the same method,
+// path and body; reusing the key for a different request fails with 422.
+// Server errors are not stored so the request can be retried. A response with
+// an Expires header, such as a self-destructing message, is not kept past it.
+// Keys are scoped per client like rate limits, so it must run after
+// Authenticate.
+func Idempotent(store IdempotencyStore, log *logrus.Logger) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body.Close()
+
+			if len(body) > maxIdempotentBody {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := clientKey(r)
+			log := log.WithFields(logrus.Fields{
+				"path":            r.URL.Path,
+				"client":          scope,
+				"idempotency_key": key,
+			})
+
+			stored, err := store.Begin(r.Context(), scope, key, requestHash(r, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, idempotencyService.ErrInvalidKey):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				case errors.Is(err, idempotencyService.ErrKeyReused):
+					log.Warn("Idempotency key reused with a different request")
+					http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				case errors.Is(err, idempotencyService.ErrInProgress):
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, err.Error(), http.StatusConflict)
+				default:
+					log.WithError(err).Error("Failed to check idempotency key")
+					http.Error(w, "Failed to check idempotency key", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if stored != nil {
+				log.Info("Replaying stored response")
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// The handler failed or panicked; let the client retry.
+				if err := store.Release(context.WithoutCancel(r.Context()), scope, key); err != nil {
+					log.WithError(err).Error("Failed to release idempotency key")
+				}
+			}()
+
+			next(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+
+			// A missing or invalid Expires header leaves the zero time,
+			// which keeps the key for the usual TTL.
+			expiresAt, _ := http.ParseTime(rec.Header().Get("Expires"))
+
+			err = store.Complete(context.WithoutCancel(r.Context()), scope, key, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes(), expiresAt)
+			if err != nil {
+				log.WithError(err).Error("Failed to store idempotent response")
+				return
+			}
+			completed = true
+		}
+	}
+}
+
+// requestHash fingerprints what makes two requests "the same": the method,
+// the path and the body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	idempotencyService "github.com/AlGrushino/chat/internal/service/idempotency"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	records map[string]*models.IdempotencyKey
+}
+
+func (s *memoryStore) Begin(ctx context.Context, scope, key, requestHash string) (*models.IdempotencyKey, error) {
+	record, ok := s.records[scope+key]
+	if !ok || (!record.ExpiresAt.IsZero() && record.ExpiresAt.Before(time.Now())) {
+		s.records[scope+key] = &models.IdempotencyKey{Scope: scope, Key: key, RequestHash: requestHash}
+		return nil, nil
+	}
+	if record.RequestHash != requestHash {
+		return nil, idempotencyService.ErrKeyReused
+	}
+	if !record.Completed() {
+		return nil, idempotencyService.ErrInProgress
+	}
+	return record, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	record := s.records[scope+key]
+	record.Status, record.ContentType, record.Body, record.ExpiresAt = status, contentType, body, expiresAt
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, scope, key string) error {
+	delete(s.records, scope+key)
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	calls := 0
+	failing := false
+	handler := Idempotent(&memoryStore{records: map[string]*models.IdempotencyKey{}}, logrus.New())(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if failing {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		},
+	)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/chats/1/messages", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	first := send("k1", `{"text":"hi"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, 1, calls)
+
+	retry := send("k1", `{"text":"hi"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, `{"text":"hi"}`, retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	reused := send("k1", `{"text":"bye"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Equal(t, 1, calls)
+
+	send("", `{"text":"hi"}`)
+	send("", `{"text":"hi"}`)
+	assert.Equal(t, 3, calls)
+
+	failing = true
+	assert.Equal(t, http.StatusInternalServerError, send("k2", `{}`).Code)
+	failing = false
+	assert.Equal(t, http.StatusCreated, send("k2", `{}`).Code)
+	assert.Equal(t, 5, calls)
+}
+
+func TestIdempotent_Expires(t *testing.T) {
+	calls := 0
+	expires := time.Now().Add(time.Hour)
+	handler := Idempotent(&memoryStore{records: map[string]*models.IdempotencyKey{}}, logrus.New())(
+		func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"text":"soon gone"}`)
+		},
+	)
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/chats/1/messages", strings.NewReader(`{"text":"soon gone","expires_in":60}`))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	send("k1")
+	assert.Equal(t, "true", send("k1").Header().Get(ReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// Once the message has expired its text is not replayed.
+	expires = time.Now().Add(-time.Minute)
+	send("k2")
+	retry := send("k2")
+	assert.Empty(t, retry.Header().Get(ReplayedHeader))
+	assert.Equal(t, 3, calls)
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimit throttles each client per route. Routes are looked up by their
+// pattern in mux without the API version prefix, so all versions and legacy
+// aliases of a route share one limit, and fall back to
+// ratelimit.DefaultRoute, whose bucket is
+// shared by all routes without a limit of their own. Clients are identified
+// by user when authenticated, so it must run after Authenticate, and by
+// remote IP otherwise. Limiter failures let the request through.
+func RateLimit(limiter ratelimit.Limiter, routes map[string]ratelimit.Limit, mux *http.ServeMux, log *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+			pattern = unversioned(pattern)
+
+			limit, ok := routes[pattern]
+			if !ok {
+				pattern = ratelimit.DefaultRoute
+				limit, ok = routes[pattern]
+			}
+
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := clientKey(r)
+
+			result, err := limiter.Allow(r.Context(), pattern+"|"+client, limit)
+			if err != nil {
+				log.WithError(err).Error("Rate limiter failed")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !result.Allowed {
+				log.WithFields(logrus.Fields{
+					"path":   r.URL.Path,
+					"route":  pattern,
+					"client": client,
+				}).Warn("Rate limit exceeded")
+				TooManyRequests(w, result.RetryAfter)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests writes a 429 response asking the client to come back after
+// retryAfter, rounded up to whole seconds.
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+func clientKey(r *http.Request) string {
+	if user := auth.UserFromContext(r.Context()); user != nil {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// unversioned strips the "/api/vN" prefix from a route pattern.
+func unversioned(pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return pattern
+	}
+
+	rest, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return pattern
+	}
+
+	version, path, _ := strings.Cut(rest, "/")
+	if _, err := strconv.Atoi(version); err != nil {
+		return pattern
+	}
+
+	return method + " /" + path
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnversioned(t *testing.T) {
+	assert.Equal(t, "POST /chats/{id}/messages", unversioned("POST /api/v1/chats/{id}/messages"))
+	assert.Equal(t, "GET /chats", unversioned("GET /api/v12/chats"))
+	assert.Equal(t, "GET /chats", unversioned("GET /chats"))
+	assert.Equal(t, "GET /api/vx/chats", unversioned("GET /api/vx/chats"))
+	assert.Equal(t, "", unversioned(""))
+}
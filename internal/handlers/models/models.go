@@ -1,25 +1,774 @@
 package models
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+)
+
+// APIPrefix is where the current API version is mounted. URLs in responses
+// point there.
+const APIPrefix = "/api/v1"
+
 type CreateChat struct {
 	Title string `json:"title"`
 }
 
 type CreateChatReposnse struct {
 	Status string `json:"status"`
+	ID     int    `json:"id"`
 	Title  string `json:"title"`
 }
 
+type ChatInfo struct {
+	ID          int            `json:"id"`
+	Type        string         `json:"type"`
+	Title       string         `json:"title,omitempty"`
+	Topic       string         `json:"topic,omitempty"`
+	Description string         `json:"description,omitempty"`
+	AvatarID    *int           `json:"avatar_id,omitempty"`
+	AvatarURL   string         `json:"avatar_url,omitempty"`
+	Visibility  string         `json:"visibility,omitempty"`
+	Settings    map[string]any `json:"settings,omitempty"`
+	Peer        *UserInfo      `json:"peer,omitempty"`
+	OwnerID     *int           `json:"owner_id,omitempty"`
+	SlowMode    int            `json:"slow_mode_seconds,omitempty"`
+	Retention   *int           `json:"retention_days,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type ListChatsResponse struct {
+	Status string     `json:"status"`
+	Chats  []ChatInfo `json:"chats"`
+}
+
+// PublicChat is a chat in the public directory.
+type PublicChat struct {
+	ChatInfo
+	MemberCount int64 `json:"member_count"`
+}
+
+type ListPublicChatsResponse struct {
+	Status string       `json:"status"`
+	Chats  []PublicChat `json:"chats"`
+}
+
+// JoinChatResponse.Joined is false when the caller was a member already.
+type JoinChatResponse struct {
+	Status string   `json:"status"`
+	Joined bool     `json:"joined"`
+	Chat   ChatInfo `json:"chat"`
+}
+
+type ChatResponse struct {
+	Status string   `json:"status"`
+	Chat   ChatInfo `json:"chat"`
+}
+
+type SetSlowMode struct {
+	Seconds int `json:"seconds"`
+}
+
+// UpdateChat changes only the fields that are present. AvatarID is an image
+// uploaded to the chat, or 0 to remove the avatar. Settings are merged into
+// the current ones; keys set to null are removed.
+type UpdateChat struct {
+	Title       *string        `json:"title,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Topic       *string        `json:"topic,omitempty"`
+	AvatarID    *int           `json:"avatar_id,omitempty"`
+	Visibility  *string        `json:"visibility,omitempty"`
+	Settings    map[string]any `json:"settings,omitempty"`
+}
+
+// SetRetention.Days is null to use the server default and 0 to keep
+// messages forever.
+type SetRetention struct {
+	Days *int `json:"days"`
+}
+
+type CreateDirectResponse struct {
+	Status  string   `json:"status"`
+	Created bool     `json:"created"`
+	Chat    ChatInfo `json:"chat"`
+}
+
+type UserInfo struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type CreateUser struct {
+	Username string `json:"username"`
+}
+
+type CreateUserResponse struct {
+	Status   string `json:"status"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// NewChatInfo builds the listing view of a chat. Direct chats have no title
+// of their own and are presented by the other participant instead.
+func NewChatInfo(chat *models.Chat, peer *models.User) ChatInfo {
+	info := ChatInfo{
+		ID:        chat.ID,
+		Type:      chat.Type,
+		CreatedAt: chat.CreatedAt,
+	}
+
+	if !chat.IsDirect() {
+		info.Title = chat.Title
+		info.Visibility = chat.Visibility
+	}
+	info.Topic = chat.Topic
+	info.Description = chat.Description
+	info.Settings = chat.Settings
+	info.OwnerID = chat.OwnerID
+	info.SlowMode = chat.SlowModeSeconds
+	info.Retention = chat.RetentionDays
+
+	if chat.AvatarID != nil {
+		info.AvatarID = chat.AvatarID
+		info.AvatarURL = fmt.Sprintf("%s/attachments/%d", APIPrefix, *chat.AvatarID)
+	}
+
+	if peer != nil {
+		info.Peer = &UserInfo{ID: peer.ID, Username: peer.Username}
+	}
+
+	return info
+}
+
+// CreateMessage.ExpiresIn makes the message self-destruct after that many
+// seconds. SendAt schedules it instead of posting it right away.
 type CreateMessage struct {
-	Text string `json:"text"`
+	Text          string     `json:"text"`
+	AttachmentIDs []int      `json:"attachment_ids,omitempty"`
+	ClientMsgID   string     `json:"client_msg_id,omitempty"`
+	ExpiresIn     int        `json:"expires_in,omitempty"`
+	SendAt        *time.Time `json:"send_at,omitempty"`
 }
 
+// CreateMessageResponse describes the posted message. Ephemeral responses
+// answer a slash command, are visible to the sender only and have no ID.
 type CreateMessageResponse struct {
+	Status      string       `json:"status"`
+	ID          int          `json:"id,omitempty"`
+	Ephemeral   bool         `json:"ephemeral,omitempty"`
+	Author      *UserInfo    `json:"author,omitempty"`
+	AuthorName  string       `json:"author_name,omitempty"`
+	ClientMsgID string       `json:"client_msg_id,omitempty"`
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Mentions    []Mention    `json:"mentions,omitempty"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+}
+
+// Mention locates a "@username" in the message text. Offset and Length are
+// counted in Unicode code points.
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+type InboxMention struct {
+	ID        int       `json:"id"`
+	ChatID    int       `json:"chat_id"`
+	MessageID int       `json:"message_id"`
+	Text      string    `json:"text"`
+	Author    *UserInfo `json:"author,omitempty"`
+	Offset    int       `json:"offset"`
+	Length    int       `json:"length"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetMentionsResponse struct {
+	Status   string         `json:"status"`
+	Mentions []InboxMention `json:"mentions"`
+}
+
+type MarkMentionsRead struct {
+	UpToID int `json:"up_to_id"`
+}
+
+type MarkMentionsReadResponse struct {
 	Status string `json:"status"`
-	Text   string `json:"text"`
+	Marked int64  `json:"marked"`
 }
 
+// GetMessagesResponse.Messages holds the texts only and Items the same
+// messages in full.
 type GetMessagesResponse struct {
-	Status   string   `json:"status"`
-	ID       int      `json:"id"`
-	Messages []string `json:"messages"`
+	Status   string        `json:"status"`
+	ID       int           `json:"id"`
+	Messages []string      `json:"messages"`
+	Items    []MessageInfo `json:"items"`
+}
+
+// MessageInfo is a stored message as shown in listings.
+type MessageInfo struct {
+	ID          int          `json:"id"`
+	ChatID      int          `json:"chat_id"`
+	Author      *UserInfo    `json:"author,omitempty"`
+	AuthorName  string       `json:"author_name,omitempty"`
+	ClientMsgID string       `json:"client_msg_id,omitempty"`
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	Pinned      bool         `json:"pinned"`
+	PinnedAt    *time.Time   `json:"pinned_at,omitempty"`
+}
+
+type PinResponse struct {
+	Status  string      `json:"status"`
+	Message MessageInfo `json:"message"`
+}
+
+type ListPinsResponse struct {
+	Status string        `json:"status"`
+	Pins   []MessageInfo `json:"pins"`
+}
+
+func NewMessageInfo(m *models.Message) MessageInfo {
+	info := MessageInfo{
+		ID:          m.ID,
+		ChatID:      m.ChatID,
+		Text:        m.Text,
+		Attachments: NewAttachments(m.Attachments),
+		CreatedAt:   m.CreatedAt,
+		ExpiresAt:   m.ExpiresAt,
+		Pinned:      m.PinnedAt != nil,
+		PinnedAt:    m.PinnedAt,
+	}
+
+	if m.Author != nil {
+		info.Author = &UserInfo{ID: m.Author.ID, Username: m.Author.Username}
+	}
+
+	if m.AuthorName != nil {
+		info.AuthorName = *m.AuthorName
+	}
+
+	if m.ClientMsgID != nil {
+		info.ClientMsgID = *m.ClientMsgID
+	}
+
+	return info
+}
+
+func NewMessageInfos(messages []*models.Message) []MessageInfo {
+	infos := make([]MessageInfo, 0, len(messages))
+	for _, m := range messages {
+		infos = append(infos, NewMessageInfo(m))
+	}
+	return infos
+}
+
+type Attachment struct {
+	ID           int    `json:"id"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	URL          string `json:"url"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+type UploadAttachmentResponse struct {
+	Status     string     `json:"status"`
+	Attachment Attachment `json:"attachment"`
+}
+
+type CreateWebhook struct {
+	URL    string   `json:"url"`
+	ChatID *int     `json:"chat_id,omitempty"`
+	Events []string `json:"events"`
+}
+
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	ChatID    *int      `json:"chat_id,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookResponse carries the signing secret. It is only returned
+// once, when the webhook is created.
+type CreateWebhookResponse struct {
+	Status  string  `json:"status"`
+	Webhook Webhook `json:"webhook"`
+	Secret  string  `json:"secret"`
+}
+
+type ListWebhooksResponse struct {
+	Status   string    `json:"status"`
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type GetDeliveriesResponse struct {
+	Status     string            `json:"status"`
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+type CreateHook struct {
+	Name      string `json:"name"`
+	RateLimit int    `json:"rate_limit,omitempty"`
+}
+
+// Hook is an incoming webhook. RateLimit is in messages per minute.
+type Hook struct {
+	ID        int       `json:"id"`
+	ChatID    int       `json:"chat_id"`
+	Name      string    `json:"name"`
+	RateLimit int       `json:"rate_limit"`
+	Revoked   bool      `json:"revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HookTokenResponse is returned when a hook token is issued. The token is
+// not stored in plain form and cannot be retrieved again.
+type HookTokenResponse struct {
+	Status string `json:"status"`
+	Hook   Hook   `json:"hook"`
+	Token  string `json:"token"`
+	URL    string `json:"url"`
+}
+
+type ListHooksResponse struct {
+	Status string `json:"status"`
+	Hooks  []Hook `json:"hooks"`
+}
+
+type PostHook struct {
+	Text string `json:"text"`
+}
+
+func NewHook(h *models.IncomingHook) Hook {
+	return Hook{
+		ID:        h.ID,
+		ChatID:    h.ChatID,
+		Name:      h.Name,
+		RateLimit: h.RateLimit,
+		Revoked:   h.RevokedAt != nil,
+		CreatedAt: h.CreatedAt,
+	}
+}
+
+// CreateInvite.ExpiresIn is in seconds. Zero values mean no limit.
+type CreateInvite struct {
+	ExpiresIn int `json:"expires_in,omitempty"`
+	MaxUses   int `json:"max_uses,omitempty"`
+}
+
+// Invite is a link to join a chat. Active is false once it is revoked,
+// expired or used up.
+type Invite struct {
+	ID        int        `json:"id"`
+	ChatID    int        `json:"chat_id"`
+	CreatorID int        `json:"creator_id"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   bool       `json:"revoked"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InviteTokenResponse is returned when an invite is created. The token is
+// not stored in plain form and cannot be retrieved again.
+type InviteTokenResponse struct {
+	Status string `json:"status"`
+	Invite Invite `json:"invite"`
+	Token  string `json:"token"`
+	URL    string `json:"url"`
+}
+
+type ListInvitesResponse struct {
+	Status  string   `json:"status"`
+	Invites []Invite `json:"invites"`
+}
+
+func NewInvite(i *models.ChatInvite) Invite {
+	return Invite{
+		ID:        i.ID,
+		ChatID:    i.ChatID,
+		CreatorID: i.CreatorID,
+		MaxUses:   i.MaxUses,
+		Uses:      i.Uses,
+		ExpiresAt: i.ExpiresAt,
+		Revoked:   i.RevokedAt != nil,
+		Active:    i.Usable(time.Now()),
+		CreatedAt: i.CreatedAt,
+	}
+}
+
+// ModerateUser is the body of kick, ban and mute requests. ExpiresIn is in
+// seconds; zero bans or mutes until lifted. Kicks ignore it.
+type ModerateUser struct {
+	Reason    string `json:"reason,omitempty"`
+	ExpiresIn int    `json:"expires_in,omitempty"`
+}
+
+// Restriction is a ban or mute in force. ExpiresAt is absent for ones that
+// last until lifted.
+type Restriction struct {
+	ID          int        `json:"id"`
+	ChatID      int        `json:"chat_id"`
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username,omitempty"`
+	Kind        string     `json:"kind"`
+	Reason      string     `json:"reason,omitempty"`
+	ModeratorID *int       `json:"moderator_id,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type RestrictionResponse struct {
+	Status      string      `json:"status"`
+	Restriction Restriction `json:"restriction"`
+}
+
+type ListRestrictionsResponse struct {
+	Status       string        `json:"status"`
+	Restrictions []Restriction `json:"restrictions"`
+}
+
+// ModerationAction is an entry of a chat's moderation log. Users who were
+// deleted since are left out.
+type ModerationAction struct {
+	ID          int        `json:"id"`
+	ChatID      int        `json:"chat_id"`
+	Action      string     `json:"action"`
+	ModeratorID *int       `json:"moderator_id,omitempty"`
+	Moderator   string     `json:"moderator,omitempty"`
+	UserID      *int       `json:"user_id,omitempty"`
+	Username    string     `json:"username,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ModerationLogResponse struct {
+	Status  string             `json:"status"`
+	Actions []ModerationAction `json:"actions"`
+}
+
+func NewRestriction(r *models.ChatRestriction) Restriction {
+	resp := Restriction{
+		ID:          r.ID,
+		ChatID:      r.ChatID,
+		UserID:      r.UserID,
+		Kind:        r.Kind,
+		Reason:      r.Reason,
+		ModeratorID: r.ModeratorID,
+		ExpiresAt:   r.ExpiresAt,
+		CreatedAt:   r.CreatedAt,
+	}
+	if r.User != nil {
+		resp.Username = r.User.Username
+	}
+	return resp
+}
+
+func NewModerationAction(a *models.ModerationAction) ModerationAction {
+	resp := ModerationAction{
+		ID:          a.ID,
+		ChatID:      a.ChatID,
+		Action:      a.Action,
+		ModeratorID: a.ModeratorID,
+		UserID:      a.UserID,
+		Reason:      a.Reason,
+		ExpiresAt:   a.ExpiresAt,
+		CreatedAt:   a.CreatedAt,
+	}
+	if a.Moderator != nil {
+		resp.Moderator = a.Moderator.Username
+	}
+	if a.User != nil {
+		resp.Username = a.User.Username
+	}
+	return resp
+}
+
+func NewWebhook(w *models.WebhookSubscription) Webhook {
+	return Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		ChatID:    w.ChatID,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func NewWebhookDelivery(d *models.WebhookDelivery) WebhookDelivery {
+	resp := WebhookDelivery{
+		ID:             d.ID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+
+	if d.Status == models.DeliveryPending {
+		next := d.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+
+	return resp
+}
+
+func NewAttachment(a *models.Attachment) Attachment {
+	resp := Attachment{
+		ID:          a.ID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		URL:         fmt.Sprintf("%s/attachments/%d", APIPrefix, a.ID),
+		Width:       a.Width,
+		Height:      a.Height,
+	}
+
+	if a.ThumbnailStatus == models.ThumbnailPending || a.ThumbnailStatus == models.ThumbnailReady {
+		resp.ThumbnailURL = fmt.Sprintf("%s/attachments/%d/thumbnail", APIPrefix, a.ID)
+	}
+
+	return resp
+}
+
+func NewAttachments(attachments []models.Attachment) []Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	resp := make([]Attachment, 0, len(attachments))
+	for i := range attachments {
+		resp = append(resp, NewAttachment(&attachments[i]))
+	}
+	return resp
+}
+
+func NewMentions(mentions []models.Mention) []Mention {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	resp := make([]Mention, 0, len(mentions))
+	for _, m := range mentions {
+		mention := Mention{
+			UserID: m.UserID,
+			Offset: m.Offset,
+			Length: m.Length,
+		}
+		if m.User != nil {
+			mention.Username = m.User.Username
+		}
+		resp = append(resp, mention)
+	}
+	return resp
+}
+
+func NewInboxMention(m *models.Mention) InboxMention {
+	resp := InboxMention{
+		ID:        m.ID,
+		MessageID: m.MessageID,
+		Offset:    m.Offset,
+		Length:    m.Length,
+		Read:      m.ReadAt != nil,
+		CreatedAt: m.CreatedAt,
+	}
+
+	if m.Message != nil {
+		resp.ChatID = m.Message.ChatID
+		resp.Text = m.Message.Text
+		if m.Message.Author != nil {
+			resp.Author = &UserInfo{ID: m.Message.Author.ID, Username: m.Message.Author.Username}
+		}
+	}
+
+	return resp
+}
+
+func NewCreateMessageResponse(m *models.Message) CreateMessageResponse {
+	resp := CreateMessageResponse{
+		Status:      "success",
+		ID:          m.ID,
+		Text:        m.Text,
+		Attachments: NewAttachments(m.Attachments),
+		Mentions:    NewMentions(m.Mentions),
+		Ephemeral:   m.Ephemeral,
+		ExpiresAt:   m.ExpiresAt,
+	}
+
+	if m.Author != nil {
+		resp.Author = &UserInfo{ID: m.Author.ID, Username: m.Author.Username}
+	}
+
+	if m.AuthorName != nil {
+		resp.AuthorName = *m.AuthorName
+	}
+
+	if m.ClientMsgID != nil {
+		resp.ClientMsgID = *m.ClientMsgID
+	}
+
+	return resp
+}
+
+type CreateExport struct {
+	Format string `json:"format"`
+}
+
+// Export is an archive of a chat's history built in the background.
+// DownloadURL is set once it succeeded.
+type Export struct {
+	ID          int        `json:"id"`
+	ChatID      int        `json:"chat_id"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	Messages    int        `json:"messages"`
+	Size        int64      `json:"size"`
+	LastError   *string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+type ExportResponse struct {
+	Status string `json:"status"`
+	Export Export `json:"export"`
+}
+
+func NewExport(e *models.ExportJob) Export {
+	resp := Export{
+		ID:          e.ID,
+		ChatID:      e.ChatID,
+		Format:      e.Format,
+		Status:      e.Status,
+		Messages:    e.Messages,
+		Size:        e.Size,
+		LastError:   e.LastError,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+	}
+
+	if e.Status == models.ExportSucceeded {
+		resp.DownloadURL = fmt.Sprintf("%s/exports/%d/download", APIPrefix, e.ID)
+	}
+
+	return resp
+}
+
+type BotCommand struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type CreateBot struct {
+	Username    string       `json:"username"`
+	CallbackURL string       `json:"callback_url"`
+	Commands    []BotCommand `json:"commands"`
+}
+
+type Bot struct {
+	ID          int          `json:"id"`
+	Username    string       `json:"username"`
+	CallbackURL string       `json:"callback_url"`
+	Commands    []BotCommand `json:"commands"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// CreateBotResponse carries the bot's API token and the secret that signs
+// its command callbacks. Neither is shown again.
+type CreateBotResponse struct {
+	Status         string `json:"status"`
+	Bot            Bot    `json:"bot"`
+	Token          string `json:"token"`
+	CallbackSecret string `json:"callback_secret"`
+}
+
+type ListBotsResponse struct {
+	Status string `json:"status"`
+	Bots   []Bot  `json:"bots"`
+}
+
+func NewBot(u *models.User, commands []*models.BotCommand) Bot {
+	bot := Bot{
+		ID:        u.ID,
+		Username:  u.Username,
+		Commands:  make([]BotCommand, 0, len(commands)),
+		CreatedAt: u.CreatedAt,
+	}
+
+	if u.CallbackURL != nil {
+		bot.CallbackURL = *u.CallbackURL
+	}
+
+	for _, c := range commands {
+		bot.Commands = append(bot.Commands, BotCommand{Name: c.Name, Description: c.Description})
+	}
+
+	return bot
+}
+
+// ScheduledMessage is a message waiting to be posted at SendAt.
+type ScheduledMessage struct {
+	ID            int       `json:"id"`
+	ChatID        int       `json:"chat_id"`
+	Text          string    `json:"text"`
+	AttachmentIDs []int     `json:"attachment_ids,omitempty"`
+	ClientMsgID   string    `json:"client_msg_id"`
+	ExpiresIn     int       `json:"expires_in,omitempty"`
+	SendAt        time.Time `json:"send_at"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ScheduledMessageResponse struct {
+	Status           string           `json:"status"`
+	ScheduledMessage ScheduledMessage `json:"scheduled_message"`
+}
+
+type ListScheduledMessagesResponse struct {
+	Status            string             `json:"status"`
+	ScheduledMessages []ScheduledMessage `json:"scheduled_messages"`
+}
+
+func NewScheduledMessage(m *models.ScheduledMessage) ScheduledMessage {
+	return ScheduledMessage{
+		ID:            m.ID,
+		ChatID:        m.ChatID,
+		Text:          m.Text,
+		AttachmentIDs: m.AttachmentIDs,
+		ClientMsgID:   m.ClientMsgID,
+		ExpiresIn:     m.ExpiresIn,
+		SendAt:        m.SendAt,
+		Status:        m.Status,
+		CreatedAt:     m.CreatedAt,
+	}
 }
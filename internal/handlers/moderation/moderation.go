@@ -0,0 +1,374 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repositoryModels "github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/sirupsen/logrus"
+)
+
+type Moderation struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewModeration(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Moderation {
+	return &Moderation{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Moderation) KickMember(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, userID, ok := userPath(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeModerateUser(w, r, log)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Moderation.Kick(r.Context(), chatID, userID, req.Reason); err != nil {
+		h.writeError(w, log, err, "Failed to kick user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Moderation) BanUser(w http.ResponseWriter, r *http.Request) {
+	h.restrict(w, r, repositoryModels.RestrictionBan)
+}
+
+func (h *Moderation) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	h.lift(w, r, repositoryModels.RestrictionBan)
+}
+
+func (h *Moderation) ListBans(w http.ResponseWriter, r *http.Request) {
+	h.listRestrictions(w, r, repositoryModels.RestrictionBan)
+}
+
+func (h *Moderation) MuteUser(w http.ResponseWriter, r *http.Request) {
+	h.restrict(w, r, repositoryModels.RestrictionMute)
+}
+
+func (h *Moderation) UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	h.lift(w, r, repositoryModels.RestrictionMute)
+}
+
+func (h *Moderation) ListMutes(w http.ResponseWriter, r *http.Request) {
+	h.listRestrictions(w, r, repositoryModels.RestrictionMute)
+}
+
+func (h *Moderation) GetModerationLog(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, offset := 0, 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			log.WithError(err).Warn("Invalid offset parameter")
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	actions, err := h.service.Moderation.GetModerationLog(r.Context(), chatID, limit, offset)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to get moderation log")
+		return
+	}
+
+	entries := make([]models.ModerationAction, 0, len(actions))
+	for _, action := range actions {
+		entries = append(entries, models.NewModerationAction(action))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ModerationLogResponse{
+		Status:  "success",
+		Actions: entries,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Moderation) restrict(w http.ResponseWriter, r *http.Request, kind string) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPut {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, userID, ok := userPath(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeModerateUser(w, r, log)
+	if !ok {
+		return
+	}
+
+	restrictFunc := h.service.Moderation.Ban
+	if kind == repositoryModels.RestrictionMute {
+		restrictFunc = h.service.Moderation.Mute
+	}
+
+	restriction, err := restrictFunc(r.Context(), chatID, userID, req.Reason, req.ExpiresIn)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to "+kind+" user")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.RestrictionResponse{
+		Status:      "success",
+		Restriction: models.NewRestriction(restriction),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Moderation) lift(w http.ResponseWriter, r *http.Request, kind string) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, userID, ok := userPath(w, r)
+	if !ok {
+		return
+	}
+
+	liftFunc := h.service.Moderation.Unban
+	if kind == repositoryModels.RestrictionMute {
+		liftFunc = h.service.Moderation.Unmute
+	}
+
+	if err := liftFunc(r.Context(), chatID, userID); err != nil {
+		h.writeError(w, log, err, "Failed to lift "+kind)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Moderation) listRestrictions(w http.ResponseWriter, r *http.Request, kind string) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	restrictions, err := h.service.Moderation.ListRestrictions(r.Context(), chatID, kind)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to list restrictions")
+		return
+	}
+
+	items := make([]models.Restriction, 0, len(restrictions))
+	for _, restriction := range restrictions {
+		items = append(items, models.NewRestriction(restriction))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListRestrictionsResponse{
+		Status:       "success",
+		Restrictions: items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Moderation) writeError(w http.ResponseWriter, log *logrus.Entry, err error, fallback string) {
+	switch {
+	case errors.Is(err, moderationService.ErrInvalidReason),
+		errors.Is(err, moderationService.ErrInvalidExpiresIn),
+		errors.Is(err, moderationService.ErrInvalidLimit):
+		log.WithError(err).Warn("Invalid moderation request")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, moderationService.ErrChatNotFound):
+		log.WithError(err).Warn("Chat does not exist")
+		http.Error(w, "Chat does not exist", http.StatusNotFound)
+	case errors.Is(err, moderationService.ErrUserNotFound):
+		log.WithError(err).Warn("User does not exist")
+		http.Error(w, "User does not exist", http.StatusNotFound)
+	case errors.Is(err, moderationService.ErrNotMember),
+		errors.Is(err, moderationService.ErrNotBanned),
+		errors.Is(err, moderationService.ErrNotMuted):
+		log.WithError(err).Warn("Nothing to undo")
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, moderationService.ErrNotOwner):
+		log.WithError(err).Warn("Not the chat owner")
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, moderationService.ErrOwnerTarget):
+		log.WithError(err).Warn("Owner cannot be moderated")
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, auth.ErrUnauthenticated):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	default:
+		log.WithError(err).Error("Service error")
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// decodeModerateUser reads the optional body of kick, ban and mute requests.
+func decodeModerateUser(w http.ResponseWriter, r *http.Request, log *logrus.Entry) (models.ModerateUser, bool) {
+	var req models.ModerateUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return req, false
+	}
+	defer r.Body.Close()
+
+	return req, true
+}
+
+func userPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return chatID, userID, true
+}
@@ -0,0 +1,195 @@
+package pin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	pinService "github.com/AlGrushino/chat/internal/service/pin"
+	"github.com/sirupsen/logrus"
+)
+
+type Pin struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewPin(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Pin {
+	return &Pin{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Pin) PinMessage(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPut {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, messageID, ok := pinPath(w, r)
+	if !ok {
+		return
+	}
+
+	message, err := h.service.Pins.Pin(r.Context(), chatID, messageID)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to pin message")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.PinResponse{
+		Status:  "success",
+		Message: models.NewMessageInfo(message),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Pin) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, messageID, ok := pinPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Pins.Unpin(r.Context(), chatID, messageID); err != nil {
+		h.writeError(w, log, err, "Failed to unpin message")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Pin) ListPins(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return
+	}
+
+	messages, err := h.service.Pins.ListPins(r.Context(), chatID)
+	if err != nil {
+		h.writeError(w, log, err, "Failed to list pinned messages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListPinsResponse{
+		Status: "success",
+		Pins:   models.NewMessageInfos(messages),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Pin) writeError(w http.ResponseWriter, log *logrus.Entry, err error, fallback string) {
+	switch {
+	case errors.Is(err, pinService.ErrChatNotFound):
+		log.WithError(err).Warn("Chat does not exist")
+		http.Error(w, "Chat does not exist", http.StatusNotFound)
+	case errors.Is(err, pinService.ErrMessageNotFound):
+		log.WithError(err).Warn("Message does not exist")
+		http.Error(w, "Message does not exist", http.StatusNotFound)
+	case errors.Is(err, pinService.ErrNotAllowed):
+		log.WithError(err).Warn("Pin not allowed")
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, pinService.ErrTooManyPins):
+		log.WithError(err).Warn("Pin limit reached")
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, auth.ErrUnauthenticated):
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	default:
+		log.WithError(err).Error("Service error")
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func pinPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	chatID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid chat ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	messageID, err := strconv.Atoi(r.PathValue("messageID"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return chatID, messageID, true
+}
@@ -0,0 +1,127 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	scheduleService "github.com/AlGrushino/chat/internal/service/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+type Schedule struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewSchedule(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Schedule {
+	return &Schedule{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Schedule) ListScheduled(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scheduled, err := h.service.Schedule.ListScheduled(r.Context())
+	if err != nil {
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list scheduled messages", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]models.ScheduledMessage, 0, len(scheduled))
+	for _, message := range scheduled {
+		items = append(items, models.NewScheduledMessage(message))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListScheduledMessagesResponse{
+		Status:            "success",
+		ScheduledMessages: items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Schedule) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid scheduled message ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Schedule.CancelScheduled(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, scheduleService.ErrNotFound):
+			log.WithError(err).Warn("Scheduled message does not exist")
+			http.Error(w, "Scheduled message does not exist", http.StatusNotFound)
+		case errors.Is(err, scheduleService.ErrNotPending):
+			log.WithError(err).Warn("Scheduled message is not pending")
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, auth.ErrUnauthenticated):
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to cancel scheduled message", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
@@ -0,0 +1,91 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	userService "github.com/AlGrushino/chat/internal/service/user"
+	"github.com/sirupsen/logrus"
+)
+
+type User struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewUser(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *User {
+	return &User{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *User) CreateUser(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CreateUser
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	log = log.WithField("username", req.Username)
+
+	user, token, err := h.service.User.Register(r.Context(), req.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, userService.ErrInvalidUsername):
+			log.WithError(err).Warn("Invalid username")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, userService.ErrUsernameTaken):
+			log.WithError(err).Warn("Username taken")
+			http.Error(w, "Username is already taken", http.StatusConflict)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.CreateUserResponse{
+		Status:   "success",
+		ID:       user.ID,
+		Username: user.Username,
+		Token:    token,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
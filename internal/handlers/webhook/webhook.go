@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	"github.com/AlGrushino/chat/internal/service"
+	webhookService "github.com/AlGrushino/chat/internal/service/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+type Webhook struct {
+	service *service.Service
+	mux     *http.ServeMux
+	log     *logrus.Logger
+}
+
+func NewWebhook(service *service.Service, mux *http.ServeMux, log *logrus.Logger) *Webhook {
+	return &Webhook{
+		service: service,
+		mux:     mux,
+		log:     log,
+	}
+}
+
+func (h *Webhook) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodPost {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CreateWebhook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid JSON")
+		http.Error(w, "InvalidJSON: ", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	subscription, err := h.service.Webhook.CreateSubscription(r.Context(), req.URL, req.ChatID, req.Events)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhookService.ErrInvalidURL), errors.Is(err, webhookService.ErrInvalidEvents):
+			log.WithError(err).Warn("Invalid webhook")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, webhookService.ErrChatNotFound):
+			log.WithError(err).Warn("Chat does not exist")
+			http.Error(w, "Chat does not exist", http.StatusNotFound)
+		default:
+			log.WithError(err).Error("Service error")
+			http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	resp := models.CreateWebhookResponse{
+		Status:  "success",
+		Webhook: models.NewWebhook(subscription),
+		Secret:  subscription.Secret,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Webhook) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	subscriptions, err := h.service.Webhook.ListSubscriptions(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	webhooks := make([]models.Webhook, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		webhooks = append(webhooks, models.NewWebhook(subscription))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.ListWebhooksResponse{
+		Status:   "success",
+		Webhooks: webhooks,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Webhook) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodDelete {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.WithError(err).Warn("Invalid webhook ID")
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Webhook.DeleteSubscription(r.Context(), id); err != nil {
+		if errors.Is(err, webhookService.ErrNotFound) {
+			log.WithError(err).Warn("Webhook does not exist")
+			http.Error(w, "Webhook does not exist", http.StatusNotFound)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
+
+func (h *Webhook) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	log := h.log.WithFields(
+		logrus.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"ip":     r.RemoteAddr,
+		},
+	)
+
+	log.Info("Incoming request")
+
+	if r.Method != http.MethodGet {
+		log.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.WithError(err).Warn("Invalid webhook ID")
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset := 0, 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			log.WithError(err).Warn("Invalid limit parameter")
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			log.WithError(err).Warn("Invalid offset parameter")
+			http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	deliveries, err := h.service.Webhook.GetDeliveries(r.Context(), id, limit, offset)
+	if err != nil {
+		if errors.Is(err, webhookService.ErrNotFound) {
+			log.WithError(err).Warn("Webhook does not exist")
+			http.Error(w, "Webhook does not exist", http.StatusNotFound)
+			return
+		}
+		log.WithError(err).Error("Service error")
+		http.Error(w, "Failed to get deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]models.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, models.NewWebhookDelivery(delivery))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	resp := models.GetDeliveriesResponse{
+		Status:     "success",
+		Deliveries: items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(resp); err != nil {
+		log.WithError(err).Error("Failed to encode response")
+	}
+
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Request completed")
+}
@@ -0,0 +1,49 @@
+package attachment
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id int) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.WithContext(ctx).First(&attachment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentRepository) GetPendingThumbnails(ctx context.Context, limit int) ([]*models.Attachment, error) {
+	var attachments []*models.Attachment
+	err := r.db.WithContext(ctx).
+		Where("thumbnail_status = ?", models.ThumbnailPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *AttachmentRepository) UpdateThumbnail(ctx context.Context, id int, key *string, status string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Attachment{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"thumbnail_key":    key,
+			"thumbnail_status": status,
+		}).Error
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+)
+
+type BotRepository struct {
+	db *gorm.DB
+}
+
+func NewBotRepository(db *gorm.DB) *BotRepository {
+	return &BotRepository{db: db}
+}
+
+// Create stores a bot account together with the slash commands it serves.
+func (r *BotRepository) Create(ctx context.Context, bot *models.User, commands []models.BotCommand) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(bot).Error; err != nil {
+			return err
+		}
+
+		if len(commands) == 0 {
+			return nil
+		}
+
+		for i := range commands {
+			commands[i].BotID = bot.ID
+		}
+
+		return tx.Omit("Bot").Create(&commands).Error
+	})
+}
+
+func (r *BotRepository) GetByOwnerID(ctx context.Context, ownerID int) ([]*models.User, error) {
+	var bots []*models.User
+	err := r.db.WithContext(ctx).
+		Where("is_bot AND owner_id = ?", ownerID).
+		Order("id ASC").
+		Find(&bots).Error
+	return bots, err
+}
+
+func (r *BotRepository) GetCommand(ctx context.Context, name string) (*models.BotCommand, error) {
+	var command models.BotCommand
+	err := r.db.WithContext(ctx).
+		Preload("Bot").
+		Where("name = ?", name).
+		First(&command).Error
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (r *BotRepository) GetCommands(ctx context.Context) ([]*models.BotCommand, error) {
+	var commands []*models.BotCommand
+	err := r.db.WithContext(ctx).
+		Order("name ASC").
+		Find(&commands).Error
+	return commands, err
+}
+
+func (r *BotRepository) GetCommandsByBotIDs(ctx context.Context, botIDs []int) ([]*models.BotCommand, error) {
+	var commands []*models.BotCommand
+	if len(botIDs) == 0 {
+		return commands, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("bot_id IN ?", botIDs).
+		Order("name ASC").
+		Find(&commands).Error
+	return commands, err
+}
+
+// TakenCommands returns which of names are already registered.
+func (r *BotRepository) TakenCommands(ctx context.Context, names []string) ([]string, error) {
+	var taken []string
+	if len(names) == 0 {
+		return taken, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&models.BotCommand{}).
+		Where("name IN ?", names).
+		Pluck("name", &taken).Error
+	return taken, err
+}
+
+func (r *BotRepository) Delete(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).
+		Where("is_bot").
+		Delete(&models.User{}, id).Error
+}
@@ -3,6 +3,7 @@ package chat
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/AlGrushino/chat/internal/repository/models"
 	"gorm.io/gorm"
@@ -35,15 +36,98 @@ func (r *ChatRepository) ChatExists(ctx context.Context, title string) (bool, er
 
 	err := r.db.WithContext(ctx).
 		Model(&models.Chat{}).
-		Where("title = ?", title).
+		Where("type = ? AND title = ?", models.ChatTypeGroup, title).
 		Count(&count).Error
 
 	return count > 0, err
 }
 
-func (r *ChatRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Chat, error) {
+const (
+	SortCreated  = "created"
+	SortActivity = "activity"
+)
+
+// Filter narrows down GetAll; zero fields match every chat. Query matches
+// the title, topic and description case-insensitively. SortActivity puts
+// the chats with the most recent messages first, SortCreated the newest
+// chats.
+type Filter struct {
+	Type       string
+	Visibility string
+	Query      string
+	Sort       string
+	Limit      int
+	Offset     int
+}
+
+func (r *ChatRepository) GetAll(ctx context.Context, filter Filter) ([]*models.Chat, error) {
+	query := r.db.WithContext(ctx).Model(&models.Chat{})
+
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	if filter.Visibility != "" {
+		query = query.Where("visibility = ?", filter.Visibility)
+	}
+
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		query = query.Where("title ILIKE ? OR topic ILIKE ? OR description ILIKE ?", pattern, pattern, pattern)
+	}
+
+	if filter.Sort == SortActivity {
+		query = query.Order("(SELECT MAX(messages.created_at) FROM messages WHERE messages.chat_id = chats.id) DESC NULLS LAST")
+	}
+
+	var chats []*models.Chat
+	err := query.
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&chats).Error
+	return chats, err
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *ChatRepository) GetByDMKey(ctx context.Context, key string) (*models.Chat, error) {
+	var chat models.Chat
+	err := r.db.WithContext(ctx).
+		Where("dm_key = ?", key).
+		First(&chat).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+// CreateDirect stores a direct chat together with its participants.
+func (r *ChatRepository) CreateDirect(ctx context.Context, chat *models.Chat, userIDs []int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(chat).Error; err != nil {
+			return err
+		}
+
+		members := make([]models.ChatMember, 0, len(userIDs))
+		for _, userID := range userIDs {
+			members = append(members, models.ChatMember{ChatID: chat.ID, UserID: userID})
+		}
+
+		return tx.Omit("User").Create(&members).Error
+	})
+}
+
+// GetForUser lists public group chats plus the chats userID owns or takes
+// part in.
+func (r *ChatRepository) GetForUser(ctx context.Context, userID, limit, offset int) ([]*models.Chat, error) {
 	var chats []*models.Chat
 	err := r.db.WithContext(ctx).
+		Where("(type = ? AND visibility = ?) OR owner_id = ? OR id IN (?)",
+			models.ChatTypeGroup, models.VisibilityPublic, userID,
+			r.db.Model(&models.ChatMember{}).Select("chat_id").Where("user_id = ?", userID)).
 		Limit(limit).
 		Offset(offset).
 		Order("created_at DESC").
@@ -51,10 +135,73 @@ func (r *ChatRepository) GetAll(ctx context.Context, limit, offset int) ([]*mode
 	return chats, err
 }
 
+// History is a chat together with the messages to import into it.
+type History struct {
+	Chat     *models.Chat
+	Messages []*models.Message
+}
+
+// Import stores chats with their messages in a single transaction, inserting
+// messages batchSize rows at a time. GORM only fills in autoCreateTime
+// columns that are zero, so the original CreatedAt values are kept.
+func (r *ChatRepository) Import(ctx context.Context, histories []History, batchSize int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, history := range histories {
+			if err := tx.Create(history.Chat).Error; err != nil {
+				return fmt.Errorf("failed to create chat %q: %w", history.Chat.Title, err)
+			}
+
+			if len(history.Messages) == 0 {
+				continue
+			}
+
+			for _, message := range history.Messages {
+				message.ChatID = history.Chat.ID
+			}
+
+			err := tx.Omit("Chat", "Author", "Attachments", "Mentions").
+				CreateInBatches(history.Messages, batchSize).Error
+			if err != nil {
+				return fmt.Errorf("failed to import messages of chat %q: %w", history.Chat.Title, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (r *ChatRepository) Update(ctx context.Context, chat *models.Chat) error {
 	return r.db.WithContext(ctx).Save(chat).Error
 }
 
+func (r *ChatRepository) UpdateTopic(ctx context.Context, id int, topic string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("id = ?", id).
+		Update("topic", topic).Error
+}
+
+// UpdateMetadata saves the editable descriptive fields of the chat.
+func (r *ChatRepository) UpdateMetadata(ctx context.Context, chat *models.Chat) error {
+	return r.db.WithContext(ctx).
+		Model(chat).
+		Select("title", "description", "topic", "avatar_id", "visibility", "settings").
+		Updates(chat).Error
+}
+
+func (r *ChatRepository) UpdateSlowMode(ctx context.Context, id, seconds int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("id = ?", id).
+		Update("slow_mode_seconds", seconds).Error
+}
+
+func (r *ChatRepository) UpdateRetention(ctx context.Context, id int, days *int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Chat{}).
+		Where("id = ?", id).
+		Update("retention_days", days).Error
+}
+
 func (r *ChatRepository) Delete(ctx context.Context, id int) error {
 	result := r.db.WithContext(ctx).Delete(&models.Chat{}, id)
 	return result.Error
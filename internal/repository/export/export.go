@@ -0,0 +1,107 @@
+package export
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func (r *ExportRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).Omit("Chat", "User").Create(job).Error
+}
+
+func (r *ExportRepository) GetByID(ctx context.Context, id int) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimPending locks pending jobs that no worker holds and claims them for
+// lease, counting the attempt. Jobs of a crashed worker are picked up again
+// once the lease runs out.
+func (r *ExportRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*models.ExportJob, error) {
+	var jobs []*models.ExportJob
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND (claimed_until IS NULL OR claimed_until <= ?)", models.ExportPending, now).
+			Order("created_at ASC").
+			Limit(limit).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(jobs))
+		claimedUntil := now.Add(lease)
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+			job.Attempts++
+			job.ClaimedUntil = &claimedUntil
+		}
+
+		return tx.Model(&models.ExportJob{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{
+				"attempts":      gorm.Expr("attempts + 1"),
+				"claimed_until": claimedUntil,
+			}).Error
+	})
+
+	return jobs, err
+}
+
+func (r *ExportRepository) Complete(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ExportJob{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]any{
+			"status":        models.ExportSucceeded,
+			"claimed_until": nil,
+			"blob_key":      job.BlobKey,
+			"size":          job.Size,
+			"messages":      job.Messages,
+			"last_error":    nil,
+			"completed_at":  job.CompletedAt,
+		}).Error
+}
+
+// Release hands a failed job back to the queue for another attempt once
+// job.ClaimedUntil has passed.
+func (r *ExportRepository) Release(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ExportJob{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]any{
+			"claimed_until": job.ClaimedUntil,
+			"last_error":    job.LastError,
+		}).Error
+}
+
+func (r *ExportRepository) Fail(ctx context.Context, job *models.ExportJob) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ExportJob{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]any{
+			"status":        models.ExportFailed,
+			"claimed_until": nil,
+			"last_error":    job.LastError,
+			"completed_at":  job.CompletedAt,
+		}).Error
+}
@@ -0,0 +1,65 @@
+package hook
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+)
+
+type HookRepository struct {
+	db *gorm.DB
+}
+
+func NewHookRepository(db *gorm.DB) *HookRepository {
+	return &HookRepository{db: db}
+}
+
+func (r *HookRepository) Create(ctx context.Context, hook *models.IncomingHook) error {
+	return r.db.WithContext(ctx).Create(hook).Error
+}
+
+func (r *HookRepository) GetByID(ctx context.Context, id int) (*models.IncomingHook, error) {
+	var hook models.IncomingHook
+	err := r.db.WithContext(ctx).First(&hook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// GetActiveByTokenHash returns a hook that has not been revoked.
+func (r *HookRepository) GetActiveByTokenHash(ctx context.Context, tokenHash string) (*models.IncomingHook, error) {
+	var hook models.IncomingHook
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL", tokenHash).
+		First(&hook).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *HookRepository) GetByChatAndUser(ctx context.Context, chatID, userID int) ([]*models.IncomingHook, error) {
+	var hooks []*models.IncomingHook
+	err := r.db.WithContext(ctx).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Order("id ASC").
+		Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *HookRepository) UpdateToken(ctx context.Context, id int, tokenHash string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.IncomingHook{}).
+		Where("id = ?", id).
+		Update("token_hash", tokenHash).Error
+}
+
+func (r *HookRepository) Revoke(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.IncomingHook{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve stores record unless its key is already taken. Expired keys and
+// reservations older than staleBefore that never completed are taken over.
+// It reports whether the key is now reserved for record.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	now := time.Now()
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"request_hash": record.RequestHash,
+				"status":       0,
+				"content_type": "",
+				"body":         nil,
+				"created_at":   now,
+				"expires_at":   record.ExpiresAt,
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Or(
+					clause.Expr{SQL: "idempotency_keys.expires_at < ?", Vars: []any{now}},
+					clause.Expr{SQL: "idempotency_keys.status = 0 AND idempotency_keys.created_at < ?", Vars: []any{staleBefore}},
+				),
+			}},
+		}).
+		Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.WithContext(ctx).
+		Where("scope = ? AND key = ?", scope, key).
+		First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete stores the response of a reserved key. A non-zero expiresAt
+// brings the key's expiry forward, never back.
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	updates := map[string]any{
+		"status":       status,
+		"content_type": contentType,
+		"body":         body,
+	}
+	if !expiresAt.IsZero() {
+		updates["expires_at"] = gorm.Expr("LEAST(expires_at, ?)", expiresAt)
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Updates(updates).Error
+}
+
+// Release drops a reservation that has not completed.
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	return r.db.WithContext(ctx).
+		Where("scope = ? AND key = ? AND status = 0", scope, key).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,100 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnusable is returned by Accept for invites that are revoked, expired or
+// used up.
+var ErrUnusable = errors.New("invite is no longer valid")
+
+type InviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) Create(ctx context.Context, invite *models.ChatInvite) error {
+	return r.db.WithContext(ctx).Omit("Chat").Create(invite).Error
+}
+
+func (r *InviteRepository) GetByChatID(ctx context.Context, chatID int) ([]*models.ChatInvite, error) {
+	var invites []*models.ChatInvite
+	err := r.db.WithContext(ctx).
+		Where("chat_id = ?", chatID).
+		Order("id DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+func (r *InviteRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.ChatInvite, error) {
+	var invite models.ChatInvite
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", tokenHash).
+		First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Revoke revokes an invite of the chat and reports whether it was active.
+func (r *InviteRepository) Revoke(ctx context.Context, chatID, id int) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.ChatInvite{}).
+		Where("id = ? AND chat_id = ? AND revoked_at IS NULL", id, chatID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// Accept makes userID a member of the chat of the invite with tokenHash and
+// reports whether they joined just now. Only new members use the invite
+// up. The invite row is locked so that concurrent accepts cannot go over
+// MaxUses.
+func (r *InviteRepository) Accept(ctx context.Context, tokenHash string, userID int) (*models.ChatInvite, bool, error) {
+	var invite models.ChatInvite
+	joined := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(&invite).Error
+		if err != nil {
+			return err
+		}
+
+		if !invite.Usable(time.Now()) {
+			return ErrUnusable
+		}
+
+		result := tx.Omit("User").
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ChatMember{ChatID: invite.ChatID, UserID: userID})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		joined = true
+		invite.Uses++
+
+		return tx.Model(&models.ChatInvite{}).
+			Where("id = ?", invite.ID).
+			Update("uses", gorm.Expr("uses + 1")).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &invite, joined, nil
+}
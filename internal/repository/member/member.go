@@ -0,0 +1,115 @@
+package member
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MemberRepository struct {
+	db *gorm.DB
+}
+
+func NewMemberRepository(db *gorm.DB) *MemberRepository {
+	return &MemberRepository{db: db}
+}
+
+func (r *MemberRepository) IsMember(ctx context.Context, chatID, userID int) (bool, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Model(&models.ChatMember{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// Add makes userID a member of the chat and reports whether they were not
+// one already.
+func (r *MemberRepository) Add(ctx context.Context, chatID, userID int) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Omit("User").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ChatMember{ChatID: chatID, UserID: userID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Remove deletes the membership and reports whether there was one.
+func (r *MemberRepository) Remove(ctx context.Context, chatID, userID int) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Delete(&models.ChatMember{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountByChatIDs returns the number of members of each chat. Chats without
+// members are left out.
+func (r *MemberRepository) CountByChatIDs(ctx context.Context, chatIDs []int) (map[int]int64, error) {
+	counts := make(map[int]int64, len(chatIDs))
+	if len(chatIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ChatID int
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.ChatMember{}).
+		Select("chat_id, COUNT(*) AS count").
+		Where("chat_id IN ?", chatIDs).
+		Group("chat_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ChatID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetPeers returns the members of the given chats other than userID, with
+// their users preloaded. It is meant for direct chats, which have at most one
+// such member each.
+func (r *MemberRepository) GetPeers(ctx context.Context, chatIDs []int, userID int) ([]*models.ChatMember, error) {
+	var members []*models.ChatMember
+	if len(chatIDs) == 0 {
+		return members, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("chat_id IN ? AND user_id <> ?", chatIDs, userID).
+		Find(&members).Error
+	return members, err
+}
+
+// GetByChatIDs returns up to limit members of each chat ordered by user ID,
+// with their users preloaded. A positive afterUserID skips members up to and
+// including that user.
+func (r *MemberRepository) GetByChatIDs(ctx context.Context, chatIDs []int, afterUserID, limit int) ([]*models.ChatMember, error) {
+	var members []*models.ChatMember
+	if len(chatIDs) == 0 {
+		return members, nil
+	}
+
+	ranked := r.db.Model(&models.ChatMember{}).
+		Select("chat_members.*, row_number() OVER (PARTITION BY chat_id ORDER BY user_id ASC) AS row_rank").
+		Where("chat_id IN ?", chatIDs)
+	if afterUserID > 0 {
+		ranked = ranked.Where("user_id > ?", afterUserID)
+	}
+
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Table("(?) AS chat_members", ranked).
+		Where("row_rank <= ?", limit).
+		Order("chat_id ASC, user_id ASC").
+		Find(&members).Error
+	return members, err
+}
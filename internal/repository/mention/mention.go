@@ -0,0 +1,89 @@
+package mention
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+)
+
+type MentionRepository struct {
+	db *gorm.DB
+}
+
+func NewMentionRepository(db *gorm.DB) *MentionRepository {
+	return &MentionRepository{db: db}
+}
+
+// GetByUserID returns the newest mentions of userID in messages written by
+// someone else, with the message and its author preloaded.
+func (r *MentionRepository) GetByUserID(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]*models.Mention, error) {
+	var mentions []*models.Mention
+
+	query := r.db.WithContext(ctx).
+		Preload("Message").
+		Preload("Message.Author").
+		Joins("JOIN messages ON messages.id = mentions.message_id").
+		Scopes(models.Unexpired).
+		Where("mentions.user_id = ?", userID).
+		Where("messages.author_id IS DISTINCT FROM ?", userID)
+
+	if unreadOnly {
+		query = query.Where("mentions.read_at IS NULL")
+	}
+
+	err := query.
+		Order("mentions.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&mentions).Error
+	return mentions, err
+}
+
+// MarkRead marks the user's mentions as read. A zero upToID marks all of them.
+func (r *MentionRepository) MarkRead(ctx context.Context, userID, upToID int) (int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.Mention{}).
+		Where("user_id = ? AND read_at IS NULL", userID)
+
+	if upToID > 0 {
+		query = query.Where("id <= ?", upToID)
+	}
+
+	result := query.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// CountUnreadByChatIDs counts the user's unread mentions in each of the given
+// chats. Chats without unread mentions are left out.
+func (r *MentionRepository) CountUnreadByChatIDs(ctx context.Context, userID int, chatIDs []int) (map[int]int, error) {
+	counts := make(map[int]int, len(chatIDs))
+	if len(chatIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ChatID int
+		Count  int
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&models.Mention{}).
+		Select("messages.chat_id, COUNT(*) AS count").
+		Joins("JOIN messages ON messages.id = mentions.message_id").
+		Scopes(models.Unexpired).
+		Where("mentions.user_id = ? AND mentions.read_at IS NULL", userID).
+		Where("messages.author_id IS DISTINCT FROM ?", userID).
+		Where("messages.chat_id IN ?", chatIDs).
+		Group("messages.chat_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ChatID] = row.Count
+	}
+	return counts, nil
+}
@@ -2,11 +2,26 @@ package message
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/AlGrushino/chat/internal/events"
 	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrAttachmentUnavailable = errors.New("attachment is missing or already used")
+	ErrDuplicateClientMsgID  = errors.New("client message id is already used in this chat")
+	ErrTooManyPins           = errors.New("chat has too many pinned messages")
+)
+
+const clientMsgIDIndex = "uq_messages_chat_client_msg_id"
+
 type MessageRepository struct {
 	db *gorm.DB
 }
@@ -15,17 +30,104 @@ func NewMessageRepository(db *gorm.DB) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
+// Create stores the message together with its mentions.
 func (r *MessageRepository) Create(ctx context.Context, message *models.Message) error {
-	return r.db.WithContext(ctx).Create(message).Error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(message).Error
+	})
+	return translateError(err)
+}
+
+func (r *MessageRepository) CreateWithAttachments(ctx context.Context, message *models.Message, attachmentIDs []int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Attachments").Create(message).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Attachment{}).
+			Where("id IN ? AND chat_id = ? AND message_id IS NULL", attachmentIDs, message.ChatID).
+			Update("message_id", message.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected != int64(len(attachmentIDs)) {
+			return fmt.Errorf("%w: expected %d, attached %d", ErrAttachmentUnavailable, len(attachmentIDs), result.RowsAffected)
+		}
+
+		return tx.Where("message_id = ?", message.ID).Order("id ASC").Find(&message.Attachments).Error
+	})
+	return translateError(err)
+}
+
+func (r *MessageRepository) GetByClientMsgID(ctx context.Context, chatID int, clientMsgID string) (*models.Message, error) {
+	var message models.Message
+	err := r.db.WithContext(ctx).
+		Scopes(models.Unexpired).
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Mentions.User").
+		Where("chat_id = ? AND client_msg_id = ?", chatID, clientMsgID).
+		First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
 }
 
+// GetByChatID returns a page of the chat's messages, newest first.
 func (r *MessageRepository) GetByChatID(ctx context.Context, chatID int, limit, offset int) ([]*models.Message, error) {
 	var messages []*models.Message
 	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Scopes(models.Unexpired).
 		Where("chat_id = ?", chatID).
 		Limit(limit).
 		Offset(offset).
-		Order("created_at ASC").
+		Order("created_at DESC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetLatestByChatIDs returns up to limit messages of each chat, newest
+// first, with attachments and mentioned users preloaded. A positive beforeID
+// only returns messages older than that message.
+func (r *MessageRepository) GetLatestByChatIDs(ctx context.Context, chatIDs []int, beforeID, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	if len(chatIDs) == 0 {
+		return messages, nil
+	}
+
+	ranked := r.db.Model(&models.Message{}).
+		Select("messages.*, row_number() OVER (PARTITION BY chat_id ORDER BY id DESC) AS row_rank").
+		Scopes(models.Unexpired).
+		Where("chat_id IN ?", chatIDs)
+	if beforeID > 0 {
+		ranked = ranked.Where("id < ?", beforeID)
+	}
+
+	err := r.db.WithContext(ctx).
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Mentions.User").
+		Table("(?) AS messages", ranked).
+		Where("row_rank <= ?", limit).
+		Order("chat_id ASC, id DESC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetAfterID returns up to limit messages of the chat with IDs above
+// afterID, oldest first, with authors and attachments preloaded. Passing the
+// last ID of a page fetches the next one.
+func (r *MessageRepository) GetAfterID(ctx context.Context, chatID, afterID, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Scopes(models.Unexpired).
+		Where("chat_id = ? AND id > ?", chatID, afterID).
+		Order("id ASC").
+		Limit(limit).
 		Find(&messages).Error
 	return messages, err
 }
@@ -41,6 +143,216 @@ func (r *MessageRepository) GetByID(ctx context.Context, id int) (*models.Messag
 	return &message, nil
 }
 
+// Pin pins a message of the chat for userID unless the chat already has
+// maxPins pinned messages. Pinning a pinned message changes nothing. The chat
+// row is locked so that concurrent pins cannot go over the limit.
+func (r *MessageRepository) Pin(ctx context.Context, chatID, messageID, userID, maxPins int) (*models.Message, error) {
+	var message models.Message
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var chat models.Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&chat, chatID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Preload("Author").
+			Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Scopes(models.Unexpired).
+			Where("chat_id = ?", chatID).
+			First(&message, messageID).Error
+		if err != nil || message.PinnedAt != nil {
+			return err
+		}
+
+		var pinned int64
+		err = tx.Model(&models.Message{}).
+			Where("chat_id = ? AND pinned_at IS NOT NULL", chatID).
+			Count(&pinned).Error
+		if err != nil {
+			return err
+		}
+
+		if pinned >= int64(maxPins) {
+			return ErrTooManyPins
+		}
+
+		now := time.Now()
+		message.PinnedAt = &now
+		message.PinnedByID = &userID
+		return tx.Model(&models.Message{}).
+			Where("id = ?", messageID).
+			Updates(map[string]any{
+				"pinned_at":    now,
+				"pinned_by_id": userID,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// Unpin unpins a message of the chat and reports whether it was pinned.
+func (r *MessageRepository) Unpin(ctx context.Context, chatID, messageID int) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Message{}).
+		Where("id = ? AND chat_id = ? AND pinned_at IS NOT NULL", messageID, chatID).
+		Updates(map[string]any{
+			"pinned_at":    nil,
+			"pinned_by_id": nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetPinned returns the pinned messages of the chat, most recently pinned
+// first, with authors and attachments preloaded.
+func (r *MessageRepository) GetPinned(ctx context.Context, chatID int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Scopes(models.Unexpired).
+		Where("chat_id = ? AND pinned_at IS NOT NULL", chatID).
+		Order("pinned_at DESC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// DeleteExpired deletes up to limit messages older than the retention of
+// their chat, with defaultDays for chats without their own, and returns how
+// many were deleted along with their attachments, whose rows go with them
+// but whose blobs are left to the caller. Rows locked by other transactions
+// are skipped, so a batch never waits on writers.
+func (r *MessageRepository) DeleteExpired(ctx context.Context, defaultDays, limit int) (int64, []models.Attachment, error) {
+	var (
+		deleted     int64
+		attachments []models.Attachment
+	)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []int
+		err := tx.Model(&models.Message{}).
+			Joins("JOIN chats ON chats.id = messages.chat_id").
+			Where("COALESCE(chats.retention_days, ?) > 0", defaultDays).
+			Where("messages.created_at < NOW() - COALESCE(chats.retention_days, ?) * INTERVAL '1 day'", defaultDays).
+			Order("messages.id ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "messages"}, Options: "SKIP LOCKED"}).
+			Pluck("messages.id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if err := tx.Where("message_id IN ?", ids).Find(&attachments).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id IN ?", ids).Delete(&models.Message{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return deleted, attachments, nil
+}
+
+// DeleteEphemeral deletes up to limit messages whose ExpiresAt has passed
+// and returns them with their chats and attachments. Like DeleteExpired, it
+// skips locked rows and leaves the attachment blobs to the caller. The text
+// of the messages is blanked in the same transaction in the stored webhook
+// payloads, which would otherwise outlive them.
+func (r *MessageRepository) DeleteEphemeral(ctx context.Context, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("expires_at <= NOW()").
+			Order("id ASC").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(messages))
+		chatIDs := make([]int, 0, len(messages))
+		for _, message := range messages {
+			ids = append(ids, message.ID)
+			chatIDs = append(chatIDs, message.ChatID)
+		}
+
+		var chats []models.Chat
+		if err := tx.Where("id IN ?", chatIDs).Find(&chats).Error; err != nil {
+			return err
+		}
+
+		var attachments []models.Attachment
+		if err := tx.Where("message_id IN ?", ids).Order("id ASC").Find(&attachments).Error; err != nil {
+			return err
+		}
+
+		byChat := make(map[int]models.Chat, len(chats))
+		for _, chat := range chats {
+			byChat[chat.ID] = chat
+		}
+		byMessage := make(map[int][]models.Attachment, len(attachments))
+		for _, attachment := range attachments {
+			byMessage[*attachment.MessageID] = append(byMessage[*attachment.MessageID], attachment)
+		}
+		for _, message := range messages {
+			message.Chat = byChat[message.ChatID]
+			message.Attachments = byMessage[message.ID]
+		}
+
+		if err := redactWebhookPayloads(tx, ids); err != nil {
+			return err
+		}
+
+		return tx.Where("id IN ?", ids).Delete(&models.Message{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// redactWebhookPayloads blanks the text of the given messages in the
+// message.created payloads of webhook deliveries and dead letters.
+func redactWebhookPayloads(tx *gorm.DB, messageIDs []int) error {
+	ids := make([]string, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+
+	redacted := gorm.Expr(`jsonb_set(payload, '{data,text}', '""')`)
+
+	err := tx.Model(&models.WebhookDelivery{}).
+		Where("event = ? AND payload #>> '{data,id}' IN ?", events.MessageCreated, ids).
+		Update("payload", redacted).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.WebhookDeadLetter{}).
+		Where("event = ? AND payload #>> '{data,id}' IN ?", events.MessageCreated, ids).
+		Update("payload", redacted).Error
+}
+
 func (r *MessageRepository) Delete(ctx context.Context, id int) error {
 	return r.db.WithContext(ctx).Delete(&models.Message{}, id).Error
 }
+
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == clientMsgIDIndex {
+		return ErrDuplicateClientMsgID
+	}
+	return err
+}
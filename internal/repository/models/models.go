@@ -7,19 +7,137 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	ChatTypeGroup  = "group"
+	ChatTypeDirect = "direct"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// Chat is a group or direct conversation. OwnerID is the user who created a
+// group chat, if any. SlowModeSeconds is the minimum interval between two
+// messages of the same user; zero disables slow mode. RetentionDays is how
+// long messages are kept: nil uses the global default, zero keeps them
+// forever. AvatarID is an image attachment uploaded to the chat. Settings
+// holds free-form client settings.
 type Chat struct {
-	ID        int       `gorm:"primaryKey"`
-	Title     string    `gorm:"size:200;not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID              int            `gorm:"primaryKey"`
+	Title           string         `gorm:"size:200;not null"`
+	Type            string         `gorm:"size:20;not null;default:group"`
+	DMKey           *string        `gorm:"column:dm_key;size:50;uniqueIndex"`
+	Topic           string         `gorm:"size:250;not null;default:''"`
+	Description     string         `gorm:"size:1000;not null;default:''"`
+	AvatarID        *int           `gorm:"index"`
+	Visibility      string         `gorm:"size:20;not null;default:public"`
+	Settings        map[string]any `gorm:"serializer:json;type:jsonb;not null;default:'{}'"`
+	OwnerID         *int           `gorm:"index"`
+	SlowModeSeconds int            `gorm:"not null;default:0"`
+	RetentionDays   *int           `gorm:"check:retention_days >= 0"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime"`
+}
+
+func (c *Chat) IsDirect() bool {
+	return c.Type == ChatTypeDirect
+}
+
+// User is a person or, when IsBot is set, a bot account owned by OwnerID.
+// Bots receive slash commands at CallbackURL, signed with CallbackSecret.
+type User struct {
+	ID             int       `gorm:"primaryKey"`
+	Username       string    `gorm:"size:32;not null"`
+	TokenHash      string    `gorm:"size:64;not null;uniqueIndex"`
+	IsBot          bool      `gorm:"not null;default:false"`
+	OwnerID        *int      `gorm:"index"`
+	CallbackURL    *string   `gorm:"size:2000"`
+	CallbackSecret *string   `gorm:"size:64"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
 }
 
+type BotCommand struct {
+	Name        string `gorm:"primaryKey;size:32"`
+	BotID       int    `gorm:"not null;index"`
+	Description string `gorm:"size:200;not null;default:''"`
+
+	Bot *User `gorm:"foreignKey:BotID;constraint:OnDelete:CASCADE;"`
+}
+
+type ChatMember struct {
+	ChatID   int       `gorm:"primaryKey"`
+	UserID   int       `gorm:"primaryKey"`
+	JoinedAt time.Time `gorm:"autoCreateTime"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+// Message.AuthorName is the display name of authors without a user account,
+// such as incoming webhooks. ClientMsgID is an optional UUID chosen by the
+// sending client, unique within the chat. A message with ExpiresAt is hidden
+// once that time passes and deleted soon after. PinnedAt is set while the
+// message is pinned to its chat, by PinnedByID.
 type Message struct {
-	ID        int       `gorm:"primaryKey"`
-	ChatID    int       `gorm:"not null"`
-	Text      string    `gorm:"size:5000;not null"`
+	ID          int       `gorm:"primaryKey"`
+	ChatID      int       `gorm:"not null"`
+	AuthorID    *int      `gorm:"index"`
+	AuthorName  *string   `gorm:"size:80"`
+	ClientMsgID *string   `gorm:"type:uuid"`
+	Text        string    `gorm:"size:5000;not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   *time.Time
+	PinnedAt    *time.Time
+	PinnedByID  *int
+
+	Chat        Chat         `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE;"`
+	Author      *User        `gorm:"foreignKey:AuthorID;constraint:OnDelete:SET NULL;"`
+	Attachments []Attachment `gorm:"foreignKey:MessageID"`
+	Mentions    []Mention    `gorm:"foreignKey:MessageID"`
+
+	// Ephemeral replies are shown to the sender only and never stored.
+	Ephemeral bool `gorm:"-"`
+}
+
+// Unexpired is a scope that leaves out messages past their ExpiresAt.
+func Unexpired(db *gorm.DB) *gorm.DB {
+	return db.Where("messages.expires_at IS NULL OR messages.expires_at > NOW()")
+}
+
+// Mention marks a "@username" reference inside a message. Offset and Length
+// are counted in Unicode code points of the message text.
+type Mention struct {
+	ID        int `gorm:"primaryKey"`
+	MessageID int `gorm:"not null;index"`
+	UserID    int `gorm:"not null"`
+	Offset    int `gorm:"column:offset;not null"`
+	Length    int `gorm:"not null"`
+	ReadAt    *time.Time
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 
-	Chat Chat `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE;"`
+	Message *Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE;"`
+	User    *User    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+const (
+	ThumbnailNone    = "none"
+	ThumbnailPending = "pending"
+	ThumbnailReady   = "ready"
+	ThumbnailFailed  = "failed"
+)
+
+type Attachment struct {
+	ID              int       `gorm:"primaryKey"`
+	ChatID          int       `gorm:"not null"`
+	MessageID       *int      `gorm:"index"`
+	Filename        string    `gorm:"size:255;not null"`
+	ContentType     string    `gorm:"size:100;not null"`
+	Size            int64     `gorm:"not null"`
+	BlobKey         string    `gorm:"size:255;not null"`
+	Width           int       `gorm:"not null;default:0"`
+	Height          int       `gorm:"not null;default:0"`
+	ThumbnailKey    *string   `gorm:"size:255"`
+	ThumbnailStatus string    `gorm:"size:20;not null;default:none"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
 }
 
 func (c *Chat) BeforeCreate(tx *gorm.DB) (err error) {
@@ -35,3 +153,216 @@ func (c *Chat) BeforeUpdate(tx *gorm.DB) (err error) {
 	}
 	return nil
 }
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+type WebhookSubscription struct {
+	ID        int       `gorm:"primaryKey"`
+	UserID    int       `gorm:"not null;index"`
+	ChatID    *int      `gorm:"index"`
+	URL       string    `gorm:"size:2000;not null"`
+	Secret    string    `gorm:"size:64;not null"`
+	Events    []string  `gorm:"serializer:json;type:jsonb;not null"`
+	Active    bool      `gorm:"not null;default:true"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type WebhookDelivery struct {
+	ID             int       `gorm:"primaryKey"`
+	SubscriptionID int       `gorm:"not null"`
+	Event          string    `gorm:"size:50;not null"`
+	Payload        string    `gorm:"type:jsonb;not null"`
+	Status         string    `gorm:"size:20;not null;default:pending"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"not null"`
+	ResponseStatus *int
+	LastError      *string   `gorm:"size:1000"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	DeliveredAt    *time.Time
+
+	Subscription *WebhookSubscription `gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE;"`
+}
+
+type WebhookDeadLetter struct {
+	ID             int       `gorm:"primaryKey"`
+	DeliveryID     int       `gorm:"not null"`
+	SubscriptionID int       `gorm:"not null"`
+	Event          string    `gorm:"size:50;not null"`
+	Payload        string    `gorm:"type:jsonb;not null"`
+	Attempts       int       `gorm:"not null"`
+	LastError      *string   `gorm:"size:1000"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+// IncomingHook lets external systems post into a chat with a secret token
+// instead of a user account. RateLimit is in messages per minute.
+type IncomingHook struct {
+	ID        int    `gorm:"primaryKey"`
+	ChatID    int    `gorm:"not null;index"`
+	UserID    int    `gorm:"not null"`
+	Name      string `gorm:"size:80;not null"`
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	RateLimit int    `gorm:"not null;default:30"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header. Keys are scoped per client. Status is zero while
+// the original request is still being processed.
+type IdempotencyKey struct {
+	Scope       string    `gorm:"primaryKey;size:64"`
+	Key         string    `gorm:"primaryKey;size:255"`
+	RequestHash string    `gorm:"size:64;not null"`
+	Status      int       `gorm:"not null;default:0"`
+	ContentType string    `gorm:"size:255;not null;default:''"`
+	Body        []byte    `gorm:"type:bytea"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (k *IdempotencyKey) Completed() bool {
+	return k.Status != 0
+}
+
+const (
+	ExportPending   = "pending"
+	ExportSucceeded = "succeeded"
+	ExportFailed    = "failed"
+)
+
+// ExportJob builds a downloadable archive of a chat's history in the
+// background for UserID. A worker holds a pending job until ClaimedUntil;
+// BlobKey points to the finished archive.
+type ExportJob struct {
+	ID           int    `gorm:"primaryKey"`
+	ChatID       int    `gorm:"not null;index"`
+	UserID       int    `gorm:"not null;index"`
+	Format       string `gorm:"size:10;not null"`
+	Status       string `gorm:"size:20;not null;default:pending"`
+	Attempts     int    `gorm:"not null;default:0"`
+	ClaimedUntil *time.Time
+	BlobKey      *string   `gorm:"size:255"`
+	Size         int64     `gorm:"not null;default:0"`
+	Messages     int       `gorm:"not null;default:0"`
+	LastError    *string   `gorm:"size:1000"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	CompletedAt  *time.Time
+
+	Chat *Chat `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE;"`
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+const (
+	ScheduledPending  = "pending"
+	ScheduledSent     = "sent"
+	ScheduledCanceled = "canceled"
+	ScheduledFailed   = "failed"
+)
+
+// ScheduledMessage is a message that UserID posts to ChatID at SendAt. A
+// worker holds a pending one until ClaimedUntil; MessageID is the posted
+// message. Posting goes through ClientMsgID, so a worker that crashes after
+// posting does not post it twice. ExpiresIn is in seconds.
+type ScheduledMessage struct {
+	ID            int       `gorm:"primaryKey"`
+	ChatID        int       `gorm:"not null;index"`
+	UserID        int       `gorm:"not null;index"`
+	Text          string    `gorm:"size:5000;not null"`
+	AttachmentIDs []int     `gorm:"serializer:json;type:jsonb;not null"`
+	ClientMsgID   string    `gorm:"type:uuid;not null"`
+	ExpiresIn     int       `gorm:"not null;default:0"`
+	SendAt        time.Time `gorm:"not null"`
+	Status        string    `gorm:"size:20;not null;default:pending"`
+	Attempts      int       `gorm:"not null;default:0"`
+	ClaimedUntil  *time.Time
+	MessageID     *int
+	LastError     *string   `gorm:"size:1000"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	CompletedAt   *time.Time
+
+	Chat *Chat `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE;"`
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+// ChatInvite lets whoever holds its token join ChatID. It stops working once
+// revoked, after ExpiresAt or after MaxUses people joined through it; nil
+// means no limit.
+type ChatInvite struct {
+	ID        int    `gorm:"primaryKey"`
+	ChatID    int    `gorm:"not null;index"`
+	CreatorID int    `gorm:"not null"`
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	MaxUses   *int   `gorm:"check:max_uses > 0"`
+	Uses      int    `gorm:"not null;default:0"`
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	Chat *Chat `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE;"`
+}
+
+// Usable reports whether the invite still lets people join at now.
+func (i *ChatInvite) Usable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+const (
+	RestrictionBan  = "ban"
+	RestrictionMute = "mute"
+)
+
+// ChatRestriction keeps UserID from joining ChatID (a ban) or from posting to
+// it (a mute) until ExpiresAt; nil means until it is lifted. A user has at
+// most one restriction of each kind per chat.
+type ChatRestriction struct {
+	ID          int    `gorm:"primaryKey"`
+	ChatID      int    `gorm:"not null;uniqueIndex:idx_chat_restrictions_chat_user_kind"`
+	UserID      int    `gorm:"not null;uniqueIndex:idx_chat_restrictions_chat_user_kind"`
+	Kind        string `gorm:"size:8;not null;uniqueIndex:idx_chat_restrictions_chat_user_kind"`
+	Reason      string `gorm:"size:500;not null;default:''"`
+	ModeratorID *int
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+// Active reports whether the restriction is still in force at now.
+func (r *ChatRestriction) Active(now time.Time) bool {
+	return r.ExpiresAt == nil || now.Before(*r.ExpiresAt)
+}
+
+const (
+	ModerationKick   = "kick"
+	ModerationBan    = "ban"
+	ModerationUnban  = "unban"
+	ModerationMute   = "mute"
+	ModerationUnmute = "unmute"
+)
+
+// ModerationAction is an entry of a chat's moderation log: ModeratorID did
+// Action to UserID. ExpiresAt is the end of a timed ban or mute.
+type ModerationAction struct {
+	ID          int `gorm:"primaryKey"`
+	ChatID      int `gorm:"not null;index"`
+	ModeratorID *int
+	UserID      *int
+	Action      string `gorm:"size:8;not null"`
+	Reason      string `gorm:"size:500;not null;default:''"`
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	Moderator *User `gorm:"foreignKey:ModeratorID;constraint:OnDelete:SET NULL;"`
+	User      *User `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL;"`
+}
@@ -0,0 +1,134 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// Restrict bans or mutes the user of restriction, replacing a restriction of
+// the same kind they already had, and records entry in the moderation log. A
+// ban also ends the user's membership of the chat.
+func (r *ModerationRepository) Restrict(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("User").
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}, {Name: "kind"}},
+				DoUpdates: clause.AssignmentColumns([]string{"reason", "moderator_id", "expires_at", "created_at"}),
+			}).
+			Create(restriction).Error
+		if err != nil {
+			return err
+		}
+
+		if restriction.Kind == models.RestrictionBan {
+			err := tx.Where("chat_id = ? AND user_id = ?", restriction.ChatID, restriction.UserID).
+				Delete(&models.ChatMember{}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Omit("Moderator", "User").Create(entry).Error
+	})
+}
+
+// Lift removes an active restriction of the given kind and records entry in
+// the moderation log. It reports whether there was one.
+func (r *ModerationRepository) Lift(ctx context.Context, chatID, userID int, kind string, entry *models.ModerationAction) (bool, error) {
+	lifted := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("chat_id = ? AND user_id = ? AND kind = ?", chatID, userID, kind).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Delete(&models.ChatRestriction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		lifted = true
+
+		return tx.Omit("Moderator", "User").Create(entry).Error
+	})
+
+	return lifted, err
+}
+
+// Kick ends the membership of the user of restriction, places restriction
+// to keep them out for a while and records entry in the moderation log. It
+// reports whether they were a member.
+func (r *ModerationRepository) Kick(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) (bool, error) {
+	kicked := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("chat_id = ? AND user_id = ?", restriction.ChatID, restriction.UserID).
+			Delete(&models.ChatMember{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		kicked = true
+
+		err := tx.Omit("User").
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}, {Name: "kind"}},
+				DoUpdates: clause.AssignmentColumns([]string{"reason", "moderator_id", "expires_at", "created_at"}),
+			}).
+			Create(restriction).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Omit("Moderator", "User").Create(entry).Error
+	})
+
+	return kicked, err
+}
+
+// GetActive returns the restrictions of userID in the chat that are in force
+// at now.
+func (r *ModerationRepository) GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error) {
+	var restrictions []*models.ChatRestriction
+	err := r.db.WithContext(ctx).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Find(&restrictions).Error
+	return restrictions, err
+}
+
+// GetRestrictions returns the restrictions of the given kind in force in the
+// chat at now, with their users preloaded, newest first.
+func (r *ModerationRepository) GetRestrictions(ctx context.Context, chatID int, kind string, now time.Time) ([]*models.ChatRestriction, error) {
+	var restrictions []*models.ChatRestriction
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("chat_id = ? AND kind = ?", chatID, kind).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at DESC, id DESC").
+		Find(&restrictions).Error
+	return restrictions, err
+}
+
+// GetLog returns the moderation log of the chat, newest first, with the
+// moderators and users preloaded.
+func (r *ModerationRepository) GetLog(ctx context.Context, chatID, limit, offset int) ([]*models.ModerationAction, error) {
+	var actions []*models.ModerationAction
+	err := r.db.WithContext(ctx).
+		Preload("Moderator").
+		Preload("User").
+		Where("chat_id = ?", chatID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&actions).Error
+	return actions, err
+}
@@ -2,37 +2,202 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/AlGrushino/chat/internal/repository/attachment"
+	"github.com/AlGrushino/chat/internal/repository/bot"
 	"github.com/AlGrushino/chat/internal/repository/chat"
+	"github.com/AlGrushino/chat/internal/repository/export"
+	"github.com/AlGrushino/chat/internal/repository/hook"
+	"github.com/AlGrushino/chat/internal/repository/idempotency"
+	"github.com/AlGrushino/chat/internal/repository/invite"
+	"github.com/AlGrushino/chat/internal/repository/member"
+	"github.com/AlGrushino/chat/internal/repository/mention"
 	"github.com/AlGrushino/chat/internal/repository/message"
 	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/repository/moderation"
+	"github.com/AlGrushino/chat/internal/repository/schedule"
+	"github.com/AlGrushino/chat/internal/repository/user"
+	"github.com/AlGrushino/chat/internal/repository/webhook"
 	"gorm.io/gorm"
 )
 
 type Message interface {
 	Create(ctx context.Context, message *models.Message) error
+	CreateWithAttachments(ctx context.Context, message *models.Message, attachmentIDs []int) error
 	GetByChatID(ctx context.Context, chatID int, limit, offset int) ([]*models.Message, error)
 	GetByID(ctx context.Context, id int) (*models.Message, error)
+	GetByClientMsgID(ctx context.Context, chatID int, clientMsgID string) (*models.Message, error)
+	GetLatestByChatIDs(ctx context.Context, chatIDs []int, beforeID, limit int) ([]*models.Message, error)
+	GetAfterID(ctx context.Context, chatID, afterID, limit int) ([]*models.Message, error)
+	DeleteExpired(ctx context.Context, defaultDays, limit int) (int64, []models.Attachment, error)
+	DeleteEphemeral(ctx context.Context, limit int) ([]*models.Message, error)
+	Pin(ctx context.Context, chatID, messageID, userID, maxPins int) (*models.Message, error)
+	Unpin(ctx context.Context, chatID, messageID int) (bool, error)
+	GetPinned(ctx context.Context, chatID int) ([]*models.Message, error)
 	Delete(ctx context.Context, id int) error
 }
 
 type Chat interface {
 	Create(ctx context.Context, chat *models.Chat) error
 	GetByID(ctx context.Context, id int) (*models.Chat, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*models.Chat, error)
+	GetAll(ctx context.Context, filter chat.Filter) ([]*models.Chat, error)
 	Update(ctx context.Context, chat *models.Chat) error
 	Delete(ctx context.Context, id int) error
 	ChatExists(ctx context.Context, title string) (bool, error)
+	GetByDMKey(ctx context.Context, key string) (*models.Chat, error)
+	CreateDirect(ctx context.Context, chat *models.Chat, userIDs []int) error
+	GetForUser(ctx context.Context, userID, limit, offset int) ([]*models.Chat, error)
+	UpdateTopic(ctx context.Context, id int, topic string) error
+	UpdateMetadata(ctx context.Context, chat *models.Chat) error
+	UpdateSlowMode(ctx context.Context, id, seconds int) error
+	UpdateRetention(ctx context.Context, id int, days *int) error
+	Import(ctx context.Context, histories []chat.History, batchSize int) error
+}
+
+type User interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*models.User, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.User, error)
+	GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+}
+
+type Mention interface {
+	GetByUserID(ctx context.Context, userID int, unreadOnly bool, limit, offset int) ([]*models.Mention, error)
+	MarkRead(ctx context.Context, userID, upToID int) (int64, error)
+	CountUnreadByChatIDs(ctx context.Context, userID int, chatIDs []int) (map[int]int, error)
+}
+
+type Member interface {
+	IsMember(ctx context.Context, chatID, userID int) (bool, error)
+	Add(ctx context.Context, chatID, userID int) (bool, error)
+	Remove(ctx context.Context, chatID, userID int) (bool, error)
+	CountByChatIDs(ctx context.Context, chatIDs []int) (map[int]int64, error)
+	GetPeers(ctx context.Context, chatIDs []int, userID int) ([]*models.ChatMember, error)
+	GetByChatIDs(ctx context.Context, chatIDs []int, afterUserID, limit int) ([]*models.ChatMember, error)
+}
+
+type Attachment interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, id int) (*models.Attachment, error)
+	GetPendingThumbnails(ctx context.Context, limit int) ([]*models.Attachment, error)
+	UpdateThumbnail(ctx context.Context, id int, key *string, status string) error
+}
+
+type Webhook interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error)
+	GetSubscriptionsByUserID(ctx context.Context, userID int) ([]*models.WebhookSubscription, error)
+	GetSubscriptionsForEvent(ctx context.Context, event string, chatID int) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	DeactivateForChat(ctx context.Context, chatID int) error
+	CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, delivery *models.WebhookDelivery) error
+	ScheduleRetry(ctx context.Context, delivery *models.WebhookDelivery) error
+	MarkDead(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, subscriptionID, limit, offset int) ([]*models.WebhookDelivery, error)
+}
+
+type Hook interface {
+	Create(ctx context.Context, hook *models.IncomingHook) error
+	GetByID(ctx context.Context, id int) (*models.IncomingHook, error)
+	GetActiveByTokenHash(ctx context.Context, tokenHash string) (*models.IncomingHook, error)
+	GetByChatAndUser(ctx context.Context, chatID, userID int) ([]*models.IncomingHook, error)
+	UpdateToken(ctx context.Context, id int, tokenHash string) error
+	Revoke(ctx context.Context, id int) error
+}
+
+type Bot interface {
+	Create(ctx context.Context, bot *models.User, commands []models.BotCommand) error
+	GetByOwnerID(ctx context.Context, ownerID int) ([]*models.User, error)
+	GetCommand(ctx context.Context, name string) (*models.BotCommand, error)
+	GetCommands(ctx context.Context) ([]*models.BotCommand, error)
+	GetCommandsByBotIDs(ctx context.Context, botIDs []int) ([]*models.BotCommand, error)
+	TakenCommands(ctx context.Context, names []string) ([]string, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type Idempotency interface {
+	Reserve(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (bool, error)
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyKey, error)
+	Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Export interface {
+	Create(ctx context.Context, job *models.ExportJob) error
+	GetByID(ctx context.Context, id int) (*models.ExportJob, error)
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]*models.ExportJob, error)
+	Complete(ctx context.Context, job *models.ExportJob) error
+	Release(ctx context.Context, job *models.ExportJob) error
+	Fail(ctx context.Context, job *models.ExportJob) error
+}
+
+type Schedule interface {
+	Create(ctx context.Context, scheduled *models.ScheduledMessage) error
+	GetByID(ctx context.Context, id int) (*models.ScheduledMessage, error)
+	GetPendingByUser(ctx context.Context, userID int) ([]*models.ScheduledMessage, error)
+	CountPendingByUser(ctx context.Context, userID int) (int64, error)
+	Cancel(ctx context.Context, id, userID int) (bool, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.ScheduledMessage, error)
+	MarkSent(ctx context.Context, scheduled *models.ScheduledMessage) error
+	Release(ctx context.Context, scheduled *models.ScheduledMessage) error
+	Fail(ctx context.Context, scheduled *models.ScheduledMessage) error
+}
+
+type Invite interface {
+	Create(ctx context.Context, invite *models.ChatInvite) error
+	GetByChatID(ctx context.Context, chatID int) ([]*models.ChatInvite, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.ChatInvite, error)
+	Revoke(ctx context.Context, chatID, id int) (bool, error)
+	Accept(ctx context.Context, tokenHash string, userID int) (*models.ChatInvite, bool, error)
+}
+
+type Moderation interface {
+	Restrict(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) error
+	Lift(ctx context.Context, chatID, userID int, kind string, entry *models.ModerationAction) (bool, error)
+	Kick(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) (bool, error)
+	GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error)
+	GetRestrictions(ctx context.Context, chatID int, kind string, now time.Time) ([]*models.ChatRestriction, error)
+	GetLog(ctx context.Context, chatID, limit, offset int) ([]*models.ModerationAction, error)
 }
 
 type Repository struct {
 	Chat
 	Message
+	Attachment
+	User
+	Member
+	Mention
+	Webhook
+	Hook
+	Bot
+	Idempotency
+	Export
+	Schedule
+	Invite
+	Moderation
 }
 
 func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{
-		Chat:    chat.NewChatRepository(db),
-		Message: message.NewMessageRepository(db),
+		Chat:        chat.NewChatRepository(db),
+		Message:     message.NewMessageRepository(db),
+		Attachment:  attachment.NewAttachmentRepository(db),
+		User:        user.NewUserRepository(db),
+		Member:      member.NewMemberRepository(db),
+		Mention:     mention.NewMentionRepository(db),
+		Webhook:     webhook.NewWebhookRepository(db),
+		Hook:        hook.NewHookRepository(db),
+		Bot:         bot.NewBotRepository(db),
+		Idempotency: idempotency.NewIdempotencyRepository(db),
+		Export:      export.NewExportRepository(db),
+		Schedule:    schedule.NewScheduleRepository(db),
+		Invite:      invite.NewInviteRepository(db),
+		Moderation:  moderation.NewModerationRepository(db),
 	}
 }
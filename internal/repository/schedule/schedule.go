@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.db.WithContext(ctx).Omit("Chat", "User").Create(scheduled).Error
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id int) (*models.ScheduledMessage, error) {
+	var scheduled models.ScheduledMessage
+	if err := r.db.WithContext(ctx).First(&scheduled, id).Error; err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+// GetPendingByUser returns the messages the user still has to send, due
+// first.
+func (r *ScheduleRepository) GetPendingByUser(ctx context.Context, userID int) ([]*models.ScheduledMessage, error) {
+	var scheduled []*models.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, models.ScheduledPending).
+		Order("send_at ASC, id ASC").
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+func (r *ScheduleRepository) CountPendingByUser(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.ScheduledMessage{}).
+		Where("user_id = ? AND status = ?", userID, models.ScheduledPending).
+		Count(&count).Error
+	return count, err
+}
+
+// Cancel cancels a pending message of the user unless a worker is sending it
+// right now, and reports whether it did.
+func (r *ScheduleRepository) Cancel(ctx context.Context, id, userID int) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&models.ScheduledMessage{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, models.ScheduledPending).
+		Where("claimed_until IS NULL OR claimed_until <= ?", now).
+		Updates(map[string]any{
+			"status":       models.ScheduledCanceled,
+			"completed_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClaimDue locks pending messages whose time has come and that no worker
+// holds, and claims them for lease, counting the attempt. Their authors are
+// preloaded. Messages of a crashed worker are picked up again once the lease
+// runs out.
+func (r *ScheduleRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.ScheduledMessage, error) {
+	var scheduled []*models.ScheduledMessage
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND send_at <= ?", models.ScheduledPending, now).
+			Where("claimed_until IS NULL OR claimed_until <= ?", now).
+			Order("send_at ASC").
+			Limit(limit).
+			Find(&scheduled).Error
+		if err != nil || len(scheduled) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(scheduled))
+		userIDs := make([]int, 0, len(scheduled))
+		claimedUntil := now.Add(lease)
+		for _, s := range scheduled {
+			ids = append(ids, s.ID)
+			userIDs = append(userIDs, s.UserID)
+			s.Attempts++
+			s.ClaimedUntil = &claimedUntil
+		}
+
+		var users []*models.User
+		if err := tx.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return err
+		}
+		byID := make(map[int]*models.User, len(users))
+		for _, user := range users {
+			byID[user.ID] = user
+		}
+		for _, s := range scheduled {
+			s.User = byID[s.UserID]
+		}
+
+		return tx.Model(&models.ScheduledMessage{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{
+				"attempts":      gorm.Expr("attempts + 1"),
+				"claimed_until": claimedUntil,
+			}).Error
+	})
+
+	return scheduled, err
+}
+
+func (r *ScheduleRepository) MarkSent(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ScheduledMessage{}).
+		Where("id = ?", scheduled.ID).
+		Updates(map[string]any{
+			"status":        models.ScheduledSent,
+			"claimed_until": nil,
+			"message_id":    scheduled.MessageID,
+			"last_error":    nil,
+			"completed_at":  scheduled.CompletedAt,
+		}).Error
+}
+
+// Release hands a message back to the queue for another attempt once
+// scheduled.ClaimedUntil has passed.
+func (r *ScheduleRepository) Release(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ScheduledMessage{}).
+		Where("id = ?", scheduled.ID).
+		Updates(map[string]any{
+			"claimed_until": scheduled.ClaimedUntil,
+			"last_error":    scheduled.LastError,
+		}).Error
+}
+
+func (r *ScheduleRepository) Fail(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ScheduledMessage{}).
+		Where("id = ?", scheduled.ID).
+		Updates(map[string]any{
+			"status":        models.ScheduledFailed,
+			"claimed_until": nil,
+			"last_error":    scheduled.LastError,
+			"completed_at":  scheduled.CompletedAt,
+		}).Error
+}
@@ -0,0 +1,81 @@
+package user
+
+import (
+	"context"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("id IN ?", ids).
+		Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", tokenHash).
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	var users []*models.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	lowered := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		lowered = append(lowered, strings.ToLower(username))
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("LOWER(username) IN ?", lowered).
+		Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("LOWER(username) = LOWER(?)", username).
+		Count(&count).Error
+
+	return count > 0, err
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.WithContext(ctx).First(&subscription, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *WebhookRepository) GetSubscriptionsByUserID(ctx context.Context, userID int) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetSubscriptionsForEvent returns active subscriptions interested in event,
+// either global ones or those bound to chatID.
+func (r *WebhookRepository) GetSubscriptionsForEvent(ctx context.Context, event string, chatID int) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+	err := r.db.WithContext(ctx).
+		Where("active AND (chat_id IS NULL OR chat_id = ?)", chatID).
+		Where("events @> ?::jsonb", fmt.Sprintf("[%q]", event)).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id int) error {
+	return r.db.WithContext(ctx).Delete(&models.WebhookSubscription{}, id).Error
+}
+
+func (r *WebhookRepository) DeactivateForChat(ctx context.Context, chatID int) error {
+	return r.db.WithContext(ctx).
+		Model(&models.WebhookSubscription{}).
+		Where("chat_id = ?", chatID).
+		Update("active", false).Error
+}
+
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Subscription").Create(&deliveries).Error
+}
+
+// ClaimDueDeliveries locks pending deliveries that are due and pushes their
+// next attempt forward by lease, so other instances skip them while this one
+// is sending. A crashed sender's deliveries become due again after the lease.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	if err != nil || len(deliveries) == 0 {
+		return deliveries, err
+	}
+
+	subscriptionIDs := make([]int, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		subscriptionIDs = append(subscriptionIDs, delivery.SubscriptionID)
+	}
+
+	var subscriptions []*models.WebhookSubscription
+	if err := r.db.WithContext(ctx).Where("id IN ?", subscriptionIDs).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*models.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byID[subscription.ID] = subscription
+	}
+
+	for _, delivery := range deliveries {
+		delivery.Subscription = byID[delivery.SubscriptionID]
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]any{
+			"status":          models.DeliverySucceeded,
+			"attempts":        delivery.Attempts,
+			"response_status": delivery.ResponseStatus,
+			"last_error":      nil,
+			"delivered_at":    now,
+		}).Error
+}
+
+func (r *WebhookRepository) ScheduleRetry(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]any{
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"last_error":      delivery.LastError,
+		}).Error
+}
+
+// MarkDead gives up on a delivery and copies it into the dead-letter table.
+func (r *WebhookRepository) MarkDead(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.WebhookDelivery{}).
+			Where("id = ?", delivery.ID).
+			Updates(map[string]any{
+				"status":          models.DeliveryDead,
+				"attempts":        delivery.Attempts,
+				"response_status": delivery.ResponseStatus,
+				"last_error":      delivery.LastError,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.WebhookDeadLetter{
+			DeliveryID:     delivery.ID,
+			SubscriptionID: delivery.SubscriptionID,
+			Event:          delivery.Event,
+			Payload:        delivery.Payload,
+			Attempts:       delivery.Attempts,
+			LastError:      delivery.LastError,
+		}).Error
+	})
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID, limit, offset int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/service"
+	chatv1 "github.com/AlGrushino/chat/pkg/api/chat/v1"
+	"github.com/sirupsen/logrus"
+)
+
+type chatServer struct {
+	chatv1.UnimplementedChatServiceServer
+
+	service *service.Service
+	log     *logrus.Logger
+}
+
+func (s *chatServer) CreateChat(ctx context.Context, req *chatv1.CreateChatRequest) (*chatv1.CreateChatResponse, error) {
+	log := s.log.WithField("rpc", "CreateChat")
+
+	chat, err := s.service.Chat.CreateChat(ctx, req.GetTitle())
+	if err != nil {
+		return nil, toStatus(log, err, "Failed to create chat")
+	}
+
+	return &chatv1.CreateChatResponse{Chat: newChat(chat, nil)}, nil
+}
+
+func (s *chatServer) ListChats(ctx context.Context, req *chatv1.ListChatsRequest) (*chatv1.ListChatsResponse, error) {
+	log := s.log.WithField("rpc", "ListChats")
+
+	summaries, err := s.service.Chat.ListChats(ctx, int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, toStatus(log, err, "Failed to list chats")
+	}
+
+	chats := make([]*chatv1.Chat, 0, len(summaries))
+	for _, summary := range summaries {
+		chats = append(chats, newChat(summary.Chat, summary.Peer))
+	}
+
+	return &chatv1.ListChatsResponse{Chats: chats}, nil
+}
+
+func (s *chatServer) DeleteChat(ctx context.Context, req *chatv1.DeleteChatRequest) (*chatv1.DeleteChatResponse, error) {
+	log := s.log.WithField("rpc", "DeleteChat")
+
+	if err := s.service.Chat.DeleteChat(ctx, int(req.GetId())); err != nil {
+		return nil, toStatus(log, err, "Failed to delete chat")
+	}
+
+	return &chatv1.DeleteChatResponse{}, nil
+}
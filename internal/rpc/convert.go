@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"github.com/AlGrushino/chat/internal/handlers/models"
+	repoModels "github.com/AlGrushino/chat/internal/repository/models"
+	chatv1 "github.com/AlGrushino/chat/pkg/api/chat/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newUser(u *repoModels.User) *chatv1.User {
+	if u == nil {
+		return nil
+	}
+
+	return &chatv1.User{
+		Id:       int64(u.ID),
+		Username: u.Username,
+		IsBot:    u.IsBot,
+	}
+}
+
+func newChat(c *repoModels.Chat, peer *repoModels.User) *chatv1.Chat {
+	chat := &chatv1.Chat{
+		Id:              int64(c.ID),
+		Title:           c.Title,
+		Type:            c.Type,
+		Topic:           c.Topic,
+		SlowModeSeconds: int32(c.SlowModeSeconds),
+		CreatedAt:       timestamppb.New(c.CreatedAt),
+		Peer:            newUser(peer),
+	}
+
+	if c.OwnerID != nil {
+		ownerID := int64(*c.OwnerID)
+		chat.OwnerId = &ownerID
+	}
+
+	return chat
+}
+
+func newMessage(m *repoModels.Message) *chatv1.Message {
+	message := &chatv1.Message{
+		Id:        int64(m.ID),
+		ChatId:    int64(m.ChatID),
+		Text:      m.Text,
+		Ephemeral: m.Ephemeral,
+		CreatedAt: timestamppb.New(m.CreatedAt),
+		Author:    newUser(m.Author),
+	}
+
+	if m.AuthorID != nil {
+		authorID := int64(*m.AuthorID)
+		message.AuthorId = &authorID
+	}
+
+	if m.AuthorName != nil {
+		message.AuthorName = *m.AuthorName
+	}
+
+	if m.ClientMsgID != nil {
+		message.ClientMsgId = *m.ClientMsgID
+	}
+
+	if m.ExpiresAt != nil {
+		message.ExpiresAt = timestamppb.New(*m.ExpiresAt)
+	}
+
+	for _, a := range m.Attachments {
+		message.Attachments = append(message.Attachments, &chatv1.Attachment{
+			Id:          int64(a.ID),
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			Url:         models.NewAttachment(&a).URL,
+		})
+	}
+
+	for _, mention := range m.Mentions {
+		item := &chatv1.Mention{
+			UserId: int64(mention.UserID),
+			Offset: int32(mention.Offset),
+			Length: int32(mention.Length),
+		}
+		if mention.User != nil {
+			item.Username = mention.User.Username
+		}
+		message.Mentions = append(message.Mentions, item)
+	}
+
+	return message
+}
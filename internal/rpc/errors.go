@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	chatService "github.com/AlGrushino/chat/internal/service/chat"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	moderationService "github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus maps service errors to gRPC codes the way the HTTP handlers map
+// them to status codes. Unknown errors are logged and reported as internal
+// with fallback as the message.
+func toStatus(log *logrus.Entry, err error, fallback string) error {
+	var slowModeErr *messageService.SlowModeError
+
+	switch {
+	case err.Error() == "chat does not exist":
+		log.WithError(err).Warn("Chat does not exist")
+		return status.Error(codes.NotFound, "Chat does not exist")
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, "Authentication required")
+	case errors.Is(err, chatService.ErrNotOwner):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, moderationService.ErrBanned), errors.Is(err, moderationService.ErrMuted):
+		log.WithError(err).Warn("Restricted")
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, chatService.ErrChatExists), errors.Is(err, messageService.ErrClientMsgIDConflict):
+		log.WithError(err).Warn("Conflict")
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, chatService.ErrInvalidTitle),
+		errors.Is(err, messageService.ErrEmptyText),
+		errors.Is(err, messageService.ErrTextTooLong),
+		errors.Is(err, messageService.ErrTooManyAttachments),
+		errors.Is(err, messageService.ErrInvalidLimit),
+		errors.Is(err, messageService.ErrInvalidClientMsgID),
+		errors.Is(err, messageService.ErrInvalidExpiresIn),
+		errors.Is(err, messageService.ErrCommandAttachments):
+		log.WithError(err).Warn("Invalid argument")
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, messageService.ErrAttachmentUnavailable):
+		log.WithError(err).Warn("Attachment unavailable")
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &slowModeErr):
+		log.WithError(err).Warn("Slow mode")
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		log.WithError(err).Error("Service error")
+		return status.Error(codes.Internal, fallback)
+	}
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/AlGrushino/chat/internal/service"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	chatv1 "github.com/AlGrushino/chat/pkg/api/chat/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+type messageServer struct {
+	chatv1.UnimplementedMessageServiceServer
+
+	service *service.Service
+	log     *logrus.Logger
+}
+
+func (s *messageServer) AddMessage(ctx context.Context, req *chatv1.AddMessageRequest) (*chatv1.AddMessageResponse, error) {
+	log := s.log.WithField("rpc", "AddMessage")
+
+	attachmentIDs := make([]int, 0, len(req.GetAttachmentIds()))
+	for _, id := range req.GetAttachmentIds() {
+		attachmentIDs = append(attachmentIDs, int(id))
+	}
+
+	message, err := s.service.Message.AddMessage(ctx, int(req.GetChatId()), messageService.NewMessage{
+		Text:          req.GetText(),
+		AttachmentIDs: attachmentIDs,
+		ClientMsgID:   req.GetClientMsgId(),
+		ExpiresIn:     int(req.GetExpiresIn()),
+	})
+	if err != nil {
+		return nil, toStatus(log, err, "Failed to add message")
+	}
+
+	return &chatv1.AddMessageResponse{Message: newMessage(message)}, nil
+}
+
+func (s *messageServer) ListMessages(ctx context.Context, req *chatv1.ListMessagesRequest) (*chatv1.ListMessagesResponse, error) {
+	log := s.log.WithField("rpc", "ListMessages")
+
+	messages, err := s.service.Message.ListMessages(ctx, int(req.GetChatId()), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(log, err, "Failed to get messages")
+	}
+
+	items := make([]*chatv1.Message, 0, len(messages))
+	for _, message := range messages {
+		items = append(items, newMessage(message))
+	}
+
+	return &chatv1.ListMessagesResponse{Messages: items}, nil
+}
+
+func (s *messageServer) Subscribe(req *chatv1.SubscribeRequest, stream grpc.ServerStreamingServer[chatv1.SubscribeResponse]) error {
+	log := s.log.WithFields(logrus.Fields{
+		"rpc":     "Subscribe",
+		"chat_id": req.GetChatId(),
+	})
+
+	messages, err := s.service.Message.Subscribe(stream.Context(), int(req.GetChatId()))
+	if err != nil {
+		return toStatus(log, err, "Failed to subscribe")
+	}
+
+	expired, err := s.service.Message.SubscribeExpired(stream.Context(), int(req.GetChatId()))
+	if err != nil {
+		return toStatus(log, err, "Failed to subscribe")
+	}
+
+	log.Info("Subscriber connected")
+	defer log.Info("Subscriber disconnected")
+
+	// Both channels close when the chat is deleted or the call ends.
+	for messages != nil || expired != nil {
+		resp := &chatv1.SubscribeResponse{}
+
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			resp.Message = newMessage(message)
+		case message, ok := <-expired:
+			if !ok {
+				expired = nil
+				continue
+			}
+			resp.Message = &chatv1.Message{Id: int64(message.ID), ChatId: int64(message.ChatID)}
+			resp.Expired = true
+		}
+
+		if err := stream.Send(resp); err != nil {
+			log.WithError(err).Warn("Failed to send message")
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
+	"github.com/AlGrushino/chat/internal/service"
+	userService "github.com/AlGrushino/chat/internal/service/user"
+	chatv1 "github.com/AlGrushino/chat/pkg/api/chat/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const DefaultAddr = ":9090"
+
+type Config struct {
+	Addr string
+}
+
+func GetConfig(log *logrus.Logger) *Config {
+	log.Info("Getting gRPC config from env")
+
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	return &Config{Addr: addr}
+}
+
+// NewServer returns a gRPC server exposing the chat and message services on
+// top of the same service layer as the HTTP API.
+func NewServer(svc *service.Service, log *logrus.Logger) *grpc.Server {
+	log.WithFields(logrus.Fields{
+		"layer":  "rpc",
+		"method": "NewServer",
+	}).Info("Create new gRPC server")
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuth(svc.User, log)),
+		grpc.ChainStreamInterceptor(streamAuth(svc.User, log)),
+	)
+
+	chatv1.RegisterChatServiceServer(server, &chatServer{service: svc, log: log})
+	chatv1.RegisterMessageServiceServer(server, &messageServer{service: svc, log: log})
+
+	return server
+}
+
+func unaryAuth(users middleware.Authenticator, log *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, users, log, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(users middleware.Authenticator, log *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), users, log, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate works like the HTTP middleware: calls without an
+// authorization entry are anonymous, calls with a bad one are rejected.
+func authenticate(ctx context.Context, users middleware.Authenticator, log *logrus.Logger, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization header")
+	}
+
+	user, err := users.Authenticate(ctx, strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, userService.ErrUnauthorized) {
+			log.WithField("method", method).Warn("Invalid token")
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+		log.WithError(err).Error("Failed to authenticate call")
+		return nil, status.Error(codes.Internal, "Failed to authenticate")
+	}
+
+	return auth.WithUser(ctx, user), nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
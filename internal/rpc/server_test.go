@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	chatService "github.com/AlGrushino/chat/internal/service/chat"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	userService "github.com/AlGrushino/chat/internal/service/user"
+	"github.com/AlGrushino/chat/internal/testutil"
+	chatv1 "github.com/AlGrushino/chat/pkg/api/chat/v1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeUsers struct {
+	service.User
+}
+
+func (fakeUsers) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	if token != "secret" {
+		return nil, userService.ErrUnauthorized
+	}
+	return testutil.Owner, nil
+}
+
+type fakeChats struct {
+	service.Chat
+}
+
+func (fakeChats) CreateChat(ctx context.Context, title string) (*models.Chat, error) {
+	owner := auth.UserFromContext(ctx)
+	if owner == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+	if title == "taken" {
+		return nil, chatService.ErrChatExists
+	}
+	return &models.Chat{ID: 1, Title: title, Type: models.ChatTypeGroup, OwnerID: &owner.ID}, nil
+}
+
+func (fakeChats) DeleteChat(ctx context.Context, id int) error {
+	return errors.New("chat does not exist")
+}
+
+// fakeMessages sends one created and one expired message to subscribers.
+type fakeMessages struct {
+	service.Message
+}
+
+func (fakeMessages) AddMessage(ctx context.Context, id int, msg messageService.NewMessage) (*models.Message, error) {
+	if msg.ExpiresIn < 0 || msg.ExpiresIn > int(messageService.MaxExpiresIn/time.Second) {
+		return nil, messageService.ErrInvalidExpiresIn
+	}
+
+	message := &models.Message{ID: 1, ChatID: id, Text: msg.Text}
+	if msg.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(msg.ExpiresIn) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+	return message, nil
+}
+
+func (fakeMessages) Subscribe(ctx context.Context, id int) (<-chan *models.Message, error) {
+	messages := make(chan *models.Message, 1)
+	messages <- &models.Message{ID: 2, ChatID: id, Text: "hi"}
+	close(messages)
+	return messages, nil
+}
+
+func (fakeMessages) SubscribeExpired(ctx context.Context, id int) (<-chan *models.Message, error) {
+	messages := make(chan *models.Message, 1)
+	messages <- &models.Message{ID: 1, ChatID: id}
+	close(messages)
+	return messages, nil
+}
+
+func newTestClient(t *testing.T) chatv1.ChatServiceClient {
+	return chatv1.NewChatServiceClient(newTestConn(t))
+}
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(&service.Service{User: fakeUsers{}, Chat: fakeChats{}, Message: fakeMessages{}}, log)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestChatService(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	signedIn := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	resp, err := client.CreateChat(signedIn, &chatv1.CreateChatRequest{Title: "general"})
+	require.NoError(t, err)
+	assert.Equal(t, "general", resp.GetChat().GetTitle())
+	assert.Equal(t, int64(testutil.Owner.ID), resp.GetChat().GetOwnerId())
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context) error
+		code codes.Code
+	}{
+		{
+			name: "anonymous",
+			ctx:  ctx,
+			call: func(ctx context.Context) error {
+				_, err := client.CreateChat(ctx, &chatv1.CreateChatRequest{Title: "general"})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "bad token",
+			ctx:  metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong"),
+			call: func(ctx context.Context) error {
+				_, err := client.CreateChat(ctx, &chatv1.CreateChatRequest{Title: "general"})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "duplicate",
+			ctx:  signedIn,
+			call: func(ctx context.Context) error {
+				_, err := client.CreateChat(ctx, &chatv1.CreateChatRequest{Title: "taken"})
+				return err
+			},
+			code: codes.AlreadyExists,
+		},
+		{
+			name: "missing chat",
+			ctx:  signedIn,
+			call: func(ctx context.Context) error {
+				_, err := client.DeleteChat(ctx, &chatv1.DeleteChatRequest{Id: 42})
+				return err
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(tt.ctx)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestMessageService_ExpiresIn(t *testing.T) {
+	client := chatv1.NewMessageServiceClient(newTestConn(t))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+
+	resp, err := client.AddMessage(ctx, &chatv1.AddMessageRequest{ChatId: 1, Text: "soon gone", ExpiresIn: 60})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), resp.GetMessage().GetExpiresAt().AsTime(), 10*time.Second)
+
+	_, err = client.AddMessage(ctx, &chatv1.AddMessageRequest{ChatId: 1, Text: "forever", ExpiresIn: math.MaxInt64})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMessageService_SubscribeExpired(t *testing.T) {
+	client := chatv1.NewMessageServiceClient(newTestConn(t))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+
+	stream, err := client.Subscribe(ctx, &chatv1.SubscribeRequest{ChatId: 3})
+	require.NoError(t, err)
+
+	expired := map[int64]bool{}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), resp.GetMessage().GetChatId())
+		expired[resp.GetMessage().GetId()] = resp.GetExpired()
+	}
+
+	assert.Equal(t, map[int64]bool{1: true, 2: false}, expired)
+}
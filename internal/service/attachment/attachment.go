@@ -0,0 +1,227 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxUploadSize = 10 << 20
+
+	// MaxImagePixels bounds width×height of uploaded images. A small file
+	// can declare a huge canvas, and decoding allocates all of it.
+	MaxImagePixels = 40_000_000
+)
+
+var (
+	ErrChatNotFound      = errors.New("chat does not exist")
+	ErrNotFound          = errors.New("attachment does not exist")
+	ErrTooLarge          = errors.New("attachment is too large")
+	ErrEmpty             = errors.New("attachment is empty")
+	ErrImageTooLarge     = fmt.Errorf("image is larger than %d pixels", MaxImagePixels)
+	ErrThumbnailNotReady = errors.New("thumbnail is not ready")
+	ErrNoThumbnail       = errors.New("attachment has no thumbnail")
+)
+
+type AttachmentService struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	worker     *ThumbnailWorker
+	log        *logrus.Logger
+}
+
+func NewAttachmentService(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore, worker *ThumbnailWorker) *AttachmentService {
+	return &AttachmentService{
+		repository: repository,
+		store:      store,
+		worker:     worker,
+		log:        log,
+	}
+}
+
+func (s *AttachmentService) Upload(ctx context.Context, chatID int, filename string, r io.Reader) (*models.Attachment, error) {
+	chat, err := s.repository.Chat.GetByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", chatID)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return nil, ErrChatNotFound
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+
+	if len(data) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	attachment := models.Attachment{
+		ChatID:          chatID,
+		Filename:        sanitizeFilename(filename),
+		ContentType:     contentType,
+		ThumbnailStatus: models.ThumbnailNone,
+	}
+
+	if isImage(contentType) {
+		cfg, err := decodeImageConfig(contentType, data)
+		if err != nil {
+			s.log.WithError(err).Warnf("Failed to decode image %q, storing as plain file", attachment.Filename)
+			attachment.ContentType = "application/octet-stream"
+		} else if err := checkImageSize(cfg); err != nil {
+			return nil, err
+		} else {
+			stripped, err := stripMetadata(contentType, data)
+			if err != nil {
+				s.log.WithError(err).Warnf("Failed to strip metadata from %q, storing as plain file", attachment.Filename)
+				attachment.ContentType = "application/octet-stream"
+			} else {
+				data = stripped
+				attachment.Width = cfg.Width
+				attachment.Height = cfg.Height
+				attachment.ThumbnailStatus = models.ThumbnailPending
+			}
+		}
+	}
+
+	prefix, err := newBlobPrefix(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.BlobKey = path.Join(prefix, "original")
+	attachment.Size = int64(len(data))
+
+	if err := s.store.Put(ctx, attachment.BlobKey, bytes.NewReader(data)); err != nil {
+		s.log.WithError(err).Error("Failed to store attachment")
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	if err := s.repository.Attachment.Create(ctx, &attachment); err != nil {
+		s.log.WithError(err).Error("Failed to create attachment in database")
+		if err := s.store.Delete(ctx, attachment.BlobKey); err != nil {
+			s.log.WithError(err).Warnf("Failed to clean up blob %s", attachment.BlobKey)
+		}
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	if attachment.ThumbnailStatus == models.ThumbnailPending {
+		s.worker.Enqueue(attachment.ID)
+	}
+
+	s.log.Infof("Attachment uploaded (ID: %d, chat: %d, type: %s, size: %d)", attachment.ID, chatID, attachment.ContentType, attachment.Size)
+	return &attachment, nil
+}
+
+func (s *AttachmentService) GetAttachment(ctx context.Context, id int) (*models.Attachment, error) {
+	attachment, err := s.repository.Attachment.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment with id: %d", id)
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, attachment.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat with id: %d", attachment.ChatID)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return nil, ErrNotFound
+	}
+
+	return attachment, nil
+}
+
+// Open returns the stored blob together with its content type. When
+// thumbnail is set the generated preview is returned instead of the original.
+func (s *AttachmentService) Open(ctx context.Context, id int, thumbnail bool) (io.ReadCloser, string, error) {
+	attachment, err := s.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, contentType := attachment.BlobKey, attachment.ContentType
+	if thumbnail {
+		switch attachment.ThumbnailStatus {
+		case models.ThumbnailReady:
+			key, contentType = *attachment.ThumbnailKey, thumbnailContentType(attachment.ContentType)
+		case models.ThumbnailPending:
+			return nil, "", ErrThumbnailNotReady
+		default:
+			return nil, "", ErrNoThumbnail
+		}
+	}
+
+	rc, err := s.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to open attachment: %w", err)
+	}
+
+	return rc, contentType, nil
+}
+
+func newBlobPrefix(chatID int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate blob key: %w", err)
+	}
+
+	return path.Join("chats", fmt.Sprint(chatID), hex.EncodeToString(b)), nil
+}
+
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+
+	return name
+}
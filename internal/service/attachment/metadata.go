@@ -0,0 +1,104 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var errMalformedImage = errors.New("malformed image data")
+
+// stripMetadata removes EXIF, XMP and textual metadata from JPEG and PNG
+// images without re-encoding pixel data. Other formats are returned as is.
+func stripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	default:
+		return data, nil
+	}
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, errMalformedImage
+		}
+
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+
+		// Start of scan: everything after it is entropy-coded image data.
+		if marker == 0xDA {
+			return append(out, data[pos:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errMalformedImage
+		}
+
+		// APP1 carries EXIF and XMP, APP13 carries Photoshop/IPTC, COM is a free-form comment.
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[pos:end]...)
+		}
+
+		pos = end
+	}
+
+	return nil, errMalformedImage
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+	"tIME": true,
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformedImage
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if end > len(data) {
+			return nil, errMalformedImage
+		}
+
+		chunkType := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[pos:end]...)
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+
+	return nil, errMalformedImage
+}
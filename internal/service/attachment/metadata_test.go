@@ -0,0 +1,147 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	original := encodeJPEG(t, 8, 8)
+
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 55.7558 37.6173")...)
+	app1 := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	app1 = append(app1, payload...)
+
+	withExif := append([]byte{}, original[:2]...)
+	withExif = append(withExif, app1...)
+	withExif = append(withExif, original[2:]...)
+
+	stripped, err := stripMetadata("image/jpeg", withExif)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stripped), "Exif")
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.Equal(t, original, stripped)
+
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	original := buf.Bytes()
+
+	// Insert a tEXt chunk right after IHDR (signature + 25 bytes).
+	text := []byte("Author\x00secret")
+	chunk := []byte{0, 0, 0, byte(len(text)), 't', 'E', 'X', 't'}
+	chunk = append(chunk, text...)
+	chunk = append(chunk, 0, 0, 0, 0)
+
+	withText := append([]byte{}, original[:33]...)
+	withText = append(withText, chunk...)
+	withText = append(withText, original[33:]...)
+
+	stripped, err := stripMetadata("image/png", withText)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(stripped), "secret")
+	assert.Equal(t, original, stripped)
+}
+
+func TestStripMetadata_Malformed(t *testing.T) {
+	_, err := stripMetadata("image/jpeg", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF})
+	assert.ErrorIs(t, err, errMalformedImage)
+
+	_, err = stripMetadata("image/png", []byte("not a png"))
+	assert.ErrorIs(t, err, errMalformedImage)
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	img, err := jpeg.Decode(bytes.NewReader(encodeJPEG(t, 1280, 640)))
+	require.NoError(t, err)
+
+	data, err := makeThumbnail("image/jpeg", img)
+	require.NoError(t, err)
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 320, cfg.Width)
+	assert.Equal(t, 160, cfg.Height)
+}
+
+func TestThumbnailSize(t *testing.T) {
+	cases := []struct {
+		width, height int
+		wantW, wantH  int
+	}{
+		{100, 50, 100, 50},
+		{640, 480, 320, 240},
+		{480, 960, 160, 320},
+		{5000, 1, 320, 1},
+	}
+
+	for _, c := range cases {
+		w, h := thumbnailSize(c.width, c.height)
+		assert.Equal(t, c.wantW, w)
+		assert.Equal(t, c.wantH, h)
+	}
+}
+
+// pngHeader returns the signature and IHDR chunk of a PNG, which is all
+// DecodeConfig reads, so the canvas can be far bigger than the file.
+func pngHeader(width, height uint32) []byte {
+	chunk := []byte("IHDR")
+	chunk = binary.BigEndian.AppendUint32(chunk, width)
+	chunk = binary.BigEndian.AppendUint32(chunk, height)
+	chunk = append(chunk, 8, 6, 0, 0, 0)
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(chunk)-4))
+	data = append(data, chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+func TestCheckImageSize(t *testing.T) {
+	cfg, err := decodeImageConfig("image/png", pngHeader(100000, 100000))
+	require.NoError(t, err)
+	assert.ErrorIs(t, checkImageSize(cfg), ErrImageTooLarge)
+
+	cfg, err = decodeImageConfig("image/png", pngHeader(8000, 5000))
+	require.NoError(t, err)
+	assert.NoError(t, checkImageSize(cfg))
+}
+
+func TestThumbnailWorker_RefusesHugeImage(t *testing.T) {
+	store := testutil.NewBlobStore(map[string][]byte{"chats/1/a/original": pngHeader(100000, 100000)})
+	worker := NewThumbnailWorker(logrus.New(), nil, store)
+
+	_, err := worker.generate(context.Background(), &models.Attachment{ContentType: "image/png", BlobKey: "chats/1/a/original"})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
@@ -0,0 +1,103 @@
+package attachment
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+const thumbnailMaxSide = 320
+
+var imageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+func isImage(contentType string) bool {
+	return imageContentTypes[contentType]
+}
+
+func decodeImage(contentType string, r io.Reader) (image.Image, error) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(r)
+	case "image/png":
+		return png.Decode(r)
+	case "image/gif":
+		// gif.Decode returns the first frame of animated images.
+		return gif.Decode(r)
+	default:
+		return nil, fmt.Errorf("unsupported image type: %s", contentType)
+	}
+}
+
+func decodeImageConfig(contentType string, data []byte) (image.Config, error) {
+	r := bytes.NewReader(data)
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.DecodeConfig(r)
+	case "image/png":
+		return png.DecodeConfig(r)
+	case "image/gif":
+		return gif.DecodeConfig(r)
+	default:
+		return image.Config{}, fmt.Errorf("unsupported image type: %s", contentType)
+	}
+}
+
+func checkImageSize(cfg image.Config) error {
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// thumbnailContentType keeps JPEG thumbnails lossy and uses PNG for
+// everything else so transparency survives.
+func thumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func thumbnailSize(width, height int) (int, int) {
+	if width <= thumbnailMaxSide && height <= thumbnailMaxSide {
+		return width, height
+	}
+
+	if width >= height {
+		return thumbnailMaxSide, max(1, height*thumbnailMaxSide/width)
+	}
+	return max(1, width*thumbnailMaxSide/height), thumbnailMaxSide
+}
+
+// makeThumbnail scales src to fit into thumbnailMaxSide and re-encodes it,
+// which also drops any metadata carried by the original file.
+func makeThumbnail(contentType string, src image.Image) ([]byte, error) {
+	bounds := src.Bounds()
+	width, height := thumbnailSize(bounds.Dx(), bounds.Dy())
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	var err error
+	if thumbnailContentType(contentType) == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
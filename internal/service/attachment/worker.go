@@ -0,0 +1,164 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	thumbnailQueueSize     = 256
+	thumbnailSweepInterval = time.Minute
+	thumbnailSweepBatch    = 50
+)
+
+// ThumbnailWorker generates previews for uploaded images in the background.
+// Attachments are queued right after upload; a periodic sweep over pending
+// rows picks up anything dropped from a full queue or left over by a restart.
+type ThumbnailWorker struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	queue      chan int
+	log        *logrus.Logger
+}
+
+func NewThumbnailWorker(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore) *ThumbnailWorker {
+	return &ThumbnailWorker{
+		repository: repository,
+		store:      store,
+		queue:      make(chan int, thumbnailQueueSize),
+		log:        log,
+	}
+}
+
+func (w *ThumbnailWorker) Enqueue(id int) {
+	select {
+	case w.queue <- id:
+	default:
+		w.log.Warnf("Thumbnail queue is full, attachment %d will be picked up by the next sweep", id)
+	}
+}
+
+func (w *ThumbnailWorker) Run(ctx context.Context) {
+	w.log.Info("Thumbnail worker started")
+	defer w.log.Info("Thumbnail worker stopped")
+
+	ticker := time.NewTicker(thumbnailSweepInterval)
+	defer ticker.Stop()
+
+	w.sweep(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-w.queue:
+			w.process(ctx, id)
+		case <-ticker.C:
+			w.sweep(ctx)
+		}
+	}
+}
+
+func (w *ThumbnailWorker) sweep(ctx context.Context) {
+	attachments, err := w.repository.Attachment.GetPendingThumbnails(ctx, thumbnailSweepBatch)
+	if err != nil {
+		w.log.WithError(err).Error("Failed to load pending thumbnails")
+		return
+	}
+
+	for _, attachment := range attachments {
+		if ctx.Err() != nil {
+			return
+		}
+		w.process(ctx, attachment.ID)
+	}
+}
+
+func (w *ThumbnailWorker) process(ctx context.Context, id int) {
+	log := w.log.WithField("attachment_id", id)
+
+	attachment, err := w.repository.Attachment.GetByID(ctx, id)
+	if err != nil {
+		log.WithError(err).Warn("Failed to load attachment for thumbnail")
+		return
+	}
+
+	if attachment.ThumbnailStatus != models.ThumbnailPending {
+		return
+	}
+
+	key, err := w.generate(ctx, attachment)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.WithError(err).Warn("Failed to generate thumbnail")
+		if err := w.repository.Attachment.UpdateThumbnail(ctx, id, nil, models.ThumbnailFailed); err != nil {
+			log.WithError(err).Error("Failed to mark thumbnail as failed")
+		}
+		return
+	}
+
+	if err := w.repository.Attachment.UpdateThumbnail(ctx, id, &key, models.ThumbnailReady); err != nil {
+		log.WithError(err).Error("Failed to save thumbnail")
+		return
+	}
+
+	log.Info("Thumbnail generated")
+}
+
+func (w *ThumbnailWorker) generate(ctx context.Context, attachment *models.Attachment) (string, error) {
+	rc, err := w.store.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to open original: %w", err)
+	}
+	defer rc.Close()
+
+	original, err := io.ReadAll(io.LimitReader(rc, MaxUploadSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read original: %w", err)
+	}
+
+	// Check the size again before decoding, originals uploaded before
+	// MaxImagePixels existed were never checked.
+	cfg, err := decodeImageConfig(attachment.ContentType, original)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if err := checkImageSize(cfg); err != nil {
+		return "", err
+	}
+
+	img, err := decodeImage(attachment.ContentType, bytes.NewReader(original))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	data, err := makeThumbnail(attachment.ContentType, img)
+	if err != nil {
+		return "", err
+	}
+
+	ext := ".png"
+	if thumbnailContentType(attachment.ContentType) == "image/jpeg" {
+		ext = ".jpg"
+	}
+
+	key := path.Join(path.Dir(attachment.BlobKey), "thumbnail"+ext)
+	if err := w.store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/command"
+	"github.com/AlGrushino/chat/internal/service/user"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const MaxCommands = 20
+
+var (
+	ErrNotFound        = errors.New("bot does not exist")
+	ErrBotOwner        = errors.New("bots cannot create bots")
+	ErrInvalidURL      = errors.New("callback URL must be an absolute http or https URL")
+	ErrInvalidCommands = fmt.Errorf("a bot needs 1-%d commands named with 1-32 lower-case letters, digits, '_' or '-'", MaxCommands)
+	ErrCommandTaken    = errors.New("command is already registered")
+)
+
+// NewCommand describes a slash command served by a bot.
+type NewCommand struct {
+	Name        string
+	Description string
+}
+
+// Bot is a bot account together with the commands it serves.
+type Bot struct {
+	User     *models.User
+	Commands []*models.BotCommand
+}
+
+type BotService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewBotService(log *logrus.Logger, repository *repository.Repository) *BotService {
+	return &BotService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// CreateBot registers a bot owned by the caller and returns it with its API
+// token. The callback secret is returned on the bot itself so the owner can
+// verify signed command requests.
+func (s *BotService) CreateBot(ctx context.Context, username, callbackURL string, commands []NewCommand) (*Bot, string, error) {
+	owner := auth.UserFromContext(ctx)
+	if owner == nil {
+		return nil, "", auth.ErrUnauthenticated
+	}
+
+	if owner.IsBot {
+		return nil, "", ErrBotOwner
+	}
+
+	username = strings.TrimSpace(username)
+	if !user.UsernamePattern.MatchString(username) {
+		return nil, "", user.ErrInvalidUsername
+	}
+
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(callbackURL) > 2000 {
+		return nil, "", ErrInvalidURL
+	}
+
+	if len(commands) == 0 || len(commands) > MaxCommands {
+		return nil, "", ErrInvalidCommands
+	}
+
+	names := make([]string, 0, len(commands))
+	botCommands := make([]models.BotCommand, 0, len(commands))
+	for _, c := range commands {
+		name, _, ok := command.Parse("/" + c.Name)
+		if !ok || name != c.Name || slices.Contains(names, name) || len(c.Description) > 200 {
+			return nil, "", ErrInvalidCommands
+		}
+
+		names = append(names, name)
+		botCommands = append(botCommands, models.BotCommand{Name: name, Description: strings.TrimSpace(c.Description)})
+	}
+
+	for _, builtin := range command.Builtins {
+		if slices.Contains(names, builtin.Name) {
+			return nil, "", fmt.Errorf("%w: /%s", ErrCommandTaken, builtin.Name)
+		}
+	}
+
+	taken, err := s.repository.Bot.TakenCommands(ctx, names)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check commands: %w", err)
+	}
+
+	if len(taken) > 0 {
+		return nil, "", fmt.Errorf("%w: /%s", ErrCommandTaken, taken[0])
+	}
+
+	exists, err := s.repository.User.UsernameExists(ctx, username)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check username: %w", err)
+	}
+
+	if exists {
+		return nil, "", user.ErrUsernameTaken
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	bot := models.User{
+		Username:       username,
+		TokenHash:      auth.HashToken(token),
+		IsBot:          true,
+		OwnerID:        &owner.ID,
+		CallbackURL:    &callbackURL,
+		CallbackSecret: &secret,
+	}
+
+	if err := s.repository.Bot.Create(ctx, &bot, botCommands); err != nil {
+		s.log.WithError(err).Error("Failed to create bot in database")
+		return nil, "", fmt.Errorf("failed to create bot: %w", err)
+	}
+
+	created := &Bot{User: &bot}
+	for i := range botCommands {
+		created.Commands = append(created.Commands, &botCommands[i])
+	}
+
+	s.log.Infof("Bot created successfully (ID: %d, Username: %q, Commands: %v)", bot.ID, username, names)
+	return created, token, nil
+}
+
+// ListBots returns the bots owned by the caller.
+func (s *BotService) ListBots(ctx context.Context) ([]*Bot, error) {
+	owner := auth.UserFromContext(ctx)
+	if owner == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	users, err := s.repository.Bot.GetByOwnerID(ctx, owner.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bots: %w", err)
+	}
+
+	ids := make([]int, 0, len(users))
+	bots := make([]*Bot, 0, len(users))
+	byID := make(map[int]*Bot, len(users))
+	for _, u := range users {
+		bot := &Bot{User: u}
+		ids = append(ids, u.ID)
+		bots = append(bots, bot)
+		byID[u.ID] = bot
+	}
+
+	commands, err := s.repository.Bot.GetCommandsByBotIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot commands: %w", err)
+	}
+
+	for _, c := range commands {
+		byID[c.BotID].Commands = append(byID[c.BotID].Commands, c)
+	}
+
+	return bots, nil
+}
+
+// DeleteBot removes a bot owned by the caller. Its commands go with it and
+// its messages stay, without an author.
+func (s *BotService) DeleteBot(ctx context.Context, id int) error {
+	owner := auth.UserFromContext(ctx)
+	if owner == nil {
+		return auth.ErrUnauthenticated
+	}
+
+	bot, err := s.repository.User.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get bot: %w", err)
+	}
+
+	if !bot.IsBot || bot.OwnerID == nil || *bot.OwnerID != owner.ID {
+		return ErrNotFound
+	}
+
+	if err := s.repository.Bot.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete bot: %w", err)
+	}
+
+	s.log.Infof("Bot deleted successfully (ID: %d)", id)
+	return nil
+}
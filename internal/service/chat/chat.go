@@ -2,70 +2,579 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/events"
 	"github.com/AlGrushino/chat/internal/repository"
+	chatRepository "github.com/AlGrushino/chat/internal/repository/chat"
 	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/moderation"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+const (
+	MaxSlowMode = time.Hour
+	// MaxRetentionDays is about a hundred years; zero keeps messages forever.
+	MaxRetentionDays = 36500
+
+	MaxTopicLength       = 250
+	MaxDescriptionLength = 1000
+	// MaxSettingsSize is the size of the settings object encoded as JSON.
+	MaxSettingsSize = 4 << 10
+)
+
+var (
+	ErrInvalidTitle     = errors.New("chat title must be 1-200 characters")
+	ErrChatExists       = errors.New("chat already exists")
+	ErrUserNotFound     = errors.New("user does not exist")
+	ErrSelfDirect       = errors.New("cannot start a direct chat with yourself")
+	ErrNotOwner         = errors.New("only the chat owner can change chat settings")
+	ErrInvalidSlowMode  = fmt.Errorf("slow mode must be between 0 and %d seconds", int(MaxSlowMode.Seconds()))
+	ErrInvalidRetention = fmt.Errorf("retention must be between 0 and %d days", MaxRetentionDays)
+
+	ErrInvalidTopic       = fmt.Errorf("chat topic must be at most %d characters", MaxTopicLength)
+	ErrInvalidDescription = fmt.Errorf("chat description must be at most %d characters", MaxDescriptionLength)
+	ErrInvalidVisibility  = errors.New("chat visibility must be public or private")
+	ErrInvalidAvatar      = errors.New("chat avatar must be an image uploaded to the chat")
+	ErrInvalidSettings    = fmt.Errorf("chat settings must be at most %d bytes of JSON", MaxSettingsSize)
+
+	ErrOwnerLeave = errors.New("the chat owner cannot leave the chat")
+	ErrNotMember  = errors.New("not a member of the chat")
+)
+
+// Update lists the chat fields to change; nil fields are left as they are.
+// AvatarID 0 removes the avatar. Settings are merged into the current ones,
+// and keys set to nil are removed.
+type Update struct {
+	Title       *string
+	Description *string
+	Topic       *string
+	AvatarID    *int
+	Visibility  *string
+	Settings    map[string]any
+}
+
+// Summary is a chat as shown in listings. Peer is set for direct chats and
+// holds the participant other than the caller. Members is only counted by
+// ListPublic.
+type Summary struct {
+	Chat    *models.Chat
+	Peer    *models.User
+	Members int64
+}
+
 type ChatService struct {
 	repository *repository.Repository
+	events     *events.Bus
 	log        *logrus.Logger
 }
 
-func NewChatService(log *logrus.Logger, repository *repository.Repository) *ChatService {
+func NewChatService(log *logrus.Logger, repository *repository.Repository, bus *events.Bus) *ChatService {
 	return &ChatService{
 		repository: repository,
+		events:     bus,
 		log:        log,
 	}
 }
 
-func (s *ChatService) CreateChat(ctx context.Context, title string) (string, error) {
+// CreateChat creates a group chat. Authenticated callers become its owner.
+func (s *ChatService) CreateChat(ctx context.Context, title string) (*models.Chat, error) {
 	trimmed := strings.TrimSpace(title)
 
 	length := len(trimmed)
 	if length < 1 {
 		s.log.Warnf("Create chat failed: empty title (original: %q)", title)
-		return "", fmt.Errorf("len of %s equals 0", trimmed)
+		return nil, fmt.Errorf("%w: len of %s equals 0", ErrInvalidTitle, trimmed)
 	}
 
 	if length > 200 {
 		s.log.Warnf("Create chat failed: title too long %d chars", len(trimmed))
-		return "", fmt.Errorf("len of %s greater than 200", trimmed)
+		return nil, fmt.Errorf("%w: len of %s greater than 200", ErrInvalidTitle, trimmed)
 	}
 
 	chat := models.Chat{
 		Title:     trimmed,
+		Type:      models.ChatTypeGroup,
 		CreatedAt: time.Now(),
 	}
 
+	if owner := auth.UserFromContext(ctx); owner != nil {
+		chat.OwnerID = &owner.ID
+	}
+
 	exist, err := s.repository.Chat.ChatExists(ctx, trimmed)
 	if err != nil {
 		s.log.WithError(err).Error("Failed to check if chat exists in database")
-		return "", fmt.Errorf("failed to check if chat exists: %w", err)
+		return nil, fmt.Errorf("failed to check if chat exists: %w", err)
 	}
 
 	if exist {
 		s.log.Warnf("Create chat failed: title: %s already exists", trimmed)
-		return "", fmt.Errorf("failed to create chat, chat: %s already exists", trimmed)
+		return nil, fmt.Errorf("%w: %s", ErrChatExists, trimmed)
 	}
 
 	if err := s.repository.Chat.Create(ctx, &chat); err != nil {
 		s.log.WithError(err).Error("Failed to create chat in database")
-		return "", fmt.Errorf("failed to create chat: %w", err)
+		return nil, fmt.Errorf("failed to create chat: %w", err)
 	}
 
 	s.log.Infof("Chat created successfully (ID: %d, Title: %q)", chat.ID, trimmed)
-	return trimmed, nil
+	s.events.Publish(ctx, events.Event{Type: events.ChatCreated, Chat: &chat})
+	return &chat, nil
+}
+
+// GetChat returns a chat the caller may see. Direct chats are reported as
+// missing to non-participants.
+func (s *ChatService) GetChat(ctx context.Context, id int) (*models.Chat, error) {
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("chat does not exist")
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", id)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return nil, errors.New("chat does not exist")
+	}
+
+	return chat, nil
 }
 
 func (s *ChatService) DeleteChat(ctx context.Context, id int) error {
-	_, err := s.repository.Chat.GetByID(ctx, id)
+	chat, err := s.GetChat(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.Chat.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat: %w", err)
+	}
+
+	s.events.Publish(ctx, events.Event{Type: events.ChatDeleted, Chat: chat})
+
+	return nil
+}
+
+// SetSlowMode changes the minimum interval between two messages of the same
+// user in a group chat. Only the owner may change it; zero turns it off.
+func (s *ChatService) SetSlowMode(ctx context.Context, id, seconds int) (*models.Chat, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if seconds < 0 || seconds > int(MaxSlowMode.Seconds()) {
+		return nil, ErrInvalidSlowMode
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("chat does not exist")
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", id)
+	}
+
+	if chat.IsDirect() {
+		return nil, errors.New("chat does not exist")
+	}
+
+	if chat.OwnerID == nil || *chat.OwnerID != caller.ID {
+		return nil, ErrNotOwner
+	}
+
+	if err := s.repository.Chat.UpdateSlowMode(ctx, id, seconds); err != nil {
+		return nil, fmt.Errorf("failed to update slow mode: %w", err)
+	}
+	chat.SlowModeSeconds = seconds
+
+	s.log.Infof("Slow mode changed (Chat ID: %d, Seconds: %d)", id, seconds)
+	return chat, nil
+}
+
+// SetRetention changes how many days messages of a group chat are kept. Only
+// the owner may change it. Nil falls back to the global default and zero
+// keeps messages forever.
+func (s *ChatService) SetRetention(ctx context.Context, id int, days *int) (*models.Chat, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if days != nil && (*days < 0 || *days > MaxRetentionDays) {
+		return nil, ErrInvalidRetention
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("chat does not exist")
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", id)
+	}
+
+	if chat.IsDirect() {
+		return nil, errors.New("chat does not exist")
+	}
+
+	if chat.OwnerID == nil || *chat.OwnerID != caller.ID {
+		return nil, ErrNotOwner
+	}
+
+	if err := s.repository.Chat.UpdateRetention(ctx, id, days); err != nil {
+		return nil, fmt.Errorf("failed to update retention: %w", err)
+	}
+	chat.RetentionDays = days
+
+	if days == nil {
+		s.log.Infof("Retention reset to default (Chat ID: %d)", id)
+	} else {
+		s.log.Infof("Retention changed (Chat ID: %d, Days: %d)", id, *days)
+	}
+	return chat, nil
+}
+
+// UpdateChat changes the metadata of a group chat. Only the owner may change
+// it.
+func (s *ChatService) UpdateChat(ctx context.Context, id int, update Update) (*models.Chat, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("chat does not exist")
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", id)
+	}
+
+	if chat.IsDirect() {
+		return nil, errors.New("chat does not exist")
+	}
+
+	if chat.OwnerID == nil || *chat.OwnerID != caller.ID {
+		return nil, ErrNotOwner
+	}
+
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if len(title) < 1 || len(title) > 200 {
+			return nil, ErrInvalidTitle
+		}
+
+		if title != chat.Title {
+			exist, err := s.repository.Chat.ChatExists(ctx, title)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check if chat exists: %w", err)
+			}
+			if exist {
+				return nil, fmt.Errorf("%w: %s", ErrChatExists, title)
+			}
+		}
+		chat.Title = title
+	}
+
+	if update.Description != nil {
+		description := strings.TrimSpace(*update.Description)
+		if utf8.RuneCountInString(description) > MaxDescriptionLength {
+			return nil, ErrInvalidDescription
+		}
+		chat.Description = description
+	}
+
+	if update.Topic != nil {
+		topic := strings.TrimSpace(*update.Topic)
+		if utf8.RuneCountInString(topic) > MaxTopicLength {
+			return nil, ErrInvalidTopic
+		}
+		chat.Topic = topic
+	}
+
+	if update.Visibility != nil {
+		if *update.Visibility != models.VisibilityPublic && *update.Visibility != models.VisibilityPrivate {
+			return nil, ErrInvalidVisibility
+		}
+		chat.Visibility = *update.Visibility
+	}
+
+	if update.AvatarID != nil {
+		if err := s.setAvatar(ctx, chat, *update.AvatarID); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Settings != nil {
+		settings, err := mergeSettings(chat.Settings, update.Settings)
+		if err != nil {
+			return nil, err
+		}
+		chat.Settings = settings
+	}
+
+	if err := s.repository.Chat.UpdateMetadata(ctx, chat); err != nil {
+		return nil, fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	s.log.Infof("Chat updated (ID: %d)", id)
+	s.events.Publish(ctx, events.Event{Type: events.ChatUpdated, Chat: chat})
+	return chat, nil
+}
+
+func (s *ChatService) setAvatar(ctx context.Context, chat *models.Chat, attachmentID int) error {
+	if attachmentID == 0 {
+		chat.AvatarID = nil
+		return nil
+	}
+
+	attachment, err := s.repository.Attachment.GetByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidAvatar
+		}
+		return fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	if attachment.ChatID != chat.ID || !strings.HasPrefix(attachment.ContentType, "image/") {
+		return ErrInvalidAvatar
+	}
+
+	chat.AvatarID = &attachment.ID
+	return nil
+}
+
+// mergeSettings applies patch to current the way a JSON merge patch would on
+// the top level.
+func mergeSettings(current, patch map[string]any) (map[string]any, error) {
+	merged := make(map[string]any, len(current)+len(patch))
+	maps.Copy(merged, current)
+
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil || len(encoded) > MaxSettingsSize {
+		return nil, ErrInvalidSettings
+	}
+
+	return merged, nil
+}
+
+// CreateDirect returns the direct chat between the caller and userID,
+// creating it on first use. The boolean result reports whether it was created.
+func (s *ChatService) CreateDirect(ctx context.Context, userID int) (Summary, bool, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return Summary{}, false, auth.ErrUnauthenticated
+	}
+
+	if caller.ID == userID {
+		return Summary{}, false, ErrSelfDirect
+	}
+
+	peer, err := s.repository.User.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Summary{}, false, ErrUserNotFound
+		}
+		return Summary{}, false, fmt.Errorf("failed to get user with id: %d", userID)
+	}
+
+	key := directKey(caller.ID, userID)
+
+	chat, err := s.repository.Chat.GetByDMKey(ctx, key)
+	if err == nil {
+		return Summary{Chat: chat, Peer: peer}, false, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.log.WithError(err).Error("Failed to look up direct chat")
+		return Summary{}, false, fmt.Errorf("failed to get direct chat: %w", err)
+	}
+
+	chat = &models.Chat{
+		Title:     key,
+		Type:      models.ChatTypeDirect,
+		DMKey:     &key,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repository.Chat.CreateDirect(ctx, chat, []int{caller.ID, userID}); err != nil {
+		// A concurrent request may have created the same conversation.
+		if existing, getErr := s.repository.Chat.GetByDMKey(ctx, key); getErr == nil {
+			return Summary{Chat: existing, Peer: peer}, false, nil
+		}
+
+		s.log.WithError(err).Error("Failed to create direct chat in database")
+		return Summary{}, false, fmt.Errorf("failed to create direct chat: %w", err)
+	}
+
+	s.log.Infof("Direct chat created successfully (ID: %d, Key: %s)", chat.ID, key)
+	s.events.Publish(ctx, events.Event{Type: events.ChatCreated, Chat: chat})
+	return Summary{Chat: chat, Peer: peer}, true, nil
+}
+
+// ListChats returns group chats and, for authenticated callers, their
+// direct chats with the other participant resolved.
+func (s *ChatService) ListChats(ctx context.Context, limit, offset int) ([]Summary, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		return nil, fmt.Errorf("limit is too big: %d", limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	userID := 0
+	if caller := auth.UserFromContext(ctx); caller != nil {
+		userID = caller.ID
+	}
+
+	chats, err := s.repository.Chat.GetForUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chats: %w", err)
+	}
+
+	directIDs := make([]int, 0)
+	for _, chat := range chats {
+		if chat.IsDirect() {
+			directIDs = append(directIDs, chat.ID)
+		}
+	}
+
+	peers := make(map[int]*models.User, len(directIDs))
+	if len(directIDs) > 0 {
+		members, err := s.repository.Member.GetPeers(ctx, directIDs, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat members: %w", err)
+		}
+
+		for _, member := range members {
+			peers[member.ChatID] = &member.User
+		}
+	}
+
+	summaries := make([]Summary, 0, len(chats))
+	for _, chat := range chats {
+		summaries = append(summaries, Summary{Chat: chat, Peer: peers[chat.ID]})
+	}
+
+	return summaries, nil
+}
+
+// ListPublic returns the public group chats matching query, most active
+// first, with their member counts.
+func (s *ChatService) ListPublic(ctx context.Context, query string, limit, offset int) ([]Summary, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		return nil, fmt.Errorf("limit is too big: %d", limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	chats, err := s.repository.Chat.GetAll(ctx, chatRepository.Filter{
+		Type:       models.ChatTypeGroup,
+		Visibility: models.VisibilityPublic,
+		Query:      strings.TrimSpace(query),
+		Sort:       chatRepository.SortActivity,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chats: %w", err)
+	}
+
+	ids := make([]int, 0, len(chats))
+	for _, chat := range chats {
+		ids = append(ids, chat.ID)
+	}
+
+	counts, err := s.repository.Member.CountByChatIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chat members: %w", err)
+	}
+
+	summaries := make([]Summary, 0, len(chats))
+	for _, chat := range chats {
+		summaries = append(summaries, Summary{Chat: chat, Members: counts[chat.ID]})
+	}
+
+	return summaries, nil
+}
+
+// JoinChat makes the caller a member of a public group chat. Private chats
+// are reported as missing to anyone who is not in them already, and banned
+// users cannot join. The boolean result reports whether the caller joined
+// just now.
+func (s *ChatService) JoinChat(ctx context.Context, id int) (*models.Chat, bool, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, false, auth.ErrUnauthenticated
+	}
+
+	chat, err := s.GetChat(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if chat.IsDirect() {
+		return nil, false, errors.New("chat does not exist")
+	}
+
+	if err := moderation.Check(ctx, s.repository.Moderation, id, caller.ID, models.RestrictionBan); err != nil {
+		return nil, false, err
+	}
+
+	joined, err := s.repository.Member.Add(ctx, id, caller.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to join chat: %w", err)
+	}
+
+	if joined {
+		s.log.Infof("User joined chat (Chat ID: %d, User ID: %d)", id, caller.ID)
+	}
+	return chat, joined, nil
+}
+
+// LeaveChat ends the caller's membership of a group chat. The owner cannot
+// leave their own chat.
+func (s *ChatService) LeaveChat(ctx context.Context, id int) error {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return auth.ErrUnauthenticated
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("chat does not exist")
@@ -73,10 +582,27 @@ func (s *ChatService) DeleteChat(ctx context.Context, id int) error {
 		return fmt.Errorf("failed to get chat with id: %d", id)
 	}
 
-	err = s.repository.Chat.Delete(ctx, id)
+	if chat.IsDirect() {
+		return errors.New("chat does not exist")
+	}
+
+	if chat.OwnerID != nil && *chat.OwnerID == caller.ID {
+		return ErrOwnerLeave
+	}
+
+	left, err := s.repository.Member.Remove(ctx, id, caller.ID)
 	if err != nil {
-		return fmt.Errorf("failed to delete chat: %w", err)
+		return fmt.Errorf("failed to leave chat: %w", err)
+	}
+
+	if !left {
+		return ErrNotMember
 	}
 
+	s.log.Infof("User left chat (Chat ID: %d, User ID: %d)", id, caller.ID)
 	return nil
 }
+
+func directKey(a, b int) string {
+	return fmt.Sprintf("%d:%d", min(a, b), max(a, b))
+}
@@ -6,12 +6,18 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/events"
 	"github.com/AlGrushino/chat/internal/repository"
+	chatRepo "github.com/AlGrushino/chat/internal/repository/chat"
 	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/moderation"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
 )
 
 type MockChatRepository struct {
@@ -45,8 +51,8 @@ func (m *MockChatRepository) ChatExists(ctx context.Context, title string) (bool
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockChatRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Chat, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *MockChatRepository) GetAll(ctx context.Context, filter chatRepo.Filter) ([]*models.Chat, error) {
+	args := m.Called(ctx, filter)
 
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -65,6 +71,162 @@ func (m *MockChatRepository) Delete(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (m *MockChatRepository) GetByDMKey(ctx context.Context, key string) (*models.Chat, error) {
+	args := m.Called(ctx, key)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.Chat), args.Error(1)
+}
+
+func (m *MockChatRepository) CreateDirect(ctx context.Context, chat *models.Chat, userIDs []int) error {
+	args := m.Called(ctx, chat, userIDs)
+
+	if chat != nil && args.Error(0) == nil {
+		if chat.ID == 0 {
+			chat.ID = 1
+		}
+	}
+
+	return args.Error(0)
+}
+
+func (m *MockChatRepository) GetForUser(ctx context.Context, userID, limit, offset int) ([]*models.Chat, error) {
+	args := m.Called(ctx, userID, limit, offset)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]*models.Chat), args.Error(1)
+}
+
+func (m *MockChatRepository) UpdateTopic(ctx context.Context, id int, topic string) error {
+	args := m.Called(ctx, id, topic)
+	return args.Error(0)
+}
+
+func (m *MockChatRepository) UpdateMetadata(ctx context.Context, chat *models.Chat) error {
+	args := m.Called(ctx, chat)
+	return args.Error(0)
+}
+
+func (m *MockChatRepository) UpdateSlowMode(ctx context.Context, id, seconds int) error {
+	args := m.Called(ctx, id, seconds)
+	return args.Error(0)
+}
+
+func (m *MockChatRepository) UpdateRetention(ctx context.Context, id int, days *int) error {
+	args := m.Called(ctx, id, days)
+	return args.Error(0)
+}
+
+func (m *MockChatRepository) Import(ctx context.Context, histories []chatRepo.History, batchSize int) error {
+	args := m.Called(ctx, histories, batchSize)
+	return args.Error(0)
+}
+
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	args := m.Called(ctx, id)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []int) ([]*models.User, error) {
+	args := m.Called(ctx, ids)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.User, error) {
+	args := m.Called(ctx, tokenHash)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	args := m.Called(ctx, usernames)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	args := m.Called(ctx, username)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockMemberRepository struct {
+	repository.Member
+	mock.Mock
+}
+
+func (m *MockMemberRepository) IsMember(ctx context.Context, chatID, userID int) (bool, error) {
+	args := m.Called(ctx, chatID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) Add(ctx context.Context, chatID, userID int) (bool, error) {
+	args := m.Called(ctx, chatID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) Remove(ctx context.Context, chatID, userID int) (bool, error) {
+	args := m.Called(ctx, chatID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) CountByChatIDs(ctx context.Context, chatIDs []int) (map[int]int64, error) {
+	args := m.Called(ctx, chatIDs)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(map[int]int64), args.Error(1)
+}
+
+type MockModerationRepository struct {
+	repository.Moderation
+	mock.Mock
+}
+
+func (m *MockModerationRepository) GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error) {
+	args := m.Called(ctx, chatID, userID)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).([]*models.ChatRestriction), args.Error(1)
+}
+
 type MockLogger struct {
 	messages []string
 	errors   []string
@@ -103,19 +265,28 @@ func (m *MockLogger) ContainsWarn(msg string) bool {
 
 type ChatServiceTestSuite struct {
 	suite.Suite
-	ctx        context.Context
-	mockRepo   *MockChatRepository
-	mockLogger *logrus.Logger
-	service    *ChatService
+	ctx          context.Context
+	mockRepo     *MockChatRepository
+	mockUserRepo *MockUserRepository
+	mockMembers  *MockMemberRepository
+	mockBans     *MockModerationRepository
+	mockLogger   *logrus.Logger
+	service      *ChatService
 }
 
 func (suite *ChatServiceTestSuite) SetupTest() {
 	suite.ctx = context.Background()
 	suite.mockRepo = new(MockChatRepository)
+	suite.mockUserRepo = new(MockUserRepository)
+	suite.mockMembers = new(MockMemberRepository)
+	suite.mockBans = new(MockModerationRepository)
 	suite.mockLogger = logrus.New()
 	suite.service = NewChatService(suite.mockLogger, &repository.Repository{
-		Chat: suite.mockRepo,
-	})
+		Chat:       suite.mockRepo,
+		User:       suite.mockUserRepo,
+		Member:     suite.mockMembers,
+		Moderation: suite.mockBans,
+	}, events.NewBus(suite.mockLogger))
 }
 
 func (suite *ChatServiceTestSuite) TestCreateChat_Success() {
@@ -128,7 +299,7 @@ func (suite *ChatServiceTestSuite) TestCreateChat_Success() {
 	result, err := suite.service.CreateChat(suite.ctx, expectedTitle)
 
 	suite.NoError(err)
-	suite.Equal(expectedTitle, result)
+	suite.Equal(expectedTitle, result.Title)
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
@@ -159,6 +330,185 @@ func (suite *ChatServiceTestSuite) TestCreateChat_DatabaseError() {
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+func (suite *ChatServiceTestSuite) TestCreateDirect_CreatesOnce() {
+	ctx := auth.WithUser(suite.ctx, &models.User{ID: 7, Username: "alice"})
+	peer := &models.User{ID: 3, Username: "bob"}
+
+	suite.mockUserRepo.On("GetByID", ctx, 3).Return(peer, nil).Twice()
+	suite.mockRepo.On("GetByDMKey", ctx, "3:7").Return(nil, gorm.ErrRecordNotFound).Once()
+	suite.mockRepo.On("CreateDirect", ctx, mock.MatchedBy(func(chat *models.Chat) bool {
+		return chat.IsDirect() && chat.DMKey != nil && *chat.DMKey == "3:7"
+	}), []int{7, 3}).Return(nil).Once()
+
+	summary, created, err := suite.service.CreateDirect(ctx, 3)
+
+	suite.NoError(err)
+	suite.True(created)
+	suite.Equal(peer, summary.Peer)
+
+	suite.mockRepo.On("GetByDMKey", ctx, "3:7").Return(summary.Chat, nil).Once()
+
+	again, created, err := suite.service.CreateDirect(ctx, 3)
+
+	suite.NoError(err)
+	suite.False(created)
+	suite.Equal(summary.Chat.ID, again.Chat.ID)
+	suite.mockRepo.AssertExpectations(suite.T())
+	suite.mockUserRepo.AssertExpectations(suite.T())
+}
+
+func (suite *ChatServiceTestSuite) TestCreateDirect_RequiresUser() {
+	_, _, err := suite.service.CreateDirect(suite.ctx, 3)
+
+	suite.ErrorIs(err, auth.ErrUnauthenticated)
+}
+
+func (suite *ChatServiceTestSuite) TestCreateDirect_WithSelf() {
+	ctx := auth.WithUser(suite.ctx, &models.User{ID: 7, Username: "alice"})
+
+	_, _, err := suite.service.CreateDirect(ctx, 7)
+
+	suite.ErrorIs(err, ErrSelfDirect)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *ChatServiceTestSuite) TestUpdateChat_MergesSettings() {
+	owner := &models.User{ID: 7, Username: "alice"}
+	ctx := auth.WithUser(suite.ctx, owner)
+	chat := &models.Chat{
+		ID:         1,
+		Title:      "standup",
+		Type:       models.ChatTypeGroup,
+		OwnerID:    &owner.ID,
+		Visibility: models.VisibilityPublic,
+		Settings:   map[string]any{"color": "red", "emoji": "wave"},
+	}
+
+	description := "  Daily sync  "
+	visibility := models.VisibilityPrivate
+
+	suite.mockRepo.On("GetByID", ctx, 1).Return(chat, nil).Once()
+	suite.mockRepo.On("UpdateMetadata", ctx, chat).Return(nil).Once()
+
+	updated, err := suite.service.UpdateChat(ctx, 1, Update{
+		Description: &description,
+		Visibility:  &visibility,
+		Settings:    map[string]any{"color": nil, "muted": true},
+	})
+
+	suite.NoError(err)
+	suite.Equal("Daily sync", updated.Description)
+	suite.Equal(models.VisibilityPrivate, updated.Visibility)
+	suite.Equal(map[string]any{"emoji": "wave", "muted": true}, updated.Settings)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *ChatServiceTestSuite) TestUpdateChat_Validation() {
+	owner := &models.User{ID: 7, Username: "alice"}
+	ctx := auth.WithUser(suite.ctx, owner)
+	chat := &models.Chat{ID: 1, Title: "standup", Type: models.ChatTypeGroup, OwnerID: &owner.ID}
+
+	suite.mockRepo.On("GetByID", ctx, 1).Return(chat, nil)
+
+	visibility := "hidden"
+	_, err := suite.service.UpdateChat(ctx, 1, Update{Visibility: &visibility})
+	suite.ErrorIs(err, ErrInvalidVisibility)
+
+	topic := strings.Repeat("т", MaxTopicLength+1)
+	_, err = suite.service.UpdateChat(ctx, 1, Update{Topic: &topic})
+	suite.ErrorIs(err, ErrInvalidTopic)
+
+	_, err = suite.service.UpdateChat(ctx, 1, Update{Settings: map[string]any{"blob": strings.Repeat("x", MaxSettingsSize)}})
+	suite.ErrorIs(err, ErrInvalidSettings)
+
+	stranger := auth.WithUser(suite.ctx, &models.User{ID: 8, Username: "bob"})
+	suite.mockRepo.On("GetByID", stranger, 1).Return(chat, nil)
+	_, err = suite.service.UpdateChat(stranger, 1, Update{Topic: &visibility})
+	suite.ErrorIs(err, ErrNotOwner)
+
+	suite.mockRepo.AssertNotCalled(suite.T(), "UpdateMetadata", mock.Anything, mock.Anything)
+}
+
+func (suite *ChatServiceTestSuite) TestListPublic_CountsMembers() {
+	chats := []*models.Chat{
+		{ID: 4, Title: "go", Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic},
+		{ID: 2, Title: "gophers", Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic},
+	}
+
+	suite.mockRepo.On("GetAll", suite.ctx, chatRepo.Filter{
+		Type:       models.ChatTypeGroup,
+		Visibility: models.VisibilityPublic,
+		Query:      "go",
+		Sort:       chatRepo.SortActivity,
+		Limit:      20,
+	}).Return(chats, nil).Once()
+	suite.mockMembers.On("CountByChatIDs", suite.ctx, []int{4, 2}).Return(map[int]int64{4: 3}, nil).Once()
+
+	summaries, err := suite.service.ListPublic(suite.ctx, " go ", 0, -1)
+
+	suite.NoError(err)
+	suite.Len(summaries, 2)
+	suite.Equal(int64(3), summaries[0].Members)
+	suite.Equal(int64(0), summaries[1].Members)
+	suite.mockRepo.AssertExpectations(suite.T())
+	suite.mockMembers.AssertExpectations(suite.T())
+}
+
+func (suite *ChatServiceTestSuite) TestJoinChat_PrivateIsHidden() {
+	ctx := auth.WithUser(suite.ctx, &models.User{ID: 8, Username: "bob"})
+	ownerID := 7
+	chat := &models.Chat{ID: 1, Title: "secret", Type: models.ChatTypeGroup, Visibility: models.VisibilityPrivate, OwnerID: &ownerID}
+
+	suite.mockRepo.On("GetByID", ctx, 1).Return(chat, nil).Once()
+	suite.mockMembers.On("IsMember", ctx, 1, 8).Return(false, nil).Once()
+
+	_, _, err := suite.service.JoinChat(ctx, 1)
+
+	suite.EqualError(err, "chat does not exist")
+	suite.mockMembers.AssertNotCalled(suite.T(), "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ChatServiceTestSuite) TestJoinAndLeave() {
+	ctx := auth.WithUser(suite.ctx, &models.User{ID: 8, Username: "bob"})
+	ownerID := 7
+	chat := &models.Chat{ID: 1, Title: "go", Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic, OwnerID: &ownerID}
+
+	suite.mockRepo.On("GetByID", ctx, 1).Return(chat, nil)
+	suite.mockBans.On("GetActive", ctx, 1, 8).Return([]*models.ChatRestriction{}, nil)
+	suite.mockMembers.On("Add", ctx, 1, 8).Return(true, nil).Once()
+	suite.mockMembers.On("Remove", ctx, 1, 8).Return(true, nil).Once()
+	suite.mockMembers.On("Remove", ctx, 1, 8).Return(false, nil).Once()
+
+	_, joined, err := suite.service.JoinChat(ctx, 1)
+	suite.NoError(err)
+	suite.True(joined)
+
+	suite.NoError(suite.service.LeaveChat(ctx, 1))
+	suite.ErrorIs(suite.service.LeaveChat(ctx, 1), ErrNotMember)
+
+	owner := auth.WithUser(suite.ctx, &models.User{ID: 7, Username: "alice"})
+	suite.mockRepo.On("GetByID", owner, 1).Return(chat, nil).Once()
+	suite.ErrorIs(suite.service.LeaveChat(owner, 1), ErrOwnerLeave)
+}
+
+func (suite *ChatServiceTestSuite) TestJoinChat_Banned() {
+	ctx := auth.WithUser(suite.ctx, &models.User{ID: 8, Username: "bob"})
+	ownerID := 7
+	chat := &models.Chat{ID: 1, Title: "go", Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic, OwnerID: &ownerID}
+	expiresAt := time.Now().Add(time.Hour)
+
+	suite.mockRepo.On("GetByID", ctx, 1).Return(chat, nil)
+	suite.mockBans.On("GetActive", ctx, 1, 8).Return([]*models.ChatRestriction{
+		{ChatID: 1, UserID: 8, Kind: models.RestrictionMute},
+		{ChatID: 1, UserID: 8, Kind: models.RestrictionBan, ExpiresAt: &expiresAt},
+	}, nil)
+
+	_, _, err := suite.service.JoinChat(ctx, 1)
+	suite.ErrorIs(err, moderation.ErrBanned)
+	suite.Contains(err.Error(), "until")
+	suite.mockMembers.AssertNotCalled(suite.T(), "Add", ctx, 1, 8)
+}
+
 func TestChatServiceSuite(t *testing.T) {
 	suite.Run(t, new(ChatServiceTestSuite))
 }
@@ -0,0 +1,241 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/webhook"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxTopicLength = 250
+
+	callbackTimeout = 5 * time.Second
+	maxReplySize    = 64 << 10
+	shrug           = `¯\_(ツ)_/¯`
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Builtins lists the commands handled by the server itself, in /help order.
+var Builtins = []models.BotCommand{
+	{Name: "help", Description: "List available commands"},
+	{Name: "me", Description: "Post an action, e.g. /me waves"},
+	{Name: "shrug", Description: `Append ¯\_(ツ)_/¯ to a message`},
+	{Name: "topic", Description: "Show the chat topic, or set it with /topic <text>"},
+}
+
+// Reply is the outcome of a command. Ephemeral replies are shown to the
+// invoker only; the others are posted to the chat, as Bot when it is set and
+// as the invoker otherwise.
+type Reply struct {
+	Text      string
+	Ephemeral bool
+	Bot       *models.User
+}
+
+// Parse splits "/name args" into its parts. Text that does not start with a
+// valid command name, including "//"-escaped text, is not a command.
+func Parse(text string) (name, args string, ok bool) {
+	rest, found := strings.CutPrefix(text, "/")
+	if !found {
+		return "", "", false
+	}
+
+	name, args, _ = strings.Cut(rest, " ")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !namePattern.MatchString(name) {
+		return "", "", false
+	}
+
+	return name, strings.TrimSpace(args), true
+}
+
+// Unescape turns "//text" into the literal "/text".
+func Unescape(text string) string {
+	if strings.HasPrefix(text, "//") {
+		return text[1:]
+	}
+	return text
+}
+
+type Router struct {
+	repository *repository.Repository
+	client     *http.Client
+	log        *logrus.Logger
+}
+
+func NewRouter(log *logrus.Logger, repository *repository.Repository) *Router {
+	return &Router{
+		repository: repository,
+		client:     &http.Client{Timeout: callbackTimeout},
+		log:        log,
+	}
+}
+
+// Run executes a parsed command sent by user in chat.
+func (r *Router) Run(ctx context.Context, chat *models.Chat, user *models.User, name, args string) (*Reply, error) {
+	switch name {
+	case "help":
+		return r.help(ctx)
+	case "me":
+		if args == "" {
+			return ephemeral("Usage: /me <action>"), nil
+		}
+		return &Reply{Text: fmt.Sprintf("_%s %s_", user.Username, args)}, nil
+	case "shrug":
+		if args == "" {
+			return &Reply{Text: shrug}, nil
+		}
+		return &Reply{Text: args + " " + shrug}, nil
+	case "topic":
+		return r.topic(ctx, chat, user, args)
+	}
+
+	command, err := r.repository.Bot.GetCommand(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ephemeral(fmt.Sprintf("Unknown command /%s. Type /help for a list of commands.", name)), nil
+		}
+		return nil, fmt.Errorf("failed to get command %s: %w", name, err)
+	}
+
+	if chat.IsDirect() {
+		return ephemeral("Bot commands are not available in direct chats."), nil
+	}
+
+	return r.dispatch(ctx, command, chat, user, args), nil
+}
+
+func (r *Router) help(ctx context.Context) (*Reply, error) {
+	commands, err := r.repository.Bot.GetCommands(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commands: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, command := range Builtins {
+		fmt.Fprintf(&b, "\n/%s - %s", command.Name, command.Description)
+	}
+	for _, command := range commands {
+		fmt.Fprintf(&b, "\n/%s - %s", command.Name, command.Description)
+	}
+	b.WriteString("\nStart a message with // to send it as text.")
+
+	return ephemeral(b.String()), nil
+}
+
+func (r *Router) topic(ctx context.Context, chat *models.Chat, user *models.User, args string) (*Reply, error) {
+	if args == "" {
+		if chat.Topic == "" {
+			return ephemeral("This chat has no topic."), nil
+		}
+		return ephemeral("Topic: " + chat.Topic), nil
+	}
+
+	if utf8.RuneCountInString(args) > MaxTopicLength {
+		return ephemeral(fmt.Sprintf("Topic must be at most %d characters.", MaxTopicLength)), nil
+	}
+
+	if err := r.repository.Chat.UpdateTopic(ctx, chat.ID, args); err != nil {
+		return nil, fmt.Errorf("failed to update topic: %w", err)
+	}
+	chat.Topic = args
+
+	return &Reply{Text: fmt.Sprintf("%s changed the topic to: %s", user.Username, args)}, nil
+}
+
+type callbackRequest struct {
+	Command string       `json:"command"`
+	Text    string       `json:"text"`
+	ChatID  int          `json:"chat_id"`
+	User    callbackUser `json:"user"`
+}
+
+type callbackUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type callbackResponse struct {
+	Text      string `json:"text"`
+	Ephemeral bool   `json:"ephemeral"`
+}
+
+// dispatch posts the command to the bot's callback URL and turns its answer
+// into a reply. Failures are reported to the invoker only.
+func (r *Router) dispatch(ctx context.Context, command *models.BotCommand, chat *models.Chat, user *models.User, args string) *Reply {
+	bot := command.Bot
+	log := r.log.WithFields(logrus.Fields{"command": command.Name, "bot_id": command.BotID})
+
+	failed := ephemeral(fmt.Sprintf("/%s failed: the bot did not respond.", command.Name))
+	if bot == nil || bot.CallbackURL == nil || bot.CallbackSecret == nil {
+		log.Warn("Bot has no callback configured")
+		return failed
+	}
+
+	body, err := json.Marshal(callbackRequest{
+		Command: command.Name,
+		Text:    args,
+		ChatID:  chat.ID,
+		User:    callbackUser{ID: user.ID, Username: user.Username},
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to encode bot callback")
+		return failed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *bot.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).Error("Failed to build bot callback request")
+		return failed
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(*bot.CallbackSecret, body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.WithError(err).Warn("Bot callback failed")
+		return failed
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Warnf("Bot callback returned status %d", resp.StatusCode)
+		return failed
+	}
+
+	var answer callbackResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxReplySize)).Decode(&answer); err != nil {
+		if errors.Is(err, io.EOF) {
+			// An empty body acknowledges the command without a reply.
+			return ephemeral("")
+		}
+		log.WithError(err).Warn("Invalid bot callback response")
+		return failed
+	}
+
+	if answer.Text == "" {
+		return ephemeral("")
+	}
+
+	return &Reply{Text: answer.Text, Ephemeral: answer.Ephemeral, Bot: bot}
+}
+
+func ephemeral(text string) *Reply {
+	return &Reply{Text: text, Ephemeral: true}
+}
@@ -0,0 +1,41 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		args string
+		ok   bool
+	}{
+		{text: "/help", name: "help", ok: true},
+		{text: "/me  waves hello ", name: "me", args: "waves hello", ok: true},
+		{text: "/Topic New topic", name: "topic", args: "New topic", ok: true},
+		{text: "/deploy-prod now", name: "deploy-prod", args: "now", ok: true},
+		{text: "hello /help"},
+		{text: "//help"},
+		{text: "/usr/bin/env"},
+		{text: "/"},
+		{text: "/ help"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			name, args, ok := Parse(tt.text)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.name, name)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	assert.Equal(t, "/help", Unescape("//help"))
+	assert.Equal(t, "/usr/bin", Unescape("/usr/bin"))
+	assert.Equal(t, "plain", Unescape("plain"))
+}
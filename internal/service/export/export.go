@@ -0,0 +1,156 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChatNotFound  = errors.New("chat does not exist")
+	ErrNotFound      = errors.New("export does not exist")
+	ErrInvalidFormat = errors.New("export format must be json, csv or html")
+	ErrNotReady      = errors.New("export is not finished yet")
+)
+
+type ExportService struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	worker     *Worker
+	log        *logrus.Logger
+}
+
+func NewExportService(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore, worker *Worker) *ExportService {
+	return &ExportService{
+		repository: repository,
+		store:      store,
+		worker:     worker,
+		log:        log,
+	}
+}
+
+// Transcript checks that the caller can read the chat and returns its
+// history, ready to be written.
+func (s *ExportService) Transcript(ctx context.Context, chatID int, format string) (*Transcript, error) {
+	if !ValidFormat(format) {
+		return nil, ErrInvalidFormat
+	}
+
+	chat, err := s.getChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transcript{Chat: chat, Format: format, messages: s.repository.Message}, nil
+}
+
+// CreateExport queues an archive of the chat history for the caller.
+func (s *ExportService) CreateExport(ctx context.Context, chatID int, format string) (*models.ExportJob, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if !ValidFormat(format) {
+		return nil, ErrInvalidFormat
+	}
+
+	if _, err := s.getChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	job := &models.ExportJob{
+		ChatID: chatID,
+		UserID: caller.ID,
+		Format: format,
+		Status: models.ExportPending,
+	}
+	if err := s.repository.Export.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"export_id": job.ID,
+		"chat_id":   chatID,
+		"user_id":   caller.ID,
+		"format":    format,
+	}).Info("Export queued")
+
+	s.worker.Wake()
+
+	return job, nil
+}
+
+// GetExport returns an export of the caller. Exports of other users are
+// reported as missing.
+func (s *ExportService) GetExport(ctx context.Context, id int) (*models.ExportJob, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	job, err := s.repository.Export.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+
+	if job.UserID != caller.ID {
+		return nil, ErrNotFound
+	}
+
+	return job, nil
+}
+
+// OpenExport opens the archive of a finished export. The caller closes it.
+func (s *ExportService) OpenExport(ctx context.Context, id int) (io.ReadCloser, *models.ExportJob, error) {
+	job, err := s.GetExport(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.Status != models.ExportSucceeded || job.BlobKey == nil {
+		return nil, nil, ErrNotReady
+	}
+
+	rc, err := s.store.Get(ctx, *job.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open export archive: %w", err)
+	}
+
+	return rc, job, nil
+}
+
+func (s *ExportService) getChat(ctx context.Context, id int) (*models.Chat, error) {
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return nil, ErrChatNotFound
+	}
+
+	return chat, nil
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMessages struct {
+	repository.Message
+
+	messages []*models.Message
+	queries  int
+}
+
+func (f *fakeMessages) GetAfterID(ctx context.Context, chatID, afterID, limit int) ([]*models.Message, error) {
+	f.queries++
+
+	var page []*models.Message
+	for _, m := range f.messages {
+		if m.ChatID == chatID && m.ID > afterID && len(page) < limit {
+			page = append(page, m)
+		}
+	}
+	return page, nil
+}
+
+func newMessages(n int) *fakeMessages {
+	alice := &models.User{ID: 1, Username: "alice"}
+	hookName := "CI"
+	created := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	f := &fakeMessages{}
+	for i := 1; i <= n; i++ {
+		m := &models.Message{ID: i, ChatID: 7, Text: "message, \"quoted\"\n<b>line</b>", CreatedAt: created.Add(time.Duration(i) * time.Second)}
+		if i%2 == 0 {
+			m.AuthorName = &hookName
+		} else {
+			m.AuthorID, m.Author = &alice.ID, alice
+		}
+		f.messages = append(f.messages, m)
+	}
+	f.messages[0].Attachments = []models.Attachment{{ID: 3, Filename: "../report.pdf", ContentType: "application/pdf", Size: 4, BlobKey: "blob/3"}}
+	return f
+}
+
+func newTranscript(messages *fakeMessages, format string) *Transcript {
+	return &Transcript{
+		Chat:     &models.Chat{ID: 7, Title: "Support <team>"},
+		Format:   format,
+		messages: messages,
+	}
+}
+
+func TestTranscript_JSONPagesThroughHistory(t *testing.T) {
+	messages := newMessages(PageSize + 3)
+
+	var buf bytes.Buffer
+	count, err := newTranscript(messages, FormatJSON).Write(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, PageSize+3, count)
+	assert.Equal(t, 2, messages.queries)
+
+	var decoded struct {
+		Chat     chatEntry      `json:"chat"`
+		Messages []messageEntry `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "Support <team>", decoded.Chat.Title)
+	require.Len(t, decoded.Messages, PageSize+3)
+	assert.Equal(t, "alice", decoded.Messages[0].Author)
+	assert.Equal(t, "CI", decoded.Messages[1].Author)
+	assert.Equal(t, PageSize+3, decoded.Messages[PageSize+2].ID)
+	assert.Equal(t, "../report.pdf", decoded.Messages[0].Attachments[0].Filename)
+	assert.Empty(t, decoded.Messages[0].Attachments[0].Path)
+}
+
+func TestTranscript_EmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	count, err := newTranscript(&fakeMessages{}, FormatJSON).Write(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, json.Valid(buf.Bytes()))
+}
+
+func TestTranscript_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newTranscript(newMessages(2), FormatCSV).Write(context.Background(), &buf)
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"id", "created_at", "author_id", "author", "text", "attachments"}, rows[0])
+	assert.Equal(t, []string{"1", "2026-10-19T12:00:01Z", "1", "alice", "message, \"quoted\"\n<b>line</b>", "../report.pdf"}, rows[1])
+	assert.Equal(t, "", rows[2][2])
+	assert.Equal(t, "CI", rows[2][3])
+}
+
+func TestTranscript_HTMLEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newTranscript(newMessages(2), FormatHTML).Write(context.Background(), &buf)
+	require.NoError(t, err)
+
+	html := buf.String()
+	assert.Contains(t, html, "<title>Support &lt;team&gt;</title>")
+	assert.Contains(t, html, "&lt;b&gt;line&lt;/b&gt;")
+	assert.NotContains(t, html, "<b>line</b>")
+	assert.Equal(t, 2, strings.Count(html, "<article"))
+	assert.True(t, strings.HasSuffix(html, "</html>\n"))
+}
+
+func TestWorker_BuildsArchive(t *testing.T) {
+	store := testutil.NewBlobStore(map[string][]byte{"blob/3": []byte("%PDF")})
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	worker := NewWorker(log, &repository.Repository{
+		Message: newMessages(2),
+		Chat:    testutil.NewChats(&models.Chat{ID: 7, Title: "Support <team>", Type: models.ChatTypeGroup}),
+	}, store)
+
+	job := &models.ExportJob{ID: 5, ChatID: 7, Format: FormatCSV}
+	require.NoError(t, worker.build(context.Background(), job))
+
+	require.NotNil(t, job.BlobKey)
+	assert.Equal(t, "exports/5/chat-7.zip", *job.BlobKey)
+	assert.Equal(t, 2, job.Messages)
+	assert.Equal(t, int64(len(store.Blobs[*job.BlobKey])), job.Size)
+
+	archive, err := zip.NewReader(bytes.NewReader(store.Blobs[*job.BlobKey]), job.Size)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+
+	assert.Equal(t, "%PDF", files["attachments/3-.._report.pdf"])
+	assert.Contains(t, files["chat-7.csv"], "attachments/3-.._report.pdf")
+}
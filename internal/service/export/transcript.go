@@ -0,0 +1,346 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+
+	// PageSize is how many messages a transcript reads per query.
+	PageSize = 500
+)
+
+var contentTypes = map[string]string{
+	FormatJSON: "application/json",
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatHTML: "text/html; charset=utf-8",
+}
+
+func ValidFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// Transcript is the complete message history of a chat in one format. It is
+// read page by page while it is written, so its size is not limited by
+// memory.
+type Transcript struct {
+	Chat   *models.Chat
+	Format string
+
+	messages repository.Message
+	// archive links attachments to their copies inside an export archive.
+	archive bool
+	// visit is called for every message after it was written.
+	visit func(*models.Message)
+}
+
+func (t *Transcript) ContentType() string {
+	return contentTypes[t.Format]
+}
+
+func (t *Transcript) Filename() string {
+	return fmt.Sprintf("chat-%d.%s", t.Chat.ID, t.Format)
+}
+
+// Write streams the transcript to w, oldest message first, and returns the
+// number of messages written.
+func (t *Transcript) Write(ctx context.Context, w io.Writer) (int, error) {
+	buf := bufio.NewWriter(w)
+	enc := newEncoder(t.Format, buf)
+
+	if err := enc.begin(newChatEntry(t.Chat)); err != nil {
+		return 0, err
+	}
+
+	count, afterID := 0, 0
+	for {
+		page, err := t.messages.GetAfterID(ctx, t.Chat.ID, afterID, PageSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to read messages: %w", err)
+		}
+
+		for _, message := range page {
+			if err := enc.message(newMessageEntry(message, t.archive)); err != nil {
+				return count, err
+			}
+			if t.visit != nil {
+				t.visit(message)
+			}
+			count++
+		}
+
+		if err := enc.flush(); err != nil {
+			return count, err
+		}
+		if err := buf.Flush(); err != nil {
+			return count, err
+		}
+
+		if len(page) < PageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	if err := enc.end(); err != nil {
+		return count, err
+	}
+	return count, buf.Flush()
+}
+
+type chatEntry struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Type       string    `json:"type"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+func newChatEntry(chat *models.Chat) chatEntry {
+	return chatEntry{
+		ID:         chat.ID,
+		Title:      chat.Title,
+		Type:       chat.Type,
+		ExportedAt: time.Now().UTC(),
+	}
+}
+
+// messageEntry is a message as it appears in a transcript. Author is the
+// username, the display name of a webhook, or empty for deleted users.
+type messageEntry struct {
+	ID          int               `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	AuthorID    *int              `json:"author_id,omitempty"`
+	Author      string            `json:"author"`
+	Text        string            `json:"text"`
+	Attachments []attachmentEntry `json:"attachments,omitempty"`
+}
+
+// attachmentEntry.Path is the file inside an export archive.
+type attachmentEntry struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Path        string `json:"path,omitempty"`
+}
+
+func newMessageEntry(m *models.Message, archive bool) messageEntry {
+	entry := messageEntry{
+		ID:        m.ID,
+		CreatedAt: m.CreatedAt.UTC(),
+		AuthorID:  m.AuthorID,
+		Text:      m.Text,
+	}
+
+	switch {
+	case m.Author != nil:
+		entry.Author = m.Author.Username
+	case m.AuthorName != nil:
+		entry.Author = *m.AuthorName
+	}
+
+	for i := range m.Attachments {
+		a := &m.Attachments[i]
+		attachment := attachmentEntry{
+			ID:          a.ID,
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		}
+		if archive {
+			attachment.Path = archivePath(a)
+		}
+		entry.Attachments = append(entry.Attachments, attachment)
+	}
+
+	return entry
+}
+
+// archivePath names the copy of an attachment inside an export archive. The
+// ID keeps names unique; path separators in the original name are dropped.
+func archivePath(a *models.Attachment) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, a.Filename)
+	return fmt.Sprintf("attachments/%d-%s", a.ID, name)
+}
+
+type encoder interface {
+	begin(chat chatEntry) error
+	message(entry messageEntry) error
+	// flush is called after every page.
+	flush() error
+	end() error
+}
+
+func newEncoder(format string, w io.Writer) encoder {
+	switch format {
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}
+	case FormatHTML:
+		return &htmlEncoder{w: w}
+	default:
+		return &jsonEncoder{w: w}
+	}
+}
+
+// jsonEncoder writes {"chat": {...}, "messages": [...]} with one message per
+// line.
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) begin(chat chatEntry) error {
+	header, err := json.Marshal(chat)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, "{\"chat\":%s,\"messages\":[", header)
+	return err
+}
+
+func (e *jsonEncoder) message(entry messageEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "\n"
+	}
+	e.count++
+
+	_, err = fmt.Fprintf(e.w, "%s%s", sep, data)
+	return err
+}
+
+func (e *jsonEncoder) flush() error {
+	return nil
+}
+
+func (e *jsonEncoder) end() error {
+	_, err := io.WriteString(e.w, "\n]}\n")
+	return err
+}
+
+// csvEncoder writes one row per message. Attachments are listed by filename,
+// or by archive path, separated by semicolons.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) begin(chat chatEntry) error {
+	return e.w.Write([]string{"id", "created_at", "author_id", "author", "text", "attachments"})
+}
+
+func (e *csvEncoder) message(entry messageEntry) error {
+	authorID := ""
+	if entry.AuthorID != nil {
+		authorID = strconv.Itoa(*entry.AuthorID)
+	}
+
+	files := make([]string, 0, len(entry.Attachments))
+	for _, a := range entry.Attachments {
+		if a.Path != "" {
+			files = append(files, a.Path)
+		} else {
+			files = append(files, a.Filename)
+		}
+	}
+
+	return e.w.Write([]string{
+		strconv.Itoa(entry.ID),
+		entry.CreatedAt.Format(time.RFC3339),
+		authorID,
+		entry.Author,
+		entry.Text,
+		strings.Join(files, ";"),
+	})
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) end() error {
+	return e.flush()
+}
+
+var htmlTemplates = template.Must(template.New("transcript").Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+article { border-bottom: 1px solid #ddd; padding: 0.5em 0; }
+time { color: #777; font-size: 0.9em; }
+p { white-space: pre-wrap; margin: 0.3em 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Exported <time datetime="{{.ExportedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.ExportedAt.Format "2006-01-02 15:04:05 MST"}}</time></p>
+{{end -}}
+{{- define "message" -}}
+<article id="m{{.ID}}">
+<header><strong>{{if .Author}}{{.Author}}{{else}}deleted user{{end}}</strong> <time datetime="{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</time></header>
+<p>{{.Text}}</p>
+{{- with .Attachments}}
+<ul>
+{{- range .}}
+<li>{{if .Path}}<a href="{{.Path}}">{{.Filename}}</a>{{else}}{{.Filename}}{{end}} ({{.ContentType}}, {{.Size}} bytes)</li>
+{{- end}}
+</ul>
+{{- end}}
+</article>
+{{end -}}
+{{- define "end" -}}
+</body>
+</html>
+{{end -}}
+`))
+
+type htmlEncoder struct {
+	w io.Writer
+}
+
+func (e *htmlEncoder) begin(chat chatEntry) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "begin", chat)
+}
+
+func (e *htmlEncoder) message(entry messageEntry) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "message", entry)
+}
+
+func (e *htmlEncoder) flush() error {
+	return nil
+}
+
+func (e *htmlEncoder) end() error {
+	return htmlTemplates.ExecuteTemplate(e.w, "end", nil)
+}
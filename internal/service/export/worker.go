@@ -0,0 +1,249 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MaxAttempts = 3
+
+	exportInterval   = 5 * time.Second
+	exportLease      = 30 * time.Minute
+	exportRetryDelay = time.Minute
+)
+
+// Worker builds export archives: a zip with the transcript and a copy of
+// every attachment. Jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED,
+// so several instances can run it side by side.
+type Worker struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	wake       chan struct{}
+	log        *logrus.Logger
+}
+
+func NewWorker(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore) *Worker {
+	return &Worker{
+		repository: repository,
+		store:      store,
+		wake:       make(chan struct{}, 1),
+		log:        log,
+	}
+}
+
+// Wake makes the worker look for new jobs without waiting for the next
+// poll.
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Info("Export worker started")
+	defer w.log.Info("Export worker stopped")
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+			w.drain(ctx)
+		case <-ticker.C:
+			w.drain(ctx)
+		}
+	}
+}
+
+// drain processes jobs one at a time until none are pending.
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := w.repository.Export.ClaimPending(ctx, 1, exportLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.log.WithError(err).Error("Failed to claim export jobs")
+			}
+			return
+		}
+
+		if len(jobs) == 0 {
+			return
+		}
+
+		for _, job := range jobs {
+			w.process(ctx, job)
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *models.ExportJob) {
+	log := w.log.WithFields(logrus.Fields{
+		"export_id": job.ID,
+		"chat_id":   job.ChatID,
+		"attempt":   job.Attempts,
+	})
+
+	start := time.Now()
+	err := w.build(ctx, job)
+	if ctx.Err() != nil {
+		// The lease runs out and another worker takes over.
+		return
+	}
+
+	now := time.Now()
+	if err == nil {
+		job.CompletedAt = &now
+		if err := w.repository.Export.Complete(ctx, job); err != nil {
+			log.WithError(err).Error("Failed to mark export as finished")
+			return
+		}
+		log.WithFields(logrus.Fields{
+			"messages":    job.Messages,
+			"size":        job.Size,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}).Info("Export finished")
+		return
+	}
+
+	lastError := err.Error()
+	if len(lastError) > 1000 {
+		lastError = lastError[:1000]
+	}
+	job.LastError = &lastError
+
+	if job.Attempts >= MaxAttempts {
+		job.CompletedAt = &now
+		if err := w.repository.Export.Fail(ctx, job); err != nil {
+			log.WithError(err).Error("Failed to mark export as failed")
+			return
+		}
+		log.WithError(err).Error("Export failed")
+		return
+	}
+
+	retryAt := now.Add(exportRetryDelay)
+	job.ClaimedUntil = &retryAt
+	if err := w.repository.Export.Release(ctx, job); err != nil {
+		log.WithError(err).Error("Failed to release export job")
+		return
+	}
+	log.WithError(err).Warn("Export failed, will retry")
+}
+
+// build streams the archive of job into the blob store.
+func (w *Worker) build(ctx context.Context, job *models.ExportJob) error {
+	chat, err := w.repository.Chat.GetByID(ctx, job.ChatID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	transcript := &Transcript{
+		Chat:     chat,
+		Format:   job.Format,
+		messages: w.repository.Message,
+		archive:  true,
+	}
+
+	key := fmt.Sprintf("exports/%d/chat-%d.zip", job.ID, job.ChatID)
+
+	pr, pw := io.Pipe()
+	written := &countingWriter{w: pw}
+	done := make(chan error, 1)
+
+	go func() {
+		count, err := w.writeArchive(ctx, transcript, written)
+		job.Messages = count
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	putErr := w.store.Put(ctx, key, pr)
+	pr.CloseWithError(putErr)
+
+	if err := <-done; err != nil {
+		return err
+	}
+	if putErr != nil {
+		return fmt.Errorf("failed to store export archive: %w", putErr)
+	}
+
+	job.BlobKey = &key
+	job.Size = written.n
+	return nil
+}
+
+// writeArchive writes the transcript and then the attachments it refers
+// to. Attachments whose blob is gone are left out.
+func (w *Worker) writeArchive(ctx context.Context, transcript *Transcript, out io.Writer) (int, error) {
+	archive := zip.NewWriter(out)
+
+	var attachments []models.Attachment
+	transcript.visit = func(m *models.Message) {
+		attachments = append(attachments, m.Attachments...)
+	}
+
+	file, err := archive.Create(transcript.Filename())
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := transcript.Write(ctx, file)
+	if err != nil {
+		return count, err
+	}
+
+	for i := range attachments {
+		if err := w.copyAttachment(ctx, archive, &attachments[i]); err != nil {
+			return count, err
+		}
+	}
+
+	return count, archive.Close()
+}
+
+func (w *Worker) copyAttachment(ctx context.Context, archive *zip.Writer, a *models.Attachment) error {
+	rc, err := w.store.Get(ctx, a.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			w.log.WithField("attachment_id", a.ID).Warn("Attachment blob is missing, leaving it out of the export")
+			return nil
+		}
+		return fmt.Errorf("failed to open attachment %d: %w", a.ID, err)
+	}
+	defer rc.Close()
+
+	file, err := archive.Create(archivePath(a))
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, rc); err != nil {
+		return fmt.Errorf("failed to copy attachment %d: %w", a.ID, err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
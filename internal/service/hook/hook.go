@@ -0,0 +1,222 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/message"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultRateLimit = 30
+	MaxRateLimit     = 600
+)
+
+var (
+	ErrNotFound         = errors.New("hook does not exist")
+	ErrChatNotFound     = errors.New("chat does not exist")
+	ErrInvalidName      = errors.New("hook name must be 1-80 characters")
+	ErrInvalidRateLimit = fmt.Errorf("hook rate limit must be between 1 and %d messages per minute", MaxRateLimit)
+	ErrInvalidToken     = errors.New("invalid hook token")
+)
+
+// RateLimitError is returned by Post when the hook has used up its quota.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("hook rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+type MessagePoster interface {
+	AddMessage(ctx context.Context, id int, msg message.NewMessage) (*models.Message, error)
+}
+
+type HookService struct {
+	repository *repository.Repository
+	messages   MessagePoster
+	limiter    ratelimit.Limiter
+	log        *logrus.Logger
+}
+
+func NewHookService(log *logrus.Logger, repository *repository.Repository, messages MessagePoster, limiter ratelimit.Limiter) *HookService {
+	return &HookService{
+		repository: repository,
+		messages:   messages,
+		limiter:    limiter,
+		log:        log,
+	}
+}
+
+// CreateHook creates an incoming webhook for a group chat and returns it
+// with its token. Only a hash of the token is stored.
+func (s *HookService) CreateHook(ctx context.Context, chatID int, name string, rateLimit int) (*models.IncomingHook, string, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, "", auth.ErrUnauthenticated
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) < 1 || len(name) > 80 {
+		return nil, "", ErrInvalidName
+	}
+
+	if rateLimit == 0 {
+		rateLimit = DefaultRateLimit
+	}
+
+	if rateLimit < 1 || rateLimit > MaxRateLimit {
+		return nil, "", ErrInvalidRateLimit
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrChatNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get chat with id: %d", chatID)
+	}
+
+	// Hooks post without a user, which direct chats do not allow.
+	if chat.IsDirect() {
+		return nil, "", ErrChatNotFound
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook := models.IncomingHook{
+		ChatID:    chatID,
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: auth.HashToken(token),
+		RateLimit: rateLimit,
+	}
+
+	if err := s.repository.Hook.Create(ctx, &hook); err != nil {
+		s.log.WithError(err).Error("Failed to create hook in database")
+		return nil, "", fmt.Errorf("failed to create hook: %w", err)
+	}
+
+	s.log.Infof("Incoming hook created successfully (ID: %d, chat: %d, name: %q)", hook.ID, chatID, name)
+	return &hook, token, nil
+}
+
+func (s *HookService) ListHooks(ctx context.Context, chatID int) ([]*models.IncomingHook, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	hooks, err := s.repository.Hook.GetByChatAndUser(ctx, chatID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// RotateHook replaces the hook token. The old token stops working at once.
+func (s *HookService) RotateHook(ctx context.Context, chatID, id int) (*models.IncomingHook, string, error) {
+	hook, err := s.ownHook(ctx, chatID, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if hook.RevokedAt != nil {
+		return nil, "", ErrNotFound
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook.TokenHash = auth.HashToken(token)
+	if err := s.repository.Hook.UpdateToken(ctx, hook.ID, hook.TokenHash); err != nil {
+		return nil, "", fmt.Errorf("failed to rotate hook token: %w", err)
+	}
+
+	s.log.Infof("Incoming hook token rotated (ID: %d)", hook.ID)
+	return hook, token, nil
+}
+
+func (s *HookService) RevokeHook(ctx context.Context, chatID, id int) error {
+	hook, err := s.ownHook(ctx, chatID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.Hook.Revoke(ctx, hook.ID); err != nil {
+		return fmt.Errorf("failed to revoke hook: %w", err)
+	}
+
+	s.log.Infof("Incoming hook revoked (ID: %d)", hook.ID)
+	return nil
+}
+
+// Post creates a message in the hook's chat on behalf of the hook.
+func (s *HookService) Post(ctx context.Context, token, text string) (*models.Message, error) {
+	hook, err := s.repository.Hook.GetActiveByTokenHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get hook: %w", err)
+	}
+
+	result, err := s.limiter.Allow(ctx, "hook:"+strconv.Itoa(hook.ID), ratelimit.Limit{
+		Requests: hook.RateLimit,
+		Per:      time.Minute,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check hook rate limit: %w", err)
+	}
+
+	if !result.Allowed {
+		s.log.Warnf("Incoming hook %d is rate limited", hook.ID)
+		return nil, &RateLimitError{RetryAfter: result.RetryAfter}
+	}
+
+	// Hooks never act as a user, whatever credentials came with the request.
+	ctx = auth.WithUser(ctx, nil)
+
+	return s.messages.AddMessage(ctx, hook.ChatID, message.NewMessage{
+		Text:       text,
+		AuthorName: hook.Name,
+	})
+}
+
+func (s *HookService) ownHook(ctx context.Context, chatID, id int) (*models.IncomingHook, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	hook, err := s.repository.Hook.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get hook with id: %d", id)
+	}
+
+	if hook.ChatID != chatID || hook.UserID != user.ID {
+		return nil, ErrNotFound
+	}
+
+	return hook, nil
+}
@@ -0,0 +1,171 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultTTL   = 24 * time.Hour
+	MaxKeyLength = 255
+
+	// lockTimeout is how long a reservation that never completed, e.g.
+	// because the instance crashed, blocks retries with the same key.
+	lockTimeout   = time.Minute
+	sweepInterval = 10 * time.Minute
+)
+
+var (
+	ErrInvalidKey = fmt.Errorf("idempotency key must be 1-%d printable ASCII characters", MaxKeyLength)
+	ErrKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+type Config struct {
+	TTL time.Duration
+}
+
+// GetConfig reads IDEMPOTENCY_TTL, a Go duration such as "24h".
+func GetConfig(log *logrus.Logger) *Config {
+	log.Info("Getting idempotency config from env")
+
+	cfg := Config{TTL: DefaultTTL}
+
+	if raw := os.Getenv("IDEMPOTENCY_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			log.Warnf("Invalid IDEMPOTENCY_TTL %q, using %s", raw, DefaultTTL)
+		} else {
+			cfg.TTL = ttl
+		}
+	}
+
+	return &cfg
+}
+
+type IdempotencyService struct {
+	repository *repository.Repository
+	ttl        time.Duration
+	log        *logrus.Logger
+}
+
+func NewIdempotencyService(log *logrus.Logger, repository *repository.Repository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repository: repository,
+		ttl:        ttl,
+		log:        log,
+	}
+}
+
+// SetTTL changes how long responses are kept for keys reserved from now on.
+func (s *IdempotencyService) SetTTL(ttl time.Duration) {
+	s.ttl = ttl
+}
+
+// Begin reserves key within scope for a request with the given hash. A nil
+// record means the caller owns the key and must Complete or Release it; a
+// non-nil record is the stored response of the original request.
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key, requestHash string) (*models.IdempotencyKey, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	record := models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+
+	reserved, err := s.repository.Idempotency.Reserve(ctx, &record, time.Now().Add(-lockTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.repository.Idempotency.Get(ctx, scope, key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between the two queries; the client may simply retry.
+			return nil, ErrInProgress
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+
+	if !existing.Completed() {
+		return nil, ErrInProgress
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response of a request that reserved key with Begin. A
+// non-zero expiresAt shortens how long it is kept.
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	if err := s.repository.Idempotency.Complete(ctx, scope, key, status, contentType, body, expiresAt); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees key so that a retry runs the request again.
+func (s *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+	if err := s.repository.Idempotency.Release(ctx, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Run deletes expired keys until ctx is cancelled.
+func (s *IdempotencyService) Run(ctx context.Context) {
+	s.log.Info("Idempotency key sweeper started")
+	defer s.log.Info("Idempotency key sweeper stopped")
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.repository.Idempotency.DeleteExpired(ctx, time.Now())
+			if err != nil {
+				s.log.WithError(err).Error("Failed to delete expired idempotency keys")
+				continue
+			}
+			if deleted > 0 {
+				s.log.Infof("Deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
+
+func validKey(key string) bool {
+	if len(key) < 1 || len(key) > MaxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,239 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Archive is chat history exported from another tool. User IDs are the
+// source tool's and only link messages to Users.
+type Archive struct {
+	Users []User `json:"users"`
+	Chats []Chat `json:"chats"`
+}
+
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Chat struct {
+	Title     string    `json:"title"`
+	Topic     string    `json:"topic,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message.User refers to a User; AuthorName is used for authors without
+// one, such as bots.
+type Message struct {
+	User       string    `json:"user,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Open reads an archive from a JSON file in the format of Archive, or from
+// a Slack export given as a directory or a zip file.
+func Open(name string) (*Archive, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return ReadSlack(os.DirFS(name))
+	}
+
+	if strings.EqualFold(path.Ext(name), ".zip") {
+		r, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer r.Close()
+		return ReadSlack(r)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var archive Archive
+	if err := json.NewDecoder(f).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	return &archive, nil
+}
+
+type slackUser struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Profile struct {
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	Name    string `json:"name"`
+	Created int64  `json:"created"`
+	Topic   struct {
+		Value string `json:"value"`
+	} `json:"topic"`
+	Purpose struct {
+		Value string `json:"value"`
+	} `json:"purpose"`
+}
+
+type slackMessage struct {
+	Type       string `json:"type"`
+	Subtype    string `json:"subtype"`
+	User       string `json:"user"`
+	Username   string `json:"username"`
+	Text       string `json:"text"`
+	TS         string `json:"ts"`
+	BotProfile *struct {
+		Name string `json:"name"`
+	} `json:"bot_profile"`
+}
+
+// slackSubtypes are the message subtypes carrying user content. Others,
+// such as channel joins, are notices generated by Slack.
+var slackSubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"me_message":       true,
+	"thread_broadcast": true,
+	"file_share":       true,
+}
+
+var slackUserMention = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// ReadSlack reads a Slack workspace export: users.json, channels.json and a
+// directory of daily message files per channel. Direct messages are not
+// imported.
+func ReadSlack(fsys fs.FS) (*Archive, error) {
+	var users []slackUser
+	if err := readJSON(fsys, "users.json", &users); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var channels []slackChannel
+	if err := readJSON(fsys, "channels.json", &channels); err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		archive.Users = append(archive.Users, User{ID: u.ID, Name: u.Name})
+		names[u.ID] = u.Name
+	}
+
+	for _, channel := range channels {
+		chat := Chat{
+			Title:     channel.Name,
+			Topic:     channel.Topic.Value,
+			CreatedAt: time.Unix(channel.Created, 0).UTC(),
+		}
+		if chat.Topic == "" {
+			chat.Topic = channel.Purpose.Value
+		}
+
+		days, err := fs.Glob(fsys, path.Join(channel.Name, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(days)
+
+		for _, day := range days {
+			var messages []slackMessage
+			if err := readJSON(fsys, day, &messages); err != nil {
+				return nil, err
+			}
+
+			for _, m := range messages {
+				if m.Type != "message" || !slackSubtypes[m.Subtype] {
+					continue
+				}
+
+				createdAt, err := parseSlackTS(m.TS)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", day, err)
+				}
+
+				message := Message{
+					User:      m.User,
+					Text:      replaceSlackMentions(m.Text, names),
+					CreatedAt: createdAt,
+				}
+				switch {
+				case m.Username != "":
+					message.AuthorName = m.Username
+				case m.BotProfile != nil:
+					message.AuthorName = m.BotProfile.Name
+				}
+
+				chat.Messages = append(chat.Messages, message)
+			}
+		}
+
+		archive.Chats = append(archive.Chats, chat)
+	}
+
+	return archive, nil
+}
+
+func readJSON(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+// parseSlackTS parses a message timestamp such as "1600000000.000100",
+// seconds and microseconds since the epoch.
+func parseSlackTS(ts string) (time.Time, error) {
+	secStr, usecStr, _ := strings.Cut(ts, ".")
+
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+	}
+
+	var usec int64
+	if usecStr != "" {
+		if usec, err = strconv.ParseInt(usecStr, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+
+	return time.Unix(sec, usec*int64(time.Microsecond)).UTC(), nil
+}
+
+// replaceSlackMentions turns "<@U123>" into "@name" so that mentions stay
+// readable.
+func replaceSlackMentions(text string, names map[string]string) string {
+	return slackUserMention.ReplaceAllStringFunc(text, func(match string) string {
+		id := slackUserMention.FindStringSubmatch(match)[1]
+		if name, ok := names[id]; ok {
+			return "@" + name
+		}
+		return match
+	})
+}
@@ -0,0 +1,256 @@
+// Package importer brings chat history over from other tools.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	chatRepo "github.com/AlGrushino/chat/internal/repository/chat"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultBatchSize = 500
+
+	maxTitleLength      = 200
+	maxTopicLength      = 250
+	maxTextLength       = 5000
+	maxAuthorNameLength = 80
+	unknownAuthor       = "unknown"
+)
+
+// Options.UserMap maps archive user IDs or names to usernames of existing
+// accounts. Without an entry, users are matched by name.
+type Options struct {
+	DryRun    bool
+	UserMap   map[string]string
+	BatchSize int
+}
+
+// Summary is what an import created, or would create in a dry run.
+type Summary struct {
+	DryRun          bool
+	Chats           int
+	Messages        int
+	SkippedChats    []string
+	SkippedMessages int
+	MappedUsers     map[string]string
+	UnmappedUsers   []string
+}
+
+func (s *Summary) Write(w io.Writer) error {
+	verb := "Imported"
+	if s.DryRun {
+		verb = "Would import"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d chats with %d messages\n", verb, s.Chats, s.Messages)
+	if len(s.SkippedChats) > 0 {
+		fmt.Fprintf(&b, "Skipped %d chats that already exist or have an invalid title: %s\n", len(s.SkippedChats), strings.Join(s.SkippedChats, ", "))
+	}
+	if s.SkippedMessages > 0 {
+		fmt.Fprintf(&b, "Skipped %d empty, undated or too long messages\n", s.SkippedMessages)
+	}
+
+	mapped := make([]string, 0, len(s.MappedUsers))
+	for from, to := range s.MappedUsers {
+		mapped = append(mapped, from+" -> "+to)
+	}
+	slices.Sort(mapped)
+	fmt.Fprintf(&b, "Mapped %d users to accounts: %s\n", len(mapped), strings.Join(mapped, ", "))
+
+	if len(s.UnmappedUsers) > 0 {
+		fmt.Fprintf(&b, "Kept %d users as author names only: %s\n", len(s.UnmappedUsers), strings.Join(s.UnmappedUsers, ", "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type Importer struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewImporter(log *logrus.Logger, repository *repository.Repository) *Importer {
+	return &Importer{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// Import creates the chats of archive as group chats with their messages,
+// keeping the original timestamps. Authors matched to an account are linked
+// to it; the others keep their name as AuthorName. Chats whose title is
+// already taken are skipped. Everything is written in one transaction.
+func (i *Importer) Import(ctx context.Context, archive *Archive, opts Options) (*Summary, error) {
+	summary := &Summary{DryRun: opts.DryRun, MappedUsers: map[string]string{}}
+
+	accounts, err := i.mapUsers(ctx, archive.Users, opts.UserMap, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(archive.Users))
+	for _, u := range archive.Users {
+		names[u.ID] = u.Name
+	}
+
+	var histories []chatRepo.History
+	seen := make(map[string]bool, len(archive.Chats))
+
+	for _, c := range archive.Chats {
+		title := strings.TrimSpace(c.Title)
+		if title == "" || len(title) > maxTitleLength || seen[strings.ToLower(title)] {
+			summary.SkippedChats = append(summary.SkippedChats, fmt.Sprintf("%q", c.Title))
+			continue
+		}
+		seen[strings.ToLower(title)] = true
+
+		exists, err := i.repository.Chat.ChatExists(ctx, title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check chat %q: %w", title, err)
+		}
+		if exists {
+			summary.SkippedChats = append(summary.SkippedChats, fmt.Sprintf("%q", title))
+			continue
+		}
+
+		history := chatRepo.History{
+			Chat: &models.Chat{
+				Title:     title,
+				Type:      models.ChatTypeGroup,
+				Topic:     truncate(c.Topic, maxTopicLength),
+				CreatedAt: c.CreatedAt,
+			},
+		}
+
+		for _, m := range c.Messages {
+			if m.Text == "" || len(m.Text) > maxTextLength || m.CreatedAt.IsZero() {
+				summary.SkippedMessages++
+				continue
+			}
+
+			message := &models.Message{Text: m.Text, CreatedAt: m.CreatedAt}
+			if account, ok := accounts[m.User]; ok {
+				message.AuthorID = &account.ID
+			} else {
+				name := truncate(firstNonEmpty(m.AuthorName, names[m.User], m.User, unknownAuthor), maxAuthorNameLength)
+				message.AuthorName = &name
+			}
+
+			history.Messages = append(history.Messages, message)
+		}
+
+		// Keep IDs in chronological order, which pagination relies on.
+		slices.SortStableFunc(history.Messages, func(a, b *models.Message) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+
+		if history.Chat.CreatedAt.IsZero() && len(history.Messages) > 0 {
+			history.Chat.CreatedAt = history.Messages[0].CreatedAt
+		}
+
+		histories = append(histories, history)
+		summary.Chats++
+		summary.Messages += len(history.Messages)
+	}
+
+	if opts.DryRun {
+		return summary, nil
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	start := time.Now()
+	if err := i.repository.Chat.Import(ctx, histories, batchSize); err != nil {
+		return nil, fmt.Errorf("failed to import: %w", err)
+	}
+
+	i.log.WithFields(logrus.Fields{
+		"chats":       summary.Chats,
+		"messages":    summary.Messages,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}).Info("Import completed")
+
+	return summary, nil
+}
+
+// mapUsers returns the accounts of archive users, keyed by archive user ID.
+func (i *Importer) mapUsers(ctx context.Context, users []User, userMap map[string]string, summary *Summary) (map[string]*models.User, error) {
+	targets := make(map[string]string, len(users))
+	usernames := make([]string, 0, len(users))
+
+	for _, u := range users {
+		target, ok := userMap[u.ID]
+		if !ok {
+			target, ok = userMap[u.Name]
+		}
+		if !ok {
+			target = u.Name
+		}
+		if target == "" {
+			continue
+		}
+
+		targets[u.ID] = target
+		usernames = append(usernames, target)
+	}
+
+	found, err := i.repository.User.GetByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up users: %w", err)
+	}
+
+	byName := make(map[string]*models.User, len(found))
+	for _, account := range found {
+		byName[strings.ToLower(account.Username)] = account
+	}
+
+	accounts := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		label := firstNonEmpty(u.Name, u.ID)
+		account, ok := byName[strings.ToLower(targets[u.ID])]
+		if !ok {
+			summary.UnmappedUsers = append(summary.UnmappedUsers, label)
+			continue
+		}
+
+		accounts[u.ID] = account
+		summary.MappedUsers[label] = account.Username
+	}
+
+	return accounts, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	// Cut at a rune boundary.
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	chatRepo "github.com/AlGrushino/chat/internal/repository/chat"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChats struct {
+	repository.Chat
+
+	existing  map[string]bool
+	imported  []chatRepo.History
+	batchSize int
+}
+
+func (f *fakeChats) ChatExists(ctx context.Context, title string) (bool, error) {
+	return f.existing[title], nil
+}
+
+func (f *fakeChats) Import(ctx context.Context, histories []chatRepo.History, batchSize int) error {
+	f.imported = histories
+	f.batchSize = batchSize
+	return nil
+}
+
+func TestReadSlack(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users.json": {Data: []byte(`[{"id": "U1", "name": "alice"}, {"id": "U2", "name": "bob"}]`)},
+		"channels.json": {Data: []byte(`[
+			{"name": "general", "created": 1600000000, "topic": {"value": ""}, "purpose": {"value": "Company-wide"}}
+		]`)},
+		"general/2020-09-14.json": {Data: []byte(`[
+			{"type": "message", "user": "U1", "text": "hi <@U2>", "ts": "1600000100.000200"},
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined", "ts": "1600000050.000000"},
+			{"type": "message", "subtype": "bot_message", "username": "deploy-bot", "text": "deployed", "ts": "1600000200.000000"}
+		]`)},
+	}
+
+	archive, err := ReadSlack(fsys)
+	require.NoError(t, err)
+
+	assert.Equal(t, []User{{ID: "U1", Name: "alice"}, {ID: "U2", Name: "bob"}}, archive.Users)
+	require.Len(t, archive.Chats, 1)
+
+	chat := archive.Chats[0]
+	assert.Equal(t, "general", chat.Title)
+	assert.Equal(t, "Company-wide", chat.Topic)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), chat.CreatedAt)
+
+	require.Len(t, chat.Messages, 2)
+	assert.Equal(t, Message{User: "U1", Text: "hi @bob", CreatedAt: time.Unix(1600000100, 200000).UTC()}, chat.Messages[0])
+	assert.Equal(t, "deploy-bot", chat.Messages[1].AuthorName)
+}
+
+func newArchive() *Archive {
+	day := time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)
+	return &Archive{
+		Users: []User{{ID: "u1", Name: "alice"}, {ID: "u2", Name: "Robert"}, {ID: "u3", Name: "carol"}},
+		Chats: []Chat{
+			{Title: "general", Messages: []Message{
+				{User: "u2", Text: "second", CreatedAt: day.Add(time.Hour)},
+				{User: "u1", Text: "first", CreatedAt: day},
+				{User: "u3", Text: "third", CreatedAt: day.Add(2 * time.Hour)},
+				{User: "u1", Text: "", CreatedAt: day},
+				{User: "u1", Text: "undated"},
+			}},
+			{Title: "random"},
+			{Title: "General"},
+		},
+	}
+}
+
+func newImporter(chats *fakeChats) *Importer {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return NewImporter(log, &repository.Repository{
+		Chat: chats,
+		User: testutil.NewUsers(&models.User{ID: 1, Username: "alice"}, &models.User{ID: 2, Username: "bob"}),
+	})
+}
+
+func TestImport(t *testing.T) {
+	chats := &fakeChats{existing: map[string]bool{"random": true}}
+
+	summary, err := newImporter(chats).Import(context.Background(), newArchive(), Options{
+		UserMap:   map[string]string{"u2": "bob"},
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, summary.Chats)
+	assert.Equal(t, 3, summary.Messages)
+	assert.Equal(t, 2, summary.SkippedMessages)
+	assert.Equal(t, []string{`"random"`, `"General"`}, summary.SkippedChats)
+	assert.Equal(t, map[string]string{"alice": "alice", "Robert": "bob"}, summary.MappedUsers)
+	assert.Equal(t, []string{"carol"}, summary.UnmappedUsers)
+
+	require.Len(t, chats.imported, 1)
+	assert.Equal(t, 2, chats.batchSize)
+
+	history := chats.imported[0]
+	day := time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, day, history.Chat.CreatedAt)
+
+	require.Len(t, history.Messages, 3)
+	assert.Equal(t, "first", history.Messages[0].Text)
+	assert.Equal(t, day, history.Messages[0].CreatedAt)
+	assert.Equal(t, 1, *history.Messages[0].AuthorID)
+	assert.Equal(t, 2, *history.Messages[1].AuthorID)
+	assert.Nil(t, history.Messages[2].AuthorID)
+	assert.Equal(t, "carol", *history.Messages[2].AuthorName)
+}
+
+func TestImport_DryRun(t *testing.T) {
+	chats := &fakeChats{}
+
+	summary, err := newImporter(chats).Import(context.Background(), newArchive(), Options{DryRun: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, summary.Chats)
+	assert.Nil(t, chats.imported)
+
+	var out bytes.Buffer
+	require.NoError(t, summary.Write(&out))
+	assert.Contains(t, out.String(), "Would import 2 chats with 3 messages")
+	assert.Contains(t, out.String(), "Kept 2 users as author names only: Robert, carol")
+}
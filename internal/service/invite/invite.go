@@ -0,0 +1,195 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	inviteRepository "github.com/AlGrushino/chat/internal/repository/invite"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxExpiresIn = 30 * 24 * time.Hour
+	MaxUses      = 10000
+)
+
+var (
+	ErrChatNotFound     = errors.New("chat does not exist")
+	ErrNotFound         = errors.New("invite does not exist")
+	ErrNotOwner         = errors.New("only the chat owner can manage invites")
+	ErrInvalidExpiresIn = fmt.Errorf("expires_in must be between 0 and %d seconds", int(MaxExpiresIn.Seconds()))
+	ErrInvalidMaxUses   = fmt.Errorf("invite max uses must be between 0 and %d", MaxUses)
+	ErrExpired          = errors.New("invite has expired, was revoked or is used up")
+)
+
+type InviteService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewInviteService(log *logrus.Logger, repository *repository.Repository) *InviteService {
+	return &InviteService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// CreateInvite creates an invite to a group chat and returns it with its
+// token. Only a hash of the token is stored. expiresIn is in seconds; zero
+// expiresIn or maxUses means no limit.
+func (s *InviteService) CreateInvite(ctx context.Context, chatID, expiresIn, maxUses int) (*models.ChatInvite, string, error) {
+	if expiresIn < 0 || expiresIn > int(MaxExpiresIn/time.Second) {
+		return nil, "", ErrInvalidExpiresIn
+	}
+
+	if maxUses < 0 || maxUses > MaxUses {
+		return nil, "", ErrInvalidMaxUses
+	}
+
+	caller, err := s.checkOwner(ctx, chatID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invite := models.ChatInvite{
+		ChatID:    chatID,
+		CreatorID: caller.ID,
+		TokenHash: auth.HashToken(token),
+	}
+
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if maxUses > 0 {
+		invite.MaxUses = &maxUses
+	}
+
+	if err := s.repository.Invite.Create(ctx, &invite); err != nil {
+		s.log.WithError(err).Error("Failed to create invite in database")
+		return nil, "", fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	s.log.Infof("Invite created successfully (ID: %d, chat: %d)", invite.ID, chatID)
+	return &invite, token, nil
+}
+
+// ListInvites returns all invites of a chat, revoked and used up ones
+// included, newest first.
+func (s *InviteService) ListInvites(ctx context.Context, chatID int) ([]*models.ChatInvite, error) {
+	if _, err := s.checkOwner(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.repository.Invite.GetByChatID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+// RevokeInvite stops an invite from working. People who already joined
+// through it stay in the chat.
+func (s *InviteService) RevokeInvite(ctx context.Context, chatID, id int) error {
+	if _, err := s.checkOwner(ctx, chatID); err != nil {
+		return err
+	}
+
+	revoked, err := s.repository.Invite.Revoke(ctx, chatID, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+
+	if !revoked {
+		return ErrNotFound
+	}
+
+	s.log.Infof("Invite revoked (ID: %d, chat: %d)", id, chatID)
+	return nil
+}
+
+// AcceptInvite makes the caller a member of the chat the token invites to.
+// The boolean result reports whether they joined just now; accepting an
+// invite to a chat one is in already does not use it up. Invites do not let
+// banned users back in.
+func (s *InviteService) AcceptInvite(ctx context.Context, token string) (*models.Chat, bool, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, false, auth.ErrUnauthenticated
+	}
+
+	tokenHash := auth.HashToken(token)
+
+	invite, err := s.repository.Invite.GetByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrNotFound
+		}
+		return nil, false, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	if err := moderation.Check(ctx, s.repository.Moderation, invite.ChatID, caller.ID, models.RestrictionBan); err != nil {
+		return nil, false, err
+	}
+
+	invite, joined, err := s.repository.Invite.Accept(ctx, tokenHash, caller.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, false, ErrNotFound
+		case errors.Is(err, inviteRepository.ErrUnusable):
+			return nil, false, ErrExpired
+		default:
+			return nil, false, fmt.Errorf("failed to accept invite: %w", err)
+		}
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, invite.ChatID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get chat with id: %d", invite.ChatID)
+	}
+
+	if joined {
+		s.log.Infof("User joined chat by invite (Chat ID: %d, User ID: %d, Invite ID: %d)", chat.ID, caller.ID, invite.ID)
+	}
+	return chat, joined, nil
+}
+
+func (s *InviteService) checkOwner(ctx context.Context, chatID int) (*models.User, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", chatID)
+	}
+
+	if chat.IsDirect() {
+		return nil, ErrChatNotFound
+	}
+
+	if chat.OwnerID == nil || *chat.OwnerID != caller.ID {
+		return nil, ErrNotOwner
+	}
+
+	return caller, nil
+}
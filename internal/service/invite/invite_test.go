@@ -0,0 +1,152 @@
+package invite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	inviteRepository "github.com/AlGrushino/chat/internal/repository/invite"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeInvites accepts invites the way the repository does, without locking.
+type fakeInvites struct {
+	repository.Invite
+
+	invites map[string]*models.ChatInvite
+	members map[int]bool
+}
+
+func (f *fakeInvites) Create(ctx context.Context, invite *models.ChatInvite) error {
+	invite.ID = len(f.invites) + 1
+	f.invites[invite.TokenHash] = invite
+	return nil
+}
+
+func (f *fakeInvites) GetByTokenHash(ctx context.Context, tokenHash string) (*models.ChatInvite, error) {
+	invite, ok := f.invites[tokenHash]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return invite, nil
+}
+
+func (f *fakeInvites) Accept(ctx context.Context, tokenHash string, userID int) (*models.ChatInvite, bool, error) {
+	invite, ok := f.invites[tokenHash]
+	if !ok {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	if !invite.Usable(time.Now()) {
+		return nil, false, inviteRepository.ErrUnusable
+	}
+	if f.members[userID] {
+		return invite, false, nil
+	}
+	f.members[userID] = true
+	invite.Uses++
+	return invite, true, nil
+}
+
+// fakeBans bans the users in it from every chat.
+type fakeBans struct {
+	repository.Moderation
+
+	banned map[int]bool
+}
+
+func (f *fakeBans) GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error) {
+	if !f.banned[userID] {
+		return nil, nil
+	}
+	return []*models.ChatRestriction{{ChatID: chatID, UserID: userID, Kind: models.RestrictionBan}}, nil
+}
+
+func newService() (*InviteService, *fakeInvites) {
+	invites := &fakeInvites{invites: map[string]*models.ChatInvite{}, members: map[int]bool{}}
+	return NewInviteService(logrus.New(), &repository.Repository{
+		Chat:       testutil.NewChats(testutil.PrivateChat("contractors")),
+		Invite:     invites,
+		Moderation: &fakeBans{banned: map[int]bool{13: true}},
+	}), invites
+}
+
+func TestCreateInvite_HashesToken(t *testing.T) {
+	s, invites := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	invite, token, err := s.CreateInvite(ctx, 1, 3600, 5)
+	require.NoError(t, err)
+
+	assert.Len(t, token, 64)
+	assert.NotContains(t, invites.invites, token)
+	assert.Contains(t, invites.invites, auth.HashToken(token))
+	assert.Equal(t, 5, *invite.MaxUses)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *invite.ExpiresAt, time.Minute)
+}
+
+func TestCreateInvite_Validation(t *testing.T) {
+	s, _ := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	_, _, err := s.CreateInvite(ctx, 1, int(MaxExpiresIn/time.Second)+1, 0)
+	assert.ErrorIs(t, err, ErrInvalidExpiresIn)
+
+	_, _, err = s.CreateInvite(ctx, 1, 0, -1)
+	assert.ErrorIs(t, err, ErrInvalidMaxUses)
+
+	_, _, err = s.CreateInvite(ctx, 2, 0, 0)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	_, _, err = s.CreateInvite(auth.WithUser(context.Background(), testutil.Stranger), 1, 0, 0)
+	assert.ErrorIs(t, err, ErrNotOwner)
+}
+
+func TestAcceptInvite_MaxUses(t *testing.T) {
+	s, _ := newService()
+
+	_, token, err := s.CreateInvite(auth.WithUser(context.Background(), testutil.Owner), 1, 0, 2)
+	require.NoError(t, err)
+
+	bob := auth.WithUser(context.Background(), testutil.Stranger)
+	chat, joined, err := s.AcceptInvite(bob, token)
+	require.NoError(t, err)
+	assert.True(t, joined)
+	assert.Equal(t, 1, chat.ID)
+
+	// Accepting again does not use the invite up any further.
+	_, joined, err = s.AcceptInvite(bob, token)
+	require.NoError(t, err)
+	assert.False(t, joined)
+
+	carol := auth.WithUser(context.Background(), &models.User{ID: 9, Username: "carol"})
+	_, joined, err = s.AcceptInvite(carol, token)
+	require.NoError(t, err)
+	assert.True(t, joined)
+
+	dave := auth.WithUser(context.Background(), &models.User{ID: 10, Username: "dave"})
+	_, _, err = s.AcceptInvite(dave, token)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	_, _, err = s.AcceptInvite(dave, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAcceptInvite_Banned(t *testing.T) {
+	s, invites := newService()
+
+	_, token, err := s.CreateInvite(auth.WithUser(context.Background(), testutil.Owner), 1, 0, 0)
+	require.NoError(t, err)
+
+	mallory := auth.WithUser(context.Background(), &models.User{ID: 13, Username: "mallory"})
+	_, _, err = s.AcceptInvite(mallory, token)
+	assert.ErrorIs(t, err, moderation.ErrBanned)
+	assert.Empty(t, invites.members)
+}
@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchFunc loads the values of a batch of keys. Keys missing from the
+// result resolve to the zero value.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader batches and caches lookups by key for the lifetime of one request,
+// in the spirit of DataLoader. Loads that arrive within the wait window share
+// one fetch. Keys announced with Expect join the next fetch, so resolving a
+// field on the first item of a list loads it for the whole list.
+type Loader[K comparable, V any] struct {
+	fetch FetchFunc[K, V]
+	wait  time.Duration
+
+	mu       sync.Mutex
+	cache    map[K]*batch[K, V]
+	current  *batch[K, V]
+	expected []K
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func New[K comparable, V any](fetch FetchFunc[K, V], wait time.Duration) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch: fetch,
+		wait:  wait,
+		cache: make(map[K]*batch[K, V]),
+	}
+}
+
+// Expect announces keys that are likely to be loaded soon.
+func (l *Loader[K, V]) Expect(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := l.cache[key]; !ok {
+			l.expected = append(l.expected, key)
+		}
+	}
+}
+
+// Load returns the value of key, fetching it together with other pending
+// keys if it is not cached yet.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.cache[key]
+	if !ok {
+		if l.current == nil {
+			l.current = &batch[K, V]{done: make(chan struct{})}
+			go l.dispatch(ctx, l.current)
+		}
+		b = l.current
+		b.keys = append(b.keys, key)
+		l.cache[key] = b
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.values[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	if l.wait > 0 {
+		timer := time.NewTimer(l.wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	l.mu.Lock()
+	l.current = nil
+	for _, key := range l.expected {
+		if _, ok := l.cache[key]; !ok {
+			b.keys = append(b.keys, key)
+			l.cache[key] = b
+		}
+	}
+	l.expected = nil
+	l.mu.Unlock()
+
+	b.values, b.err = l.fetch(ctx, b.keys)
+	close(b.done)
+}
@@ -0,0 +1,90 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]string, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, keys)
+	r.mu.Unlock()
+
+	values := make(map[int]string, len(keys))
+	for _, key := range keys {
+		if key > 0 {
+			values[key] = string(rune('a' + key - 1))
+		}
+	}
+	return values, nil
+}
+
+func TestLoader_BatchesConcurrentLoads(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch, 10*time.Millisecond)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Go(func() {
+			value, err := l.Load(ctx, i%3+1)
+			assert.NoError(t, err)
+			results[i] = value
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"a", "b", "c", "a"}, results)
+	require.Len(t, r.batches, 1)
+	assert.ElementsMatch(t, []int{1, 2, 3}, r.batches[0])
+
+	value, err := l.Load(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+	assert.Len(t, r.batches, 1, "cached keys are not fetched again")
+}
+
+func TestLoader_Expect(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch, 0)
+	ctx := context.Background()
+
+	l.Expect(1, 2, 3, -1)
+
+	value, err := l.Load(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+
+	missing, err := l.Load(ctx, -1)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	value, err = l.Load(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+
+	require.Len(t, r.batches, 1)
+	assert.ElementsMatch(t, []int{1, 2, 3, -1}, r.batches[0])
+}
+
+func TestLoader_Error(t *testing.T) {
+	failure := errors.New("database is down")
+	l := New(func(ctx context.Context, keys []int) (map[int]string, error) {
+		return nil, failure
+	}, 0)
+
+	_, err := l.Load(context.Background(), 1)
+	assert.ErrorIs(t, err, failure)
+}
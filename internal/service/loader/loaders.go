@@ -0,0 +1,136 @@
+package loader
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultWait is how long a loader collects keys before fetching them.
+const DefaultWait = time.Millisecond
+
+// MessagePage selects the newest Limit messages of a chat older than Before.
+type MessagePage struct {
+	ChatID int
+	Before int
+	Limit  int
+}
+
+// MemberPage selects the first Limit members of a chat after user After.
+type MemberPage struct {
+	ChatID int
+	After  int
+	Limit  int
+}
+
+// Loaders are the batched lookups of one request. They read the repositories
+// directly, so callers must only ask for chats the viewer may access.
+type Loaders struct {
+	Users          *Loader[int, *models.User]
+	Messages       *Loader[MessagePage, []*models.Message]
+	Members        *Loader[MemberPage, []*models.ChatMember]
+	UnreadMentions *Loader[int, int]
+}
+
+type LoaderService struct {
+	repository *repository.Repository
+	wait       time.Duration
+	log        *logrus.Logger
+}
+
+func NewLoaderService(log *logrus.Logger, repository *repository.Repository) *LoaderService {
+	return &LoaderService{
+		repository: repository,
+		wait:       DefaultWait,
+		log:        log,
+	}
+}
+
+// New returns fresh loaders for a request made by the user in ctx, if any.
+func (s *LoaderService) New(ctx context.Context) *Loaders {
+	viewer := auth.UserFromContext(ctx)
+
+	return &Loaders{
+		Users:    New(s.users, s.wait),
+		Messages: New(s.messages, s.wait),
+		Members:  New(s.members, s.wait),
+		UnreadMentions: New(func(ctx context.Context, chatIDs []int) (map[int]int, error) {
+			if viewer == nil {
+				return nil, nil
+			}
+			return s.repository.Mention.CountUnreadByChatIDs(ctx, viewer.ID, chatIDs)
+		}, s.wait),
+	}
+}
+
+func (s *LoaderService) users(ctx context.Context, ids []int) (map[int]*models.User, error) {
+	users, err := s.repository.User.GetByIDs(ctx, ids)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to load users")
+		return nil, err
+	}
+
+	byID := make(map[int]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+// messages runs one query per distinct cursor and page size; a list of chats
+// asking for the same page of each is a single query.
+func (s *LoaderService) messages(ctx context.Context, pages []MessagePage) (map[MessagePage][]*models.Message, error) {
+	result := make(map[MessagePage][]*models.Message, len(pages))
+
+	for group, chatIDs := range groupPages(pages, func(p MessagePage) (MessagePage, int) {
+		return MessagePage{Before: p.Before, Limit: p.Limit}, p.ChatID
+	}) {
+		messages, err := s.repository.Message.GetLatestByChatIDs(ctx, chatIDs, group.Before, group.Limit)
+		if err != nil {
+			s.log.WithError(err).Error("Failed to load messages")
+			return nil, err
+		}
+
+		for _, message := range messages {
+			page := MessagePage{ChatID: message.ChatID, Before: group.Before, Limit: group.Limit}
+			result[page] = append(result[page], message)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *LoaderService) members(ctx context.Context, pages []MemberPage) (map[MemberPage][]*models.ChatMember, error) {
+	result := make(map[MemberPage][]*models.ChatMember, len(pages))
+
+	for group, chatIDs := range groupPages(pages, func(p MemberPage) (MemberPage, int) {
+		return MemberPage{After: p.After, Limit: p.Limit}, p.ChatID
+	}) {
+		members, err := s.repository.Member.GetByChatIDs(ctx, chatIDs, group.After, group.Limit)
+		if err != nil {
+			s.log.WithError(err).Error("Failed to load members")
+			return nil, err
+		}
+
+		for _, member := range members {
+			page := MemberPage{ChatID: member.ChatID, After: group.After, Limit: group.Limit}
+			result[page] = append(result[page], member)
+		}
+	}
+
+	return result, nil
+}
+
+// groupPages splits pages into groups sharing everything but the chat ID.
+func groupPages[P comparable](pages []P, split func(P) (P, int)) map[P][]int {
+	groups := make(map[P][]int)
+	for _, page := range pages {
+		group, chatID := split(page)
+		groups[group] = append(groups[group], chatID)
+	}
+	return groups
+}
@@ -0,0 +1,67 @@
+package mention
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+)
+
+type MentionService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewMentionService(log *logrus.Logger, repository *repository.Repository) *MentionService {
+	return &MentionService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// GetMentions returns the caller's mention inbox, newest first.
+func (s *MentionService) GetMentions(ctx context.Context, unreadOnly bool, limit, offset int) ([]*models.Mention, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		return nil, fmt.Errorf("limit is too big: %d", limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	mentions, err := s.repository.Mention.GetByUserID(ctx, user.ID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mentions: %w", err)
+	}
+
+	return mentions, nil
+}
+
+// MarkRead marks the caller's mentions up to upToID as read, or all of them
+// when upToID is zero.
+func (s *MentionService) MarkRead(ctx context.Context, upToID int) (int64, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return 0, auth.ErrUnauthenticated
+	}
+
+	count, err := s.repository.Mention.MarkRead(ctx, user.ID, upToID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark mentions as read: %w", err)
+	}
+
+	s.log.Infof("Marked %d mentions as read for user %d", count, user.ID)
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository/models"
+)
+
+// mentionPattern matches "@username" that is not glued to a preceding word,
+// so e-mail addresses like "bob@example" are not treated as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])(@([A-Za-z0-9_]{3,32}))\b`)
+
+type mentionToken struct {
+	Username string
+	Offset   int
+	Length   int
+}
+
+// parseMentions finds "@username" tokens in text. Offsets and lengths are in
+// Unicode code points so clients can highlight them without knowing UTF-8.
+func parseMentions(text string) []mentionToken {
+	matches := mentionPattern.FindAllStringSubmatchIndex(text, -1)
+	tokens := make([]mentionToken, 0, len(matches))
+
+	for _, m := range matches {
+		start, end := m[2], m[3]
+		tokens = append(tokens, mentionToken{
+			Username: text[m[4]:m[5]],
+			Offset:   utf8.RuneCountInString(text[:start]),
+			Length:   utf8.RuneCountInString(text[start:end]),
+		})
+	}
+
+	return tokens
+}
+
+// resolveMentions turns parsed tokens into mention records for existing users
+// and returns the mentioned users keyed by id. Only users who can access the
+// chat can be mentioned, so private and direct chats do not leak messages to
+// outsiders through their mention inbox.
+func (s *MessageService) resolveMentions(ctx context.Context, chat *models.Chat, text string) ([]models.Mention, map[int]*models.User, error) {
+	tokens := parseMentions(text)
+	if len(tokens) == 0 {
+		return nil, nil, nil
+	}
+
+	usernames := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		usernames = append(usernames, token.Username)
+	}
+
+	users, err := s.repository.User.GetByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+
+	byName := make(map[string]*models.User, len(users))
+	for _, user := range users {
+		allowed, err := auth.CanAccess(auth.WithUser(ctx, user), s.repository.Member, chat)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check chat access: %w", err)
+		}
+		if !allowed {
+			continue
+		}
+		byName[strings.ToLower(user.Username)] = user
+	}
+
+	mentions := make([]models.Mention, 0, len(tokens))
+	mentioned := make(map[int]*models.User, len(byName))
+	for _, token := range tokens {
+		user, ok := byName[strings.ToLower(token.Username)]
+		if !ok {
+			continue
+		}
+
+		mentions = append(mentions, models.Mention{
+			UserID: user.ID,
+			Offset: token.Offset,
+			Length: token.Length,
+		})
+		mentioned[user.ID] = user
+	}
+
+	return mentions, mentioned, nil
+}
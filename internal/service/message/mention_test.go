@@ -0,0 +1,105 @@
+package message
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want []mentionToken
+	}{
+		{
+			name: "single",
+			text: "hi @alice",
+			want: []mentionToken{{Username: "alice", Offset: 3, Length: 6}},
+		},
+		{
+			name: "several with punctuation",
+			text: "@bob, @carol_1: ping",
+			want: []mentionToken{
+				{Username: "bob", Offset: 0, Length: 4},
+				{Username: "carol_1", Offset: 6, Length: 8},
+			},
+		},
+		{
+			name: "offsets in code points",
+			text: "привет @ivan",
+			want: []mentionToken{{Username: "ivan", Offset: 7, Length: 5}},
+		},
+		{
+			name: "email is not a mention",
+			text: "write to bob@example.com",
+			want: []mentionToken{},
+		},
+		{
+			name: "too short",
+			text: "@al is not a user",
+			want: []mentionToken{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, parseMentions(c.text))
+		})
+	}
+}
+
+func TestResolveMentions_Access(t *testing.T) {
+	alice, bob := testutil.Owner, testutil.Stranger
+	carol := &models.User{ID: 9, Username: "carol"}
+
+	s := &MessageService{
+		repository: &repository.Repository{
+			User:   testutil.NewUsers(alice, bob, carol),
+			Member: testutil.NewMembers(bob.ID),
+		},
+		log: logrus.New(),
+	}
+
+	cases := []struct {
+		name string
+		chat *models.Chat
+		want []int
+	}{
+		{
+			name: "public chat",
+			chat: &models.Chat{ID: 1, Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic, OwnerID: &alice.ID},
+			want: []int{alice.ID, bob.ID, carol.ID},
+		},
+		{
+			name: "private chat",
+			chat: &models.Chat{ID: 1, Type: models.ChatTypeGroup, Visibility: models.VisibilityPrivate, OwnerID: &alice.ID},
+			want: []int{alice.ID, bob.ID},
+		},
+		{
+			name: "direct chat",
+			chat: &models.Chat{ID: 1, Type: models.ChatTypeDirect},
+			want: []int{bob.ID},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mentions, mentioned, err := s.resolveMentions(context.Background(), c.chat, "@alice @bob @carol")
+			require.NoError(t, err)
+
+			ids := make([]int, 0, len(mentions))
+			for _, mention := range mentions {
+				ids = append(ids, mention.UserID)
+			}
+			assert.Equal(t, c.want, ids)
+			assert.Len(t, mentioned, len(c.want))
+		})
+	}
+}
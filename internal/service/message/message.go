@@ -4,68 +4,333 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/events"
 	"github.com/AlGrushino/chat/internal/repository"
+	messageRepository "github.com/AlGrushino/chat/internal/repository/message"
 	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/command"
+	"github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+const (
+	MaxAttachments = 10
+
+	// SubscribeBuffer is how many messages a Subscribe receiver may lag behind.
+	SubscribeBuffer = 64
+
+	// MaxExpiresIn is the longest lifetime of a self-destructing message.
+	MaxExpiresIn = 7 * 24 * time.Hour
+)
+
+var (
+	ErrEmptyText             = errors.New("text of message is empty")
+	ErrTextTooLong           = errors.New("text of message is too long")
+	ErrTooManyAttachments    = fmt.Errorf("too many attachments, at most %d are allowed", MaxAttachments)
+	ErrInvalidLimit          = errors.New("limit is too big")
+	ErrAttachmentUnavailable = errors.New("attachment does not exist or is already used")
+	ErrCommandAttachments    = errors.New("attachments cannot be sent with a command")
+	ErrInvalidClientMsgID    = errors.New("client_msg_id must be a UUID")
+	ErrClientMsgIDConflict   = errors.New("client_msg_id is already used by another message in this chat")
+	ErrInvalidExpiresIn      = fmt.Errorf("expires_in must be between 0 and %d seconds", int(MaxExpiresIn.Seconds()))
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// SlowModeError is returned by AddMessage when the author has to wait before
+// posting again to a chat in slow mode.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("chat is in slow mode, retry after %s", e.RetryAfter)
+}
+
 type MessageService struct {
 	repository *repository.Repository
+	events     *events.Bus
+	commands   *command.Router
+	limiter    ratelimit.Limiter
 	log        *logrus.Logger
 }
 
-func NewMessageService(log *logrus.Logger, repository *repository.Repository) *MessageService {
+func NewMessageService(log *logrus.Logger, repository *repository.Repository, bus *events.Bus, limiter ratelimit.Limiter) *MessageService {
 	return &MessageService{
 		repository: repository,
+		events:     bus,
+		commands:   command.NewRouter(log, repository),
+		limiter:    limiter,
 		log:        log,
 	}
 }
 
-func (s *MessageService) AddMessage(ctx context.Context, id int, text string) (string, error) {
-	_, err := s.repository.Chat.GetByID(ctx, id)
+// NewMessage is the input of AddMessage. AuthorName is only used when the
+// context carries no user, e.g. for messages posted by incoming webhooks.
+// ClientMsgID is an optional client-generated UUID; sending it again returns
+// the message created the first time. A message with ExpiresIn, in seconds,
+// is hidden and deleted once it elapses.
+type NewMessage struct {
+	Text          string
+	AttachmentIDs []int
+	AuthorName    string
+	ClientMsgID   string
+	ExpiresIn     int
+}
+
+func (s *MessageService) AddMessage(ctx context.Context, id int, msg NewMessage) (*models.Message, error) {
+	chat, err := s.checkChat(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("chat does not exist")
-		}
-		return "", fmt.Errorf("failed to get chat with id: %d", id)
+		return nil, err
 	}
 
+	text := msg.Text
 	if text == "" {
-		return "", errors.New("text of message is empty")
+		return nil, ErrEmptyText
 	}
 
 	if len(text) > 5000 {
-		return "", errors.New("text of message is too long")
+		return nil, ErrTextTooLong
+	}
+
+	if len(msg.AttachmentIDs) > MaxAttachments {
+		return nil, fmt.Errorf("%w: %d", ErrTooManyAttachments, len(msg.AttachmentIDs))
+	}
+
+	if msg.ExpiresIn < 0 || msg.ExpiresIn > int(MaxExpiresIn/time.Second) {
+		return nil, ErrInvalidExpiresIn
+	}
+
+	var clientMsgID *string
+	if msg.ClientMsgID != "" {
+		normalized, err := ParseClientMsgID(msg.ClientMsgID)
+		if err != nil {
+			return nil, err
+		}
+		clientMsgID = &normalized
+	}
+
+	author := auth.UserFromContext(ctx)
+	var bot *models.User
+
+	if author != nil {
+		err := moderation.Check(ctx, s.repository.Moderation, id, author.ID, models.RestrictionBan, models.RestrictionMute)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Slash commands are only available to signed-in users; anonymous and
+	// incoming webhook messages are always stored as they are.
+	if author != nil {
+		if name, args, ok := command.Parse(text); ok {
+			if len(msg.AttachmentIDs) > 0 {
+				return nil, ErrCommandAttachments
+			}
+
+			reply, err := s.commands.Run(ctx, chat, author, name, args)
+			if err != nil {
+				return nil, err
+			}
+
+			if reply.Bot != nil {
+				author, bot = reply.Bot, reply.Bot
+			}
+
+			if reply.Ephemeral {
+				return &models.Message{
+					ChatID:    id,
+					AuthorID:  &author.ID,
+					Author:    reply.Bot,
+					Text:      reply.Text,
+					CreatedAt: time.Now(),
+					Ephemeral: true,
+				}, nil
+			}
+
+			text = reply.Text
+			if len(text) > 5000 {
+				return nil, ErrTextTooLong
+			}
+		} else {
+			text = command.Unescape(text)
+		}
+	}
+
+	if clientMsgID != nil {
+		existing, err := s.findByClientMsgID(ctx, id, *clientMsgID, author, text)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
+	if author != nil && bot == nil {
+		if err := s.checkSlowMode(ctx, chat, author); err != nil {
+			return nil, err
+		}
 	}
 
 	message := models.Message{
-		ChatID:    id,
-		Text:      text,
-		CreatedAt: time.Now(),
+		ChatID:      id,
+		ClientMsgID: clientMsgID,
+		Text:        text,
+		CreatedAt:   time.Now(),
+	}
+
+	if msg.ExpiresIn > 0 {
+		expiresAt := message.CreatedAt.Add(time.Duration(msg.ExpiresIn) * time.Second)
+		message.ExpiresAt = &expiresAt
+	}
+
+	if author != nil {
+		message.AuthorID = &author.ID
+	} else if msg.AuthorName != "" {
+		message.AuthorName = &msg.AuthorName
+	}
+
+	mentions, mentioned, err := s.resolveMentions(ctx, chat, text)
+	if err != nil {
+		return nil, err
 	}
+	message.Mentions = mentions
 
-	err = s.repository.Message.Create(ctx, &message)
+	attachmentIDs := uniqueIDs(msg.AttachmentIDs)
+	if len(attachmentIDs) > 0 {
+		err = s.repository.Message.CreateWithAttachments(ctx, &message, attachmentIDs)
+	} else {
+		err = s.repository.Message.Create(ctx, &message)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to add message: %w", err)
+		if errors.Is(err, messageRepository.ErrAttachmentUnavailable) {
+			return nil, ErrAttachmentUnavailable
+		}
+		if errors.Is(err, messageRepository.ErrDuplicateClientMsgID) {
+			// A concurrent retry stored the message first.
+			existing, findErr := s.findByClientMsgID(ctx, id, *clientMsgID, author, text)
+			if findErr != nil || existing != nil {
+				return existing, findErr
+			}
+			return nil, ErrClientMsgIDConflict
+		}
+		return nil, fmt.Errorf("failed to add message: %w", err)
 	}
 
-	return text, nil
+	// Users are attached only after saving so GORM does not try to upsert them.
+	for i := range message.Mentions {
+		message.Mentions[i].User = mentioned[message.Mentions[i].UserID]
+	}
+	message.Author = bot
+
+	s.events.Publish(ctx, events.Event{Type: events.MessageCreated, Chat: chat, Message: &message})
+
+	return &message, nil
 }
 
 func (s *MessageService) GetMessages(ctx context.Context, id, limit int) ([]string, error) {
+	messages, err := s.ListMessages(ctx, id, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messageList := make([]string, 0, len(messages))
+	for _, message := range messages {
+		messageList = append(messageList, message.Text)
+	}
+
+	return messageList, nil
+}
+
+// ListMessages returns the latest limit messages of a chat, oldest first.
+func (s *MessageService) ListMessages(ctx context.Context, id, limit int) ([]*models.Message, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
 	if limit > 100 {
-		return nil, fmt.Errorf("limit is too big: %d", limit)
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLimit, limit)
+	}
+
+	if _, err := s.checkChat(ctx, id); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repository.Message.GetByChatID(ctx, id, limit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+
+	return messages, nil
+}
+
+// Subscribe streams messages created in a chat from now on. The channel is
+// closed when ctx is done or the chat is deleted. Messages are dropped while
+// the receiver falls behind by more than SubscribeBuffer.
+func (s *MessageService) Subscribe(ctx context.Context, id int) (<-chan *models.Message, error) {
+	return s.subscribe(ctx, id, events.MessageCreated)
+}
+
+// SubscribeExpired is like Subscribe but streams self-destructing messages
+// of the chat as they are deleted, so that clients can remove them from view.
+// Only IDs and chat IDs are meaningful.
+func (s *MessageService) SubscribeExpired(ctx context.Context, id int) (<-chan *models.Message, error) {
+	return s.subscribe(ctx, id, events.MessageExpired)
+}
+
+func (s *MessageService) subscribe(ctx context.Context, id int, eventType string) (<-chan *models.Message, error) {
+	if _, err := s.checkChat(ctx, id); err != nil {
+		return nil, err
 	}
 
-	_, err := s.repository.Chat.GetByID(ctx, id)
+	stream, unsubscribe := s.events.Subscribe(SubscribeBuffer)
+	messages := make(chan *models.Message, SubscribeBuffer)
+
+	go func() {
+		defer close(messages)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-stream:
+				if !ok {
+					return
+				}
+				if event.Chat == nil || event.Chat.ID != id {
+					continue
+				}
+
+				switch event.Type {
+				case events.ChatDeleted:
+					return
+				case eventType:
+					select {
+					case messages <- event.Message:
+					default:
+						s.log.Warnf("Subscriber of chat %d is too slow, dropping message %d", id, event.Message.ID)
+					}
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
+
+// checkChat makes sure the chat exists and the caller may see it. Direct
+// chats are reported as missing to non-participants.
+func (s *MessageService) checkChat(ctx context.Context, id int) (*models.Chat, error) {
+	chat, err := s.repository.Chat.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("chat does not exist")
@@ -73,17 +338,79 @@ func (s *MessageService) GetMessages(ctx context.Context, id, limit int) ([]stri
 		return nil, fmt.Errorf("failed to get chat with id: %d", id)
 	}
 
-	messages, err := s.repository.Message.GetByChatID(ctx, id, limit, 0)
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
 	}
 
-	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	if !allowed {
+		return nil, errors.New("chat does not exist")
+	}
 
-	messageList := make([]string, 0, len(messages))
-	for _, message := range messages {
-		messageList = append(messageList, message.Text)
+	return chat, nil
+}
+
+// findByClientMsgID returns the message already stored under clientMsgID, if
+// any. A retry must come from the same author with the same text; anything
+// else is a conflicting reuse of the id.
+func (s *MessageService) findByClientMsgID(ctx context.Context, chatID int, clientMsgID string, author *models.User, text string) (*models.Message, error) {
+	existing, err := s.repository.Message.GetByClientMsgID(ctx, chatID, clientMsgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get message by client id: %w", err)
 	}
 
-	return messageList, nil
+	sameAuthor := (author == nil && existing.AuthorID == nil) ||
+		(author != nil && existing.AuthorID != nil && *existing.AuthorID == author.ID)
+	if !sameAuthor || existing.Text != text {
+		return nil, ErrClientMsgIDConflict
+	}
+
+	return existing, nil
+}
+
+// checkSlowMode takes the author's turn in a chat in slow mode. The chat
+// owner is exempt.
+func (s *MessageService) checkSlowMode(ctx context.Context, chat *models.Chat, author *models.User) error {
+	if chat.SlowModeSeconds <= 0 || (chat.OwnerID != nil && *chat.OwnerID == author.ID) {
+		return nil
+	}
+
+	interval := time.Duration(chat.SlowModeSeconds) * time.Second
+	key := fmt.Sprintf("slowmode|chat:%d|user:%d", chat.ID, author.ID)
+
+	result, err := s.limiter.Allow(ctx, key, ratelimit.Limit{Requests: 1, Per: interval})
+	if err != nil {
+		s.log.WithError(err).Error("Failed to check slow mode")
+		return nil
+	}
+
+	if !result.Allowed {
+		return &SlowModeError{RetryAfter: result.RetryAfter}
+	}
+
+	return nil
+}
+
+// ParseClientMsgID checks that id is a UUID and returns it in lower case.
+func ParseClientMsgID(id string) (string, error) {
+	normalized := strings.ToLower(id)
+	if !uuidPattern.MatchString(normalized) {
+		return "", ErrInvalidClientMsgID
+	}
+	return normalized, nil
+}
+
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
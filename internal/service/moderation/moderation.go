@@ -0,0 +1,317 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxExpiresIn is the longest timed ban or mute; longer ones are
+	// meant to be permanent.
+	MaxExpiresIn = 365 * 24 * time.Hour
+
+	// KickBan is how long a kicked user is kept out. Without it a kicked
+	// user could go on posting to a public chat, which needs no membership.
+	KickBan = 10 * time.Minute
+
+	MaxReasonLength = 500
+	MaxLogLimit     = 100
+)
+
+var (
+	ErrChatNotFound     = errors.New("chat does not exist")
+	ErrUserNotFound     = errors.New("user does not exist")
+	ErrNotOwner         = errors.New("only the chat owner can moderate members")
+	ErrOwnerTarget      = errors.New("the chat owner cannot be kicked, banned or muted")
+	ErrNotMember        = errors.New("user is not a member of the chat")
+	ErrNotBanned        = errors.New("user is not banned from the chat")
+	ErrNotMuted         = errors.New("user is not muted in the chat")
+	ErrInvalidReason    = fmt.Errorf("reason must be at most %d characters", MaxReasonLength)
+	ErrInvalidExpiresIn = fmt.Errorf("expires_in must be between 0 and %d seconds", int(MaxExpiresIn.Seconds()))
+	ErrInvalidKind      = errors.New("unknown restriction kind")
+	ErrInvalidLimit     = fmt.Errorf("limit must be at most %d", MaxLogLimit)
+
+	// ErrBanned and ErrMuted are returned by Check, and so by the services
+	// that enforce bans and mutes.
+	ErrBanned = errors.New("you are banned from this chat")
+	ErrMuted  = errors.New("you are muted in this chat")
+)
+
+type activeRestrictions interface {
+	GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error)
+}
+
+// Check returns ErrBanned or ErrMuted when userID is under a restriction of
+// one of kinds in the chat. A timed restriction error says when it ends.
+func Check(ctx context.Context, restrictions activeRestrictions, chatID, userID int, kinds ...string) error {
+	active, err := restrictions.GetActive(ctx, chatID, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to check restrictions: %w", err)
+	}
+
+	for _, restriction := range active {
+		for _, kind := range kinds {
+			if restriction.Kind != kind {
+				continue
+			}
+
+			err := ErrMuted
+			if kind == models.RestrictionBan {
+				err = ErrBanned
+			}
+			if restriction.ExpiresAt != nil {
+				return fmt.Errorf("%w until %s", err, restriction.ExpiresAt.UTC().Format(time.RFC3339))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ModerationService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewModerationService(log *logrus.Logger, repository *repository.Repository) *ModerationService {
+	return &ModerationService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// Kick removes userID from the chat and bans them for KickBan, after which
+// they may join again.
+func (s *ModerationService) Kick(ctx context.Context, chatID, userID int, reason string) error {
+	if utf8.RuneCountInString(reason) > MaxReasonLength {
+		return ErrInvalidReason
+	}
+
+	caller, err := s.checkTarget(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(KickBan)
+
+	kicked, err := s.repository.Moderation.Kick(ctx, &models.ChatRestriction{
+		ChatID:      chatID,
+		UserID:      userID,
+		Kind:        models.RestrictionBan,
+		Reason:      reason,
+		ModeratorID: &caller.ID,
+		ExpiresAt:   &expiresAt,
+		CreatedAt:   now,
+	}, &models.ModerationAction{
+		ChatID:      chatID,
+		ModeratorID: &caller.ID,
+		UserID:      &userID,
+		Action:      models.ModerationKick,
+		Reason:      reason,
+		ExpiresAt:   &expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to kick user: %w", err)
+	}
+
+	if !kicked {
+		return ErrNotMember
+	}
+
+	s.log.Infof("User kicked from chat (Chat ID: %d, User ID: %d)", chatID, userID)
+	return nil
+}
+
+// Ban removes userID from the chat and keeps them from joining it again,
+// through invites too, for expiresIn seconds or, if it is zero, until Unban.
+func (s *ModerationService) Ban(ctx context.Context, chatID, userID int, reason string, expiresIn int) (*models.ChatRestriction, error) {
+	return s.restrict(ctx, chatID, userID, models.RestrictionBan, models.ModerationBan, reason, expiresIn)
+}
+
+// Mute keeps userID from posting to the chat for expiresIn seconds or, if it
+// is zero, until Unmute.
+func (s *ModerationService) Mute(ctx context.Context, chatID, userID int, reason string, expiresIn int) (*models.ChatRestriction, error) {
+	return s.restrict(ctx, chatID, userID, models.RestrictionMute, models.ModerationMute, reason, expiresIn)
+}
+
+func (s *ModerationService) Unban(ctx context.Context, chatID, userID int) error {
+	return s.lift(ctx, chatID, userID, models.RestrictionBan, models.ModerationUnban, ErrNotBanned)
+}
+
+func (s *ModerationService) Unmute(ctx context.Context, chatID, userID int) error {
+	return s.lift(ctx, chatID, userID, models.RestrictionMute, models.ModerationUnmute, ErrNotMuted)
+}
+
+// ListRestrictions returns the bans or mutes in force in the chat.
+func (s *ModerationService) ListRestrictions(ctx context.Context, chatID int, kind string) ([]*models.ChatRestriction, error) {
+	if kind != models.RestrictionBan && kind != models.RestrictionMute {
+		return nil, ErrInvalidKind
+	}
+
+	if _, err := s.checkOwner(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	restrictions, err := s.repository.Moderation.GetRestrictions(ctx, chatID, kind, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restrictions: %w", err)
+	}
+
+	return restrictions, nil
+}
+
+// GetModerationLog returns the kicks, bans and mutes of the chat and their
+// lifting, newest first.
+func (s *ModerationService) GetModerationLog(ctx context.Context, chatID, limit, offset int) ([]*models.ModerationAction, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > MaxLogLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err := s.checkOwner(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	actions, err := s.repository.Moderation.GetLog(ctx, chatID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation log: %w", err)
+	}
+
+	return actions, nil
+}
+
+func (s *ModerationService) restrict(ctx context.Context, chatID, userID int, kind, action, reason string, expiresIn int) (*models.ChatRestriction, error) {
+	if utf8.RuneCountInString(reason) > MaxReasonLength {
+		return nil, ErrInvalidReason
+	}
+
+	if expiresIn < 0 || expiresIn > int(MaxExpiresIn/time.Second) {
+		return nil, ErrInvalidExpiresIn
+	}
+
+	caller, err := s.checkTarget(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.User.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user with id: %d", userID)
+	}
+
+	restriction := models.ChatRestriction{
+		ChatID:      chatID,
+		UserID:      userID,
+		Kind:        kind,
+		Reason:      reason,
+		ModeratorID: &caller.ID,
+		CreatedAt:   time.Now(),
+	}
+
+	if expiresIn > 0 {
+		expiresAt := restriction.CreatedAt.Add(time.Duration(expiresIn) * time.Second)
+		restriction.ExpiresAt = &expiresAt
+	}
+
+	err = s.repository.Moderation.Restrict(ctx, &restriction, &models.ModerationAction{
+		ChatID:      chatID,
+		ModeratorID: &caller.ID,
+		UserID:      &userID,
+		Action:      action,
+		Reason:      reason,
+		ExpiresAt:   restriction.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s user: %w", action, err)
+	}
+
+	restriction.User = user
+
+	s.log.Infof("User restricted in chat (Chat ID: %d, User ID: %d, Kind: %s)", chatID, userID, kind)
+	return &restriction, nil
+}
+
+func (s *ModerationService) lift(ctx context.Context, chatID, userID int, kind, action string, notFound error) error {
+	caller, err := s.checkOwner(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	lifted, err := s.repository.Moderation.Lift(ctx, chatID, userID, kind, &models.ModerationAction{
+		ChatID:      chatID,
+		ModeratorID: &caller.ID,
+		UserID:      &userID,
+		Action:      action,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s user: %w", action, err)
+	}
+
+	if !lifted {
+		return notFound
+	}
+
+	s.log.Infof("User restriction lifted (Chat ID: %d, User ID: %d, Kind: %s)", chatID, userID, kind)
+	return nil
+}
+
+// checkTarget checks that the caller owns the chat and that userID is
+// somebody else.
+func (s *ModerationService) checkTarget(ctx context.Context, chatID, userID int) (*models.User, error) {
+	caller, err := s.checkOwner(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID == caller.ID {
+		return nil, ErrOwnerTarget
+	}
+
+	return caller, nil
+}
+
+func (s *ModerationService) checkOwner(ctx context.Context, chatID int) (*models.User, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	chat, err := s.repository.Chat.GetByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat with id: %d", chatID)
+	}
+
+	if chat.IsDirect() {
+		return nil, ErrChatNotFound
+	}
+
+	if chat.OwnerID == nil || *chat.OwnerID != caller.ID {
+		return nil, ErrNotOwner
+	}
+
+	return caller, nil
+}
@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeModeration keeps the restrictions and log of chat 1 in memory.
+type fakeModeration struct {
+	repository.Moderation
+
+	members      map[int]bool
+	restrictions map[string]*models.ChatRestriction
+	log          []*models.ModerationAction
+}
+
+func restrictionKey(userID int, kind string) string {
+	return fmt.Sprintf("%s:%d", kind, userID)
+}
+
+func (f *fakeModeration) Restrict(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) error {
+	f.restrictions[restrictionKey(restriction.UserID, restriction.Kind)] = restriction
+	if restriction.Kind == models.RestrictionBan {
+		delete(f.members, restriction.UserID)
+	}
+	f.log = append(f.log, entry)
+	return nil
+}
+
+func (f *fakeModeration) Lift(ctx context.Context, chatID, userID int, kind string, entry *models.ModerationAction) (bool, error) {
+	key := restrictionKey(userID, kind)
+	restriction, ok := f.restrictions[key]
+	if !ok || !restriction.Active(time.Now()) {
+		return false, nil
+	}
+	delete(f.restrictions, key)
+	f.log = append(f.log, entry)
+	return true, nil
+}
+
+func (f *fakeModeration) Kick(ctx context.Context, restriction *models.ChatRestriction, entry *models.ModerationAction) (bool, error) {
+	if !f.members[restriction.UserID] {
+		return false, nil
+	}
+	delete(f.members, restriction.UserID)
+	f.restrictions[restrictionKey(restriction.UserID, restriction.Kind)] = restriction
+	f.log = append(f.log, entry)
+	return true, nil
+}
+
+func (f *fakeModeration) GetActive(ctx context.Context, chatID, userID int, now time.Time) ([]*models.ChatRestriction, error) {
+	var active []*models.ChatRestriction
+	for _, restriction := range f.restrictions {
+		if restriction.UserID == userID && restriction.Active(now) {
+			active = append(active, restriction)
+		}
+	}
+	return active, nil
+}
+
+func newService() (*ModerationService, *fakeModeration) {
+	moderation := &fakeModeration{
+		members:      map[int]bool{8: true, 9: true},
+		restrictions: map[string]*models.ChatRestriction{},
+	}
+	return NewModerationService(logrus.New(), &repository.Repository{
+		Chat:       testutil.NewChats(testutil.GroupChat("moderated")),
+		User:       testutil.NewUsers(testutil.Owner, testutil.Stranger, &models.User{ID: 9, Username: "carol"}),
+		Moderation: moderation,
+	}), moderation
+}
+
+func TestBan(t *testing.T) {
+	s, moderation := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	restriction, err := s.Ban(ctx, 1, 8, "spam", 3600)
+	require.NoError(t, err)
+	assert.Equal(t, models.RestrictionBan, restriction.Kind)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *restriction.ExpiresAt, time.Minute)
+	assert.False(t, moderation.members[8])
+
+	err = Check(ctx, moderation, 1, 8, models.RestrictionBan)
+	assert.ErrorIs(t, err, ErrBanned)
+	assert.Contains(t, err.Error(), "until")
+
+	// A ban does not mute.
+	assert.NoError(t, Check(ctx, moderation, 1, 8, models.RestrictionMute))
+
+	require.NoError(t, s.Unban(ctx, 1, 8))
+	assert.NoError(t, Check(ctx, moderation, 1, 8, models.RestrictionBan))
+	assert.ErrorIs(t, s.Unban(ctx, 1, 8), ErrNotBanned)
+
+	require.Len(t, moderation.log, 2)
+	assert.Equal(t, models.ModerationBan, moderation.log[0].Action)
+	assert.Equal(t, "spam", moderation.log[0].Reason)
+	assert.Equal(t, testutil.Owner.ID, *moderation.log[0].ModeratorID)
+	assert.Equal(t, models.ModerationUnban, moderation.log[1].Action)
+}
+
+func TestMute_Expires(t *testing.T) {
+	s, moderation := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	_, err := s.Mute(ctx, 1, 9, "", 0)
+	require.NoError(t, err)
+	assert.True(t, moderation.members[9])
+
+	err = Check(ctx, moderation, 1, 9, models.RestrictionBan, models.RestrictionMute)
+	assert.Equal(t, ErrMuted, err)
+
+	past := time.Now().Add(-time.Second)
+	moderation.restrictions[restrictionKey(9, models.RestrictionMute)].ExpiresAt = &past
+	assert.NoError(t, Check(ctx, moderation, 1, 9, models.RestrictionMute))
+	assert.ErrorIs(t, s.Unmute(ctx, 1, 9), ErrNotMuted)
+}
+
+func TestKick(t *testing.T) {
+	s, moderation := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	require.NoError(t, s.Kick(ctx, 1, 8, "off topic"))
+	assert.False(t, moderation.members[8])
+	assert.ErrorIs(t, s.Kick(ctx, 1, 8, ""), ErrNotMember)
+
+	// A kicked user can neither post nor join again for a while, which
+	// matters in public chats that need no membership.
+	assert.ErrorIs(t, Check(ctx, moderation, 1, 8, models.RestrictionBan, models.RestrictionMute), ErrBanned)
+	assert.ErrorIs(t, Check(ctx, moderation, 1, 8, models.RestrictionBan), ErrBanned)
+
+	restriction := moderation.restrictions[restrictionKey(8, models.RestrictionBan)]
+	assert.WithinDuration(t, time.Now().Add(KickBan), *restriction.ExpiresAt, time.Minute)
+
+	past := time.Now().Add(-time.Second)
+	restriction.ExpiresAt = &past
+	assert.NoError(t, Check(ctx, moderation, 1, 8, models.RestrictionBan))
+
+	require.Len(t, moderation.log, 1)
+	assert.Equal(t, models.ModerationKick, moderation.log[0].Action)
+	assert.NotNil(t, moderation.log[0].ExpiresAt)
+}
+
+func TestModeration_Permissions(t *testing.T) {
+	s, moderation := newService()
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	_, err := s.Ban(context.Background(), 1, 8, "", 0)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	_, err = s.Ban(auth.WithUser(context.Background(), testutil.Stranger), 1, 9, "", 0)
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	err = s.Kick(auth.WithUser(context.Background(), testutil.Stranger), 1, 9, "")
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	_, err = s.Mute(ctx, 1, testutil.Owner.ID, "", 0)
+	assert.ErrorIs(t, err, ErrOwnerTarget)
+
+	_, err = s.Ban(ctx, 2, 8, "", 0)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	_, err = s.Ban(ctx, 1, 42, "", 0)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = s.Ban(ctx, 1, 8, strings.Repeat("x", MaxReasonLength+1), 0)
+	assert.ErrorIs(t, err, ErrInvalidReason)
+
+	_, err = s.Mute(ctx, 1, 8, "", int(MaxExpiresIn/time.Second)+1)
+	assert.ErrorIs(t, err, ErrInvalidExpiresIn)
+
+	assert.Empty(t, moderation.log)
+}
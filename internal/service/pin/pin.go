@@ -0,0 +1,164 @@
+package pin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	messageRepository "github.com/AlGrushino/chat/internal/repository/message"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const DefaultMaxPins = 50
+
+var (
+	ErrChatNotFound    = errors.New("chat does not exist")
+	ErrMessageNotFound = errors.New("message does not exist")
+	ErrNotAllowed      = errors.New("only the chat owner can pin messages")
+	ErrTooManyPins     = errors.New("chat has too many pinned messages")
+)
+
+type Config struct {
+	MaxPins int
+}
+
+// GetConfig reads PINS_MAX, the number of messages a chat may have pinned.
+func GetConfig(log *logrus.Logger) *Config {
+	log.Info("Getting pins config from env")
+
+	cfg := Config{MaxPins: DefaultMaxPins}
+
+	if raw := os.Getenv("PINS_MAX"); raw != "" {
+		maxPins, err := strconv.Atoi(raw)
+		if err != nil || maxPins <= 0 {
+			log.Warnf("Invalid PINS_MAX %q, using %d", raw, DefaultMaxPins)
+		} else {
+			cfg.MaxPins = maxPins
+		}
+	}
+
+	return &cfg
+}
+
+type PinService struct {
+	repository *repository.Repository
+	maxPins    int
+	log        *logrus.Logger
+}
+
+func NewPinService(log *logrus.Logger, repository *repository.Repository, maxPins int) *PinService {
+	return &PinService{
+		repository: repository,
+		maxPins:    maxPins,
+		log:        log,
+	}
+}
+
+// SetMaxPins changes the limit for pins made from now on.
+func (s *PinService) SetMaxPins(maxPins int) {
+	s.maxPins = maxPins
+}
+
+// Pin pins a message to its chat. In group chats only the owner may pin;
+// group chats without an owner let any signed-in user do it, and direct
+// chats both participants.
+func (s *PinService) Pin(ctx context.Context, chatID, messageID int) (*models.Message, error) {
+	caller, err := s.checkAllowed(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.repository.Message.Pin(ctx, chatID, messageID, caller.ID, s.maxPins)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, ErrMessageNotFound
+		case errors.Is(err, messageRepository.ErrTooManyPins):
+			return nil, fmt.Errorf("%w: at most %d are allowed", ErrTooManyPins, s.maxPins)
+		default:
+			return nil, fmt.Errorf("failed to pin message: %w", err)
+		}
+	}
+
+	s.log.Infof("Message pinned (Chat ID: %d, Message ID: %d, User ID: %d)", chatID, messageID, caller.ID)
+	return message, nil
+}
+
+// Unpin unpins a message. It takes the same permission as Pin.
+func (s *PinService) Unpin(ctx context.Context, chatID, messageID int) error {
+	if _, err := s.checkAllowed(ctx, chatID); err != nil {
+		return err
+	}
+
+	unpinned, err := s.repository.Message.Unpin(ctx, chatID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to unpin message: %w", err)
+	}
+
+	if !unpinned {
+		return ErrMessageNotFound
+	}
+
+	s.log.Infof("Message unpinned (Chat ID: %d, Message ID: %d)", chatID, messageID)
+	return nil
+}
+
+// ListPins returns the pinned messages of a chat the caller can read, most
+// recently pinned first.
+func (s *PinService) ListPins(ctx context.Context, chatID int) ([]*models.Message, error) {
+	if _, err := s.getChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	messages, err := s.repository.Message.GetPinned(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pinned messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (s *PinService) checkAllowed(ctx context.Context, chatID int) (*models.User, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	chat, err := s.getChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !chat.IsDirect() && chat.OwnerID != nil && *chat.OwnerID != caller.ID {
+		return nil, ErrNotAllowed
+	}
+
+	return caller, nil
+}
+
+func (s *PinService) getChat(ctx context.Context, id int) (*models.Chat, error) {
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return nil, ErrChatNotFound
+	}
+
+	return chat, nil
+}
@@ -0,0 +1,99 @@
+package pin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	messageRepository "github.com/AlGrushino/chat/internal/repository/message"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeMessages pins messages 1 to 9 and keeps at most maxPins of them.
+type fakeMessages struct {
+	repository.Message
+
+	pinned map[int]bool
+}
+
+func (f *fakeMessages) Pin(ctx context.Context, chatID, messageID, userID, maxPins int) (*models.Message, error) {
+	if messageID < 1 || messageID > 9 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if !f.pinned[messageID] && len(f.pinned) >= maxPins {
+		return nil, messageRepository.ErrTooManyPins
+	}
+	f.pinned[messageID] = true
+
+	now := time.Now()
+	return &models.Message{ID: messageID, ChatID: chatID, PinnedAt: &now, PinnedByID: &userID}, nil
+}
+
+func (f *fakeMessages) Unpin(ctx context.Context, chatID, messageID int) (bool, error) {
+	if !f.pinned[messageID] {
+		return false, nil
+	}
+	delete(f.pinned, messageID)
+	return true, nil
+}
+
+func newService(maxPins int) (*PinService, *fakeMessages) {
+	messages := &fakeMessages{pinned: map[int]bool{}}
+	return NewPinService(logrus.New(), &repository.Repository{
+		Chat:    testutil.NewChats(testutil.GroupChat("standup")),
+		Message: messages,
+	}, maxPins), messages
+}
+
+func TestPin_Limit(t *testing.T) {
+	s, messages := newService(2)
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	for _, id := range []int{1, 2, 2} {
+		message, err := s.Pin(ctx, 1, id)
+		require.NoError(t, err)
+		assert.Equal(t, testutil.Owner.ID, *message.PinnedByID)
+	}
+
+	_, err := s.Pin(ctx, 1, 3)
+	assert.ErrorIs(t, err, ErrTooManyPins)
+
+	require.NoError(t, s.Unpin(ctx, 1, 1))
+	_, err = s.Pin(ctx, 1, 3)
+	require.NoError(t, err)
+	assert.Len(t, messages.pinned, 2)
+}
+
+func TestPin_Permissions(t *testing.T) {
+	s, _ := newService(DefaultMaxPins)
+
+	_, err := s.Pin(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	_, err = s.Pin(auth.WithUser(context.Background(), testutil.Stranger), 1, 1)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	err = s.Unpin(auth.WithUser(context.Background(), testutil.Stranger), 1, 1)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+}
+
+func TestPin_NotFound(t *testing.T) {
+	s, _ := newService(DefaultMaxPins)
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+
+	_, err := s.Pin(ctx, 2, 1)
+	assert.ErrorIs(t, err, ErrChatNotFound)
+
+	_, err = s.Pin(ctx, 1, 42)
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	err = s.Unpin(ctx, 1, 5)
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
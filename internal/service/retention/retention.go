@@ -0,0 +1,209 @@
+// Package retention deletes messages that outlived the retention of their
+// chat.
+package retention
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultInterval  = time.Hour
+	DefaultBatchSize = 500
+
+	// batchPause spaces out batches so that the janitor does not starve
+	// regular traffic to the messages table.
+	batchPause = 100 * time.Millisecond
+)
+
+// Metrics are published with expvar under "retention".
+var (
+	metrics             = expvar.NewMap("retention")
+	runs                = new(expvar.Int)
+	failures            = new(expvar.Int)
+	messagesDeleted     = new(expvar.Int)
+	attachmentsDeleted  = new(expvar.Int)
+	lastRunDurationMs   = new(expvar.Int)
+	lastRunMessageCount = new(expvar.Int)
+	ephemeralDeleted    = new(expvar.Int)
+)
+
+func init() {
+	metrics.Set("runs_total", runs)
+	metrics.Set("failures_total", failures)
+	metrics.Set("messages_deleted_total", messagesDeleted)
+	metrics.Set("attachments_deleted_total", attachmentsDeleted)
+	metrics.Set("last_run_duration_ms", lastRunDurationMs)
+	metrics.Set("last_run_messages_deleted", lastRunMessageCount)
+	metrics.Set("ephemeral_deleted_total", ephemeralDeleted)
+}
+
+// Config.DefaultDays applies to chats without their own retention; zero
+// keeps their messages forever.
+type Config struct {
+	DefaultDays int
+	Interval    time.Duration
+	BatchSize   int
+}
+
+// GetConfig reads RETENTION_DAYS, RETENTION_INTERVAL (a Go duration such as
+// "1h") and RETENTION_BATCH_SIZE.
+func GetConfig(log *logrus.Logger) *Config {
+	log.Info("Getting retention config from env")
+
+	cfg := Config{
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+	}
+
+	if raw := os.Getenv("RETENTION_DAYS"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 0 {
+			log.Warnf("Invalid RETENTION_DAYS %q, keeping messages forever", raw)
+		} else {
+			cfg.DefaultDays = days
+		}
+	}
+
+	if raw := os.Getenv("RETENTION_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			log.Warnf("Invalid RETENTION_INTERVAL %q, using %s", raw, DefaultInterval)
+		} else {
+			cfg.Interval = interval
+		}
+	}
+
+	if raw := os.Getenv("RETENTION_BATCH_SIZE"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			log.Warnf("Invalid RETENTION_BATCH_SIZE %q, using %d", raw, DefaultBatchSize)
+		} else {
+			cfg.BatchSize = size
+		}
+	}
+
+	return &cfg
+}
+
+// Janitor periodically deletes expired messages. Each batch is a short
+// transaction of its own that skips rows locked by writers, so the messages
+// table is never locked for long and several instances can run side by side.
+type Janitor struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	cfg        Config
+	log        *logrus.Logger
+}
+
+// NewJanitor keeps messages forever until SetConfig sets a default.
+func NewJanitor(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore) *Janitor {
+	return &Janitor{
+		repository: repository,
+		store:      store,
+		cfg: Config{
+			Interval:  DefaultInterval,
+			BatchSize: DefaultBatchSize,
+		},
+		log: log,
+	}
+}
+
+// SetConfig must be called before Run.
+func (j *Janitor) SetConfig(cfg *Config) {
+	j.cfg = *cfg
+}
+
+func (j *Janitor) Run(ctx context.Context) {
+	j.log.WithFields(logrus.Fields{
+		"default_days": j.cfg.DefaultDays,
+		"interval":     j.cfg.Interval.String(),
+		"batch_size":   j.cfg.BatchSize,
+	}).Info("Retention janitor started")
+	defer j.log.Info("Retention janitor stopped")
+
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	j.sweep(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.sweep(ctx)
+		}
+	}
+}
+
+// sweep deletes batches until no expired messages are left and returns how
+// many messages it deleted.
+func (j *Janitor) sweep(ctx context.Context) int64 {
+	start := time.Now()
+	runs.Add(1)
+
+	var total int64
+	for ctx.Err() == nil {
+		deleted, attachments, err := j.repository.Message.DeleteExpired(ctx, j.cfg.DefaultDays, j.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				failures.Add(1)
+				j.log.WithError(err).Error("Failed to delete expired messages")
+			}
+			break
+		}
+
+		total += deleted
+		messagesDeleted.Add(deleted)
+		deleteBlobs(ctx, j.log, j.store, attachments)
+
+		if deleted < int64(j.cfg.BatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(batchPause):
+		}
+	}
+
+	lastRunDurationMs.Set(time.Since(start).Milliseconds())
+	lastRunMessageCount.Set(total)
+
+	if total > 0 {
+		j.log.WithFields(logrus.Fields{
+			"messages":    total,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}).Info("Deleted expired messages")
+	}
+
+	return total
+}
+
+// deleteBlobs removes the files of attachments whose rows were deleted with
+// their messages. Failures only leave orphaned files behind.
+func deleteBlobs(ctx context.Context, log *logrus.Logger, store storage.BlobStore, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		keys := []string{attachment.BlobKey}
+		if attachment.ThumbnailKey != nil {
+			keys = append(keys, *attachment.ThumbnailKey)
+		}
+
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				log.WithError(err).WithField("attachment_id", attachment.ID).Warn("Failed to delete attachment blob")
+			}
+		}
+		attachmentsDeleted.Add(1)
+	}
+}
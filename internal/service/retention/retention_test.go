@@ -0,0 +1,118 @@
+package retention
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlGrushino/chat/internal/events"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMessages struct {
+	repository.Message
+
+	expired     int
+	attachments []models.Attachment
+	calls       int
+	defaultDays int
+	ephemeral   []*models.Message
+}
+
+func (f *fakeMessages) DeleteExpired(ctx context.Context, defaultDays, limit int) (int64, []models.Attachment, error) {
+	f.calls++
+	f.defaultDays = defaultDays
+
+	n := min(f.expired, limit)
+	f.expired -= n
+
+	attachments := f.attachments
+	f.attachments = nil
+	return int64(n), attachments, nil
+}
+
+func (f *fakeMessages) DeleteEphemeral(ctx context.Context, limit int) ([]*models.Message, error) {
+	f.calls++
+
+	n := min(len(f.ephemeral), limit)
+	batch := f.ephemeral[:n]
+	f.ephemeral = f.ephemeral[n:]
+	return batch, nil
+}
+
+func TestSweep_DeletesInBatches(t *testing.T) {
+	thumb := "thumb/1"
+	messages := &fakeMessages{
+		expired: 25,
+		attachments: []models.Attachment{
+			{ID: 1, BlobKey: "blob/1", ThumbnailKey: &thumb},
+			{ID: 2, BlobKey: "blob/missing"},
+		},
+	}
+	store := testutil.NewBlobStore(map[string][]byte{"blob/1": nil, "thumb/1": nil, "blob/2": nil})
+
+	janitor := NewJanitor(logrus.New(), &repository.Repository{Message: messages}, store)
+	janitor.SetConfig(&Config{DefaultDays: 30, Interval: DefaultInterval, BatchSize: 10})
+
+	deleted := janitor.sweep(context.Background())
+
+	assert.Equal(t, int64(25), deleted)
+	assert.Equal(t, 3, messages.calls)
+	assert.Equal(t, 30, messages.defaultDays)
+	assert.Equal(t, map[string][]byte{"blob/2": nil}, store.Blobs)
+}
+
+func TestSweep_StopsOnCancel(t *testing.T) {
+	messages := &fakeMessages{expired: 100}
+
+	janitor := NewJanitor(logrus.New(), &repository.Repository{Message: messages}, testutil.NewBlobStore(nil))
+	janitor.SetConfig(&Config{Interval: DefaultInterval, BatchSize: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Zero(t, janitor.sweep(ctx))
+	assert.Zero(t, messages.calls)
+}
+
+func TestSweeper_PublishesExpired(t *testing.T) {
+	log := logrus.New()
+	bus := events.NewBus(log)
+	stream, unsubscribe := bus.Subscribe(10)
+	defer unsubscribe()
+
+	messages := &fakeMessages{ephemeral: []*models.Message{{
+		ID:          5,
+		ChatID:      2,
+		Text:        "the password is hunter2",
+		Chat:        models.Chat{ID: 2, Title: "ops"},
+		Attachments: []models.Attachment{{ID: 1, BlobKey: "blob/1"}},
+	}}}
+	store := testutil.NewBlobStore(map[string][]byte{"blob/1": nil})
+
+	sweeper := NewSweeper(log, &repository.Repository{Message: messages}, store, bus)
+
+	assert.Equal(t, 1, sweeper.sweep(context.Background()))
+	assert.Empty(t, store.Blobs)
+
+	event := <-stream
+	assert.Equal(t, events.MessageExpired, event.Type)
+	assert.Equal(t, 2, event.Chat.ID)
+	assert.Equal(t, 5, event.Message.ID)
+	assert.Empty(t, event.Message.Text)
+}
+
+func TestGetConfig(t *testing.T) {
+	t.Setenv("RETENTION_DAYS", "90")
+	t.Setenv("RETENTION_INTERVAL", "bogus")
+	t.Setenv("RETENTION_BATCH_SIZE", "250")
+
+	cfg := GetConfig(logrus.New())
+
+	assert.Equal(t, 90, cfg.DefaultDays)
+	assert.Equal(t, DefaultInterval, cfg.Interval)
+	assert.Equal(t, 250, cfg.BatchSize)
+}
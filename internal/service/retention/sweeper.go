@@ -0,0 +1,96 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/events"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SweepInterval bounds how long an expired message stays in the
+	// database. Listings hide it from the moment it expires.
+	SweepInterval  = 10 * time.Second
+	SweepBatchSize = 100
+)
+
+// Sweeper deletes self-destructing messages once they expire and publishes
+// a MessageExpired event for each of them.
+type Sweeper struct {
+	repository *repository.Repository
+	store      storage.BlobStore
+	events     *events.Bus
+	log        *logrus.Logger
+}
+
+func NewSweeper(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore, bus *events.Bus) *Sweeper {
+	return &Sweeper{
+		repository: repository,
+		store:      store,
+		events:     bus,
+		log:        log,
+	}
+}
+
+func (s *Sweeper) Run(ctx context.Context) {
+	s.log.Info("Expired message sweeper started")
+	defer s.log.Info("Expired message sweeper stopped")
+
+	ticker := time.NewTicker(SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep deletes batches until no expired messages are left and returns how
+// many it deleted.
+func (s *Sweeper) sweep(ctx context.Context) int {
+	total := 0
+	for ctx.Err() == nil {
+		messages, err := s.repository.Message.DeleteEphemeral(ctx, SweepBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				failures.Add(1)
+				s.log.WithError(err).Error("Failed to delete expired messages")
+			}
+			break
+		}
+
+		for _, message := range messages {
+			deleteBlobs(ctx, s.log, s.store, message.Attachments)
+
+			// The content of a self-destructing message must not outlive
+			// it, so the event only identifies the message.
+			s.events.Publish(ctx, events.Event{
+				Type:    events.MessageExpired,
+				Chat:    &message.Chat,
+				Message: &models.Message{ID: message.ID, ChatID: message.ChatID, ExpiresAt: message.ExpiresAt},
+			})
+		}
+
+		total += len(messages)
+		ephemeralDeleted.Add(int64(len(messages)))
+
+		if len(messages) < SweepBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.log.WithField("messages", total).Info("Deleted self-destructing messages")
+	}
+
+	return total
+}
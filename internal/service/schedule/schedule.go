@@ -0,0 +1,208 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/command"
+	"github.com/AlGrushino/chat/internal/service/message"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxPending is how many messages a user may have waiting to be sent.
+	MaxPending = 100
+
+	// MaxAhead is how far in the future a message may be scheduled.
+	MaxAhead = 365 * 24 * time.Hour
+)
+
+var (
+	ErrChatNotFound  = errors.New("chat does not exist")
+	ErrNotFound      = errors.New("scheduled message does not exist")
+	ErrInvalidSendAt = errors.New("send_at must be in the future and at most a year ahead")
+	ErrCommand       = errors.New("slash commands cannot be scheduled")
+	ErrTooMany       = fmt.Errorf("at most %d messages may be scheduled at a time", MaxPending)
+	ErrNotPending    = errors.New("scheduled message is already sent, canceled or being sent")
+)
+
+type ScheduleService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewScheduleService(log *logrus.Logger, repository *repository.Repository) *ScheduleService {
+	return &ScheduleService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// ScheduleMessage stores msg to be posted by the caller at sendAt. The
+// message is validated now, but mentions, slow mode and attachments are
+// only resolved when it is sent.
+func (s *ScheduleService) ScheduleMessage(ctx context.Context, chatID int, msg message.NewMessage, sendAt time.Time) (*models.ScheduledMessage, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if msg.Text == "" {
+		return nil, message.ErrEmptyText
+	}
+
+	if len(msg.Text) > 5000 {
+		return nil, message.ErrTextTooLong
+	}
+
+	if len(msg.AttachmentIDs) > message.MaxAttachments {
+		return nil, fmt.Errorf("%w: %d", message.ErrTooManyAttachments, len(msg.AttachmentIDs))
+	}
+
+	if msg.ExpiresIn < 0 || msg.ExpiresIn > int(message.MaxExpiresIn/time.Second) {
+		return nil, message.ErrInvalidExpiresIn
+	}
+
+	if _, _, ok := command.Parse(msg.Text); ok {
+		return nil, ErrCommand
+	}
+
+	now := time.Now()
+	if !sendAt.After(now) || sendAt.Sub(now) > MaxAhead {
+		return nil, ErrInvalidSendAt
+	}
+
+	clientMsgID := newClientMsgID()
+	if msg.ClientMsgID != "" {
+		normalized, err := message.ParseClientMsgID(msg.ClientMsgID)
+		if err != nil {
+			return nil, err
+		}
+		clientMsgID = normalized
+	}
+
+	if err := s.checkChat(ctx, chatID); err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repository.Schedule.CountPendingByUser(ctx, caller.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count scheduled messages: %w", err)
+	}
+
+	if pending >= MaxPending {
+		return nil, ErrTooMany
+	}
+
+	attachmentIDs := msg.AttachmentIDs
+	if attachmentIDs == nil {
+		attachmentIDs = []int{}
+	}
+
+	scheduled := &models.ScheduledMessage{
+		ChatID:        chatID,
+		UserID:        caller.ID,
+		Text:          msg.Text,
+		AttachmentIDs: attachmentIDs,
+		ClientMsgID:   clientMsgID,
+		ExpiresIn:     msg.ExpiresIn,
+		SendAt:        sendAt,
+		Status:        models.ScheduledPending,
+	}
+	if err := s.repository.Schedule.Create(ctx, scheduled); err != nil {
+		return nil, fmt.Errorf("failed to schedule message: %w", err)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"scheduled_id": scheduled.ID,
+		"chat_id":      chatID,
+		"user_id":      caller.ID,
+		"send_at":      sendAt,
+	}).Info("Message scheduled")
+
+	return scheduled, nil
+}
+
+// ListScheduled returns the caller's messages waiting to be sent.
+func (s *ScheduleService) ListScheduled(ctx context.Context) ([]*models.ScheduledMessage, error) {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	scheduled, err := s.repository.Schedule.GetPendingByUser(ctx, caller.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled messages: %w", err)
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduled cancels a pending message of the caller. Messages of other
+// users are reported as missing.
+func (s *ScheduleService) CancelScheduled(ctx context.Context, id int) error {
+	caller := auth.UserFromContext(ctx)
+	if caller == nil {
+		return auth.ErrUnauthenticated
+	}
+
+	canceled, err := s.repository.Schedule.Cancel(ctx, id, caller.ID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled message: %w", err)
+	}
+
+	if canceled {
+		return nil
+	}
+
+	scheduled, err := s.repository.Schedule.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get scheduled message: %w", err)
+	}
+
+	if scheduled.UserID != caller.ID {
+		return ErrNotFound
+	}
+
+	return ErrNotPending
+}
+
+func (s *ScheduleService) checkChat(ctx context.Context, id int) error {
+	chat, err := s.repository.Chat.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatNotFound
+		}
+		return fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+	if err != nil {
+		return fmt.Errorf("failed to check chat access: %w", err)
+	}
+
+	if !allowed {
+		return ErrChatNotFound
+	}
+
+	return nil
+}
+
+// newClientMsgID returns a random UUID.
+func newClientMsgID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,170 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/message"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSchedule struct {
+	repository.Schedule
+
+	created  []*models.ScheduledMessage
+	due      []*models.ScheduledMessage
+	sent     []*models.ScheduledMessage
+	released []*models.ScheduledMessage
+	failed   []*models.ScheduledMessage
+}
+
+func (f *fakeSchedule) Create(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	scheduled.ID = len(f.created) + 1
+	f.created = append(f.created, scheduled)
+	return nil
+}
+
+func (f *fakeSchedule) CountPendingByUser(ctx context.Context, userID int) (int64, error) {
+	return int64(len(f.created)), nil
+}
+
+func (f *fakeSchedule) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.ScheduledMessage, error) {
+	due := f.due
+	f.due = nil
+	for _, scheduled := range due {
+		scheduled.Attempts++
+	}
+	return due, nil
+}
+
+func (f *fakeSchedule) MarkSent(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	f.sent = append(f.sent, scheduled)
+	return nil
+}
+
+func (f *fakeSchedule) Release(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	f.released = append(f.released, scheduled)
+	return nil
+}
+
+func (f *fakeSchedule) Fail(ctx context.Context, scheduled *models.ScheduledMessage) error {
+	f.failed = append(f.failed, scheduled)
+	return nil
+}
+
+// poster fails with the errors queued for a message's text, then posts it.
+type poster struct {
+	errs  map[string]error
+	calls []message.NewMessage
+	users []*models.User
+}
+
+func (p *poster) AddMessage(ctx context.Context, id int, msg message.NewMessage) (*models.Message, error) {
+	p.calls = append(p.calls, msg)
+	p.users = append(p.users, auth.UserFromContext(ctx))
+	if err := p.errs[msg.Text]; err != nil {
+		return nil, err
+	}
+	return &models.Message{ID: 100 + len(p.calls), ChatID: id, Text: msg.Text}, nil
+}
+
+func newRepository(schedule *fakeSchedule) *repository.Repository {
+	return &repository.Repository{Chat: testutil.NewChats(testutil.GroupChat("standup")), Schedule: schedule}
+}
+
+func TestScheduleMessage(t *testing.T) {
+	schedule := &fakeSchedule{}
+	service := NewScheduleService(logrus.New(), newRepository(schedule))
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+	sendAt := time.Now().Add(time.Hour)
+
+	scheduled, err := service.ScheduleMessage(ctx, 1, message.NewMessage{Text: "standup in 5"}, sendAt)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledPending, scheduled.Status)
+	assert.Equal(t, testutil.Owner.ID, scheduled.UserID)
+	assert.Equal(t, sendAt, scheduled.SendAt)
+	_, err = message.ParseClientMsgID(scheduled.ClientMsgID)
+	assert.NoError(t, err)
+}
+
+func TestScheduleMessage_Invalid(t *testing.T) {
+	service := NewScheduleService(logrus.New(), newRepository(&fakeSchedule{}))
+	ctx := auth.WithUser(context.Background(), testutil.Owner)
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		chatID int
+		text   string
+		sendAt time.Time
+		want   error
+	}{
+		{"anonymous", context.Background(), 1, "hi", later, auth.ErrUnauthenticated},
+		{"in the past", ctx, 1, "hi", time.Now().Add(-time.Minute), ErrInvalidSendAt},
+		{"too far ahead", ctx, 1, "hi", time.Now().Add(2 * MaxAhead), ErrInvalidSendAt},
+		{"command", ctx, 1, "/remind me", later, ErrCommand},
+		{"empty", ctx, 1, "", later, message.ErrEmptyText},
+		{"missing chat", ctx, 2, "hi", later, ErrChatNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ScheduleMessage(tt.ctx, tt.chatID, message.NewMessage{Text: tt.text}, tt.sendAt)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestScheduler_Send(t *testing.T) {
+	schedule := &fakeSchedule{due: []*models.ScheduledMessage{
+		{ID: 1, ChatID: 1, UserID: testutil.Owner.ID, User: testutil.Owner, Text: "posted", ClientMsgID: "c1"},
+		{ID: 2, ChatID: 1, UserID: testutil.Owner.ID, User: testutil.Owner, Text: "gone", ClientMsgID: "c2"},
+		{ID: 3, ChatID: 1, UserID: testutil.Owner.ID, User: testutil.Owner, Text: "flaky", ClientMsgID: "c3"},
+		{ID: 4, ChatID: 1, UserID: testutil.Owner.ID, User: testutil.Owner, Text: "slow", ClientMsgID: "c4"},
+	}}
+	messages := &poster{errs: map[string]error{
+		"gone":  errors.New("chat does not exist"),
+		"flaky": errors.New("connection reset"),
+		"slow":  &message.SlowModeError{RetryAfter: 30 * time.Second},
+	}}
+
+	scheduler := NewScheduler(logrus.New(), newRepository(schedule), messages)
+	scheduler.drain(context.Background())
+
+	require.Len(t, schedule.sent, 1)
+	assert.Equal(t, 1, schedule.sent[0].ID)
+	assert.Equal(t, 101, *schedule.sent[0].MessageID)
+
+	require.Len(t, schedule.failed, 1)
+	assert.Equal(t, 2, schedule.failed[0].ID)
+
+	require.Len(t, schedule.released, 2)
+	assert.Equal(t, 3, schedule.released[0].ID)
+	assert.Equal(t, 4, schedule.released[1].ID)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), *schedule.released[1].ClaimedUntil, time.Second)
+
+	assert.Equal(t, "c1", messages.calls[0].ClientMsgID)
+	assert.Equal(t, testutil.Owner, messages.users[0])
+}
+
+func TestScheduler_GivesUp(t *testing.T) {
+	schedule := &fakeSchedule{due: []*models.ScheduledMessage{
+		{ID: 1, ChatID: 1, UserID: testutil.Owner.ID, User: testutil.Owner, Text: "flaky", Attempts: MaxAttempts - 1},
+	}}
+	messages := &poster{errs: map[string]error{"flaky": errors.New("connection reset")}}
+
+	NewScheduler(logrus.New(), newRepository(schedule), messages).drain(context.Background())
+
+	require.Len(t, schedule.failed, 1)
+	assert.Equal(t, "connection reset", *schedule.failed[0].LastError)
+}
@@ -0,0 +1,173 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/message"
+	"github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MaxAttempts = 5
+
+	schedulerInterval   = 5 * time.Second
+	schedulerBatchSize  = 20
+	schedulerLease      = time.Minute
+	schedulerRetryDelay = time.Minute
+)
+
+type MessagePoster interface {
+	AddMessage(ctx context.Context, id int, msg message.NewMessage) (*models.Message, error)
+}
+
+// Scheduler posts scheduled messages once they are due. Messages are claimed
+// with SELECT ... FOR UPDATE SKIP LOCKED and stay in the database until they
+// are sent, so they survive restarts and several instances can run it side
+// by side.
+type Scheduler struct {
+	repository *repository.Repository
+	messages   MessagePoster
+	log        *logrus.Logger
+}
+
+func NewScheduler(log *logrus.Logger, repository *repository.Repository, messages MessagePoster) *Scheduler {
+	return &Scheduler{
+		repository: repository,
+		messages:   messages,
+		log:        log,
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	s.log.Info("Message scheduler started")
+	defer s.log.Info("Message scheduler stopped")
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain sends due messages until none are left.
+func (s *Scheduler) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := s.repository.Schedule.ClaimDue(ctx, schedulerBatchSize, schedulerLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.WithError(err).Error("Failed to claim scheduled messages")
+			}
+			return
+		}
+
+		for _, scheduled := range due {
+			s.send(ctx, scheduled)
+		}
+
+		if len(due) < schedulerBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, scheduled *models.ScheduledMessage) {
+	log := s.log.WithFields(logrus.Fields{
+		"scheduled_id": scheduled.ID,
+		"chat_id":      scheduled.ChatID,
+		"user_id":      scheduled.UserID,
+		"attempt":      scheduled.Attempts,
+	})
+
+	posted, err := s.post(ctx, scheduled)
+	if ctx.Err() != nil {
+		// The lease runs out and another worker takes over.
+		return
+	}
+
+	now := time.Now()
+	if err == nil {
+		scheduled.MessageID = &posted.ID
+		scheduled.CompletedAt = &now
+		if err := s.repository.Schedule.MarkSent(ctx, scheduled); err != nil {
+			log.WithError(err).Error("Failed to mark scheduled message as sent")
+			return
+		}
+		log.WithField("message_id", posted.ID).Info("Scheduled message sent")
+		return
+	}
+
+	lastError := err.Error()
+	if len(lastError) > 1000 {
+		lastError = lastError[:1000]
+	}
+	scheduled.LastError = &lastError
+
+	retryAt := now.Add(schedulerRetryDelay)
+	var slowModeErr *message.SlowModeError
+	switch {
+	case errors.As(err, &slowModeErr):
+		// Slow mode only delays the message, however long it takes.
+		retryAt = now.Add(slowModeErr.RetryAfter)
+	case permanent(err) || scheduled.Attempts >= MaxAttempts:
+		scheduled.CompletedAt = &now
+		if err := s.repository.Schedule.Fail(ctx, scheduled); err != nil {
+			log.WithError(err).Error("Failed to mark scheduled message as failed")
+			return
+		}
+		log.WithError(err).Warn("Scheduled message failed")
+		return
+	}
+
+	scheduled.ClaimedUntil = &retryAt
+	if err := s.repository.Schedule.Release(ctx, scheduled); err != nil {
+		log.WithError(err).Error("Failed to release scheduled message")
+		return
+	}
+	log.WithError(err).Warn("Scheduled message not sent, will retry")
+}
+
+// post posts the message on behalf of its author, with the same checks as if
+// they sent it now. The client message ID makes a repeated attempt return the
+// message posted by the first one.
+func (s *Scheduler) post(ctx context.Context, scheduled *models.ScheduledMessage) (*models.Message, error) {
+	if scheduled.User == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	return s.messages.AddMessage(auth.WithUser(ctx, scheduled.User), scheduled.ChatID, message.NewMessage{
+		Text:          scheduled.Text,
+		AttachmentIDs: scheduled.AttachmentIDs,
+		ClientMsgID:   scheduled.ClientMsgID,
+		ExpiresIn:     scheduled.ExpiresIn,
+	})
+}
+
+// permanent reports whether retrying err cannot help, e.g. because the chat
+// is gone, the message no longer passes validation or the author was banned
+// or muted.
+func permanent(err error) bool {
+	return errors.Is(err, auth.ErrUnauthenticated) ||
+		errors.Is(err, message.ErrEmptyText) ||
+		errors.Is(err, message.ErrTextTooLong) ||
+		errors.Is(err, message.ErrTooManyAttachments) ||
+		errors.Is(err, message.ErrAttachmentUnavailable) ||
+		errors.Is(err, message.ErrInvalidClientMsgID) ||
+		errors.Is(err, message.ErrClientMsgIDConflict) ||
+		errors.Is(err, message.ErrInvalidExpiresIn) ||
+		errors.Is(err, moderation.ErrBanned) ||
+		errors.Is(err, moderation.ErrMuted) ||
+		err.Error() == "chat does not exist"
+}
@@ -2,31 +2,182 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
+	"github.com/AlGrushino/chat/internal/events"
 	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service/attachment"
+	"github.com/AlGrushino/chat/internal/service/bot"
 	"github.com/AlGrushino/chat/internal/service/chat"
+	"github.com/AlGrushino/chat/internal/service/export"
+	"github.com/AlGrushino/chat/internal/service/hook"
+	"github.com/AlGrushino/chat/internal/service/idempotency"
+	"github.com/AlGrushino/chat/internal/service/invite"
+	"github.com/AlGrushino/chat/internal/service/loader"
+	"github.com/AlGrushino/chat/internal/service/mention"
 	"github.com/AlGrushino/chat/internal/service/message"
+	"github.com/AlGrushino/chat/internal/service/moderation"
+	"github.com/AlGrushino/chat/internal/service/pin"
+	"github.com/AlGrushino/chat/internal/service/retention"
+	"github.com/AlGrushino/chat/internal/service/schedule"
+	"github.com/AlGrushino/chat/internal/service/user"
+	"github.com/AlGrushino/chat/internal/service/webhook"
+	"github.com/AlGrushino/chat/pkg/ratelimit"
+	"github.com/AlGrushino/chat/pkg/storage"
 	"github.com/sirupsen/logrus"
 )
 
 type Chat interface {
-	CreateChat(ctx context.Context, title string) (string, error)
+	CreateChat(ctx context.Context, title string) (*models.Chat, error)
+	GetChat(ctx context.Context, id int) (*models.Chat, error)
 	DeleteChat(ctx context.Context, id int) error
+	CreateDirect(ctx context.Context, userID int) (chat.Summary, bool, error)
+	ListChats(ctx context.Context, limit, offset int) ([]chat.Summary, error)
+	SetSlowMode(ctx context.Context, id, seconds int) (*models.Chat, error)
+	SetRetention(ctx context.Context, id int, days *int) (*models.Chat, error)
+	UpdateChat(ctx context.Context, id int, update chat.Update) (*models.Chat, error)
+	ListPublic(ctx context.Context, query string, limit, offset int) ([]chat.Summary, error)
+	JoinChat(ctx context.Context, id int) (*models.Chat, bool, error)
+	LeaveChat(ctx context.Context, id int) error
+}
+
+type User interface {
+	Register(ctx context.Context, username string) (*models.User, string, error)
+	Authenticate(ctx context.Context, token string) (*models.User, error)
 }
 
 type Message interface {
-	AddMessage(ctx context.Context, id int, text string) (string, error)
+	AddMessage(ctx context.Context, id int, msg message.NewMessage) (*models.Message, error)
 	GetMessages(ctx context.Context, id, limit int) ([]string, error)
+	ListMessages(ctx context.Context, id, limit int) ([]*models.Message, error)
+	Subscribe(ctx context.Context, id int) (<-chan *models.Message, error)
+	SubscribeExpired(ctx context.Context, id int) (<-chan *models.Message, error)
+}
+
+type Attachment interface {
+	Upload(ctx context.Context, chatID int, filename string, r io.Reader) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, id int) (*models.Attachment, error)
+	Open(ctx context.Context, id int, thumbnail bool) (io.ReadCloser, string, error)
+}
+
+type Mention interface {
+	GetMentions(ctx context.Context, unreadOnly bool, limit, offset int) ([]*models.Mention, error)
+	MarkRead(ctx context.Context, upToID int) (int64, error)
+}
+
+type Webhook interface {
+	CreateSubscription(ctx context.Context, rawURL string, chatID *int, eventTypes []string) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int) error
+	GetDeliveries(ctx context.Context, id, limit, offset int) ([]*models.WebhookDelivery, error)
+}
+
+type Hook interface {
+	CreateHook(ctx context.Context, chatID int, name string, rateLimit int) (*models.IncomingHook, string, error)
+	ListHooks(ctx context.Context, chatID int) ([]*models.IncomingHook, error)
+	RotateHook(ctx context.Context, chatID, id int) (*models.IncomingHook, string, error)
+	RevokeHook(ctx context.Context, chatID, id int) error
+	Post(ctx context.Context, token, text string) (*models.Message, error)
+}
+
+type Bot interface {
+	CreateBot(ctx context.Context, username, callbackURL string, commands []bot.NewCommand) (*bot.Bot, string, error)
+	ListBots(ctx context.Context) ([]*bot.Bot, error)
+	DeleteBot(ctx context.Context, id int) error
+}
+
+type Export interface {
+	Transcript(ctx context.Context, chatID int, format string) (*export.Transcript, error)
+	CreateExport(ctx context.Context, chatID int, format string) (*models.ExportJob, error)
+	GetExport(ctx context.Context, id int) (*models.ExportJob, error)
+	OpenExport(ctx context.Context, id int) (io.ReadCloser, *models.ExportJob, error)
+}
+
+type Schedule interface {
+	ScheduleMessage(ctx context.Context, chatID int, msg message.NewMessage, sendAt time.Time) (*models.ScheduledMessage, error)
+	ListScheduled(ctx context.Context) ([]*models.ScheduledMessage, error)
+	CancelScheduled(ctx context.Context, id int) error
+}
+
+type Invite interface {
+	CreateInvite(ctx context.Context, chatID, expiresIn, maxUses int) (*models.ChatInvite, string, error)
+	ListInvites(ctx context.Context, chatID int) ([]*models.ChatInvite, error)
+	RevokeInvite(ctx context.Context, chatID, id int) error
+	AcceptInvite(ctx context.Context, token string) (*models.Chat, bool, error)
+}
+
+type Moderation interface {
+	Kick(ctx context.Context, chatID, userID int, reason string) error
+	Ban(ctx context.Context, chatID, userID int, reason string, expiresIn int) (*models.ChatRestriction, error)
+	Unban(ctx context.Context, chatID, userID int) error
+	Mute(ctx context.Context, chatID, userID int, reason string, expiresIn int) (*models.ChatRestriction, error)
+	Unmute(ctx context.Context, chatID, userID int) error
+	ListRestrictions(ctx context.Context, chatID int, kind string) ([]*models.ChatRestriction, error)
+	GetModerationLog(ctx context.Context, chatID, limit, offset int) ([]*models.ModerationAction, error)
 }
 
 type Service struct {
 	Chat
 	Message
+	Attachment
+	User
+	Mention
+	Webhook
+	Hook
+	Bot
+	Export
+	Schedule
+	Invite
+	Moderation
+
+	Events      *events.Bus
+	Limiter     ratelimit.Limiter
+	Idempotency *idempotency.IdempotencyService
+	Loaders     *loader.LoaderService
+	Pins        *pin.PinService
+	Thumbnails  *attachment.ThumbnailWorker
+	Webhooks    *webhook.Dispatcher
+	Exports     *export.Worker
+	Retention   *retention.Janitor
+	Sweeper     *retention.Sweeper
+	Scheduler   *schedule.Scheduler
 }
 
-func NewService(log *logrus.Logger, repository *repository.Repository) *Service {
+func NewService(log *logrus.Logger, repository *repository.Repository, store storage.BlobStore, limiter ratelimit.Limiter) *Service {
+	bus := events.NewBus(log)
+	thumbnails := attachment.NewThumbnailWorker(log, repository, store)
+
+	webhooks := webhook.NewWebhookService(log, repository)
+	bus.Handle(webhooks.HandleEvent)
+
+	messages := message.NewMessageService(log, repository, bus, limiter)
+	exports := export.NewWorker(log, repository, store)
+
 	return &Service{
-		Chat:    chat.NewChatService(log, repository),
-		Message: message.NewMessageService(log, repository),
+		Chat:        chat.NewChatService(log, repository, bus),
+		Message:     messages,
+		Attachment:  attachment.NewAttachmentService(log, repository, store, thumbnails),
+		User:        user.NewUserService(log, repository),
+		Mention:     mention.NewMentionService(log, repository),
+		Webhook:     webhooks,
+		Hook:        hook.NewHookService(log, repository, messages, limiter),
+		Bot:         bot.NewBotService(log, repository),
+		Export:      export.NewExportService(log, repository, store, exports),
+		Schedule:    schedule.NewScheduleService(log, repository),
+		Invite:      invite.NewInviteService(log, repository),
+		Moderation:  moderation.NewModerationService(log, repository),
+		Events:      bus,
+		Limiter:     limiter,
+		Idempotency: idempotency.NewIdempotencyService(log, repository, idempotency.DefaultTTL),
+		Loaders:     loader.NewLoaderService(log, repository),
+		Pins:        pin.NewPinService(log, repository, pin.DefaultMaxPins),
+		Thumbnails:  thumbnails,
+		Webhooks:    webhook.NewDispatcher(log, repository),
+		Exports:     exports,
+		Retention:   retention.NewJanitor(log, repository, store),
+		Sweeper:     retention.NewSweeper(log, repository, store, bus),
+		Scheduler:   schedule.NewScheduler(log, repository, messages),
 	}
 }
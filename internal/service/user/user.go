@@ -0,0 +1,91 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// UsernamePattern is shared by user and bot accounts.
+var UsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+var (
+	ErrInvalidUsername = errors.New("username must be 3-32 letters, digits or underscores")
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrUnauthorized    = errors.New("invalid token")
+)
+
+type UserService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewUserService(log *logrus.Logger, repository *repository.Repository) *UserService {
+	return &UserService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// Register creates a user and returns it together with its API token. Only a
+// hash of the token is stored, so this is the one chance to hand it out.
+func (s *UserService) Register(ctx context.Context, username string) (*models.User, string, error) {
+	username = strings.TrimSpace(username)
+	if !UsernamePattern.MatchString(username) {
+		s.log.Warnf("Register failed: invalid username %q", username)
+		return nil, "", ErrInvalidUsername
+	}
+
+	exists, err := s.repository.User.UsernameExists(ctx, username)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to check if username exists")
+		return nil, "", fmt.Errorf("failed to check username: %w", err)
+	}
+
+	if exists {
+		s.log.Warnf("Register failed: username %s already exists", username)
+		return nil, "", ErrUsernameTaken
+	}
+
+	token, err := auth.NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := models.User{
+		Username:  username,
+		TokenHash: auth.HashToken(token),
+	}
+
+	if err := s.repository.User.Create(ctx, &user); err != nil {
+		s.log.WithError(err).Error("Failed to create user in database")
+		return nil, "", fmt.Errorf("failed to create user: %w", err)
+	}
+
+	s.log.Infof("User registered successfully (ID: %d, Username: %q)", user.ID, username)
+	return &user, token, nil
+}
+
+func (s *UserService) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.repository.User.GetByTokenHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnauthorized
+		}
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	return user, nil
+}
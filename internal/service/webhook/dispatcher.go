@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MaxAttempts = 8
+
+	dispatchInterval = 2 * time.Second
+	dispatchBatch    = 20
+	dispatchLease    = time.Minute
+	requestTimeout   = 10 * time.Second
+	baseBackoff      = 10 * time.Second
+	maxBackoff       = time.Hour
+)
+
+// Dispatcher delivers queued webhook events. Deliveries are claimed with
+// SELECT ... FOR UPDATE SKIP LOCKED, so several instances can run it side by
+// side; failed attempts are retried with exponential backoff and moved to
+// the dead-letter table after MaxAttempts.
+type Dispatcher struct {
+	repository *repository.Repository
+	client     *http.Client
+	log        *logrus.Logger
+}
+
+func NewDispatcher(log *logrus.Logger, repository *repository.Repository) *Dispatcher {
+	return &Dispatcher{
+		repository: repository,
+		client:     &http.Client{Timeout: requestTimeout},
+		log:        log,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.Info("Webhook dispatcher started")
+	defer d.log.Info("Webhook dispatcher stopped")
+
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.dispatch(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	deliveries, err := d.repository.Webhook.ClaimDueDeliveries(ctx, dispatchBatch, dispatchLease)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.log.WithError(err).Error("Failed to claim webhook deliveries")
+		}
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	log := d.log.WithFields(logrus.Fields{
+		"delivery_id":     delivery.ID,
+		"subscription_id": delivery.SubscriptionID,
+		"event":           delivery.Event,
+	})
+
+	// The subscription was deleted after the claim; its deliveries go with it.
+	if delivery.Subscription == nil {
+		return
+	}
+
+	delivery.Attempts++
+
+	status, err := d.send(ctx, delivery.Subscription, delivery)
+
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	if err == nil {
+		if err := d.repository.Webhook.MarkDelivered(ctx, delivery); err != nil {
+			log.WithError(err).Error("Failed to mark webhook delivery as succeeded")
+			return
+		}
+		log.Info("Webhook delivered")
+		return
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	lastError := err.Error()
+	if len(lastError) > 1000 {
+		lastError = lastError[:1000]
+	}
+	delivery.LastError = &lastError
+
+	if delivery.Attempts >= MaxAttempts {
+		if err := d.repository.Webhook.MarkDead(ctx, delivery); err != nil {
+			log.WithError(err).Error("Failed to move webhook delivery to dead letters")
+			return
+		}
+		log.WithError(err).Warn("Webhook delivery moved to dead letters")
+		return
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(backoff(delivery.Attempts))
+	if err := d.repository.Webhook.ScheduleRetry(ctx, delivery); err != nil {
+		log.WithError(err).Error("Failed to schedule webhook retry")
+		return
+	}
+
+	log.WithError(err).Warnf("Webhook delivery failed, retry %d at %s", delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339))
+}
+
+// send POSTs the signed payload and returns the response status. Any status
+// outside 2xx counts as a failure.
+func (d *Dispatcher) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chat-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain a bounded amount so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt following the given one:
+// baseBackoff doubled per attempt, capped at maxBackoff, with up to 20% jitter.
+func backoff(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 20 {
+		delay = min(baseBackoff<<(attempt-1), maxBackoff)
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay) / 5))
+	return delay + jitter
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/events"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherSend_SignsPayload(t *testing.T) {
+	body, err := buildPayload(events.Event{
+		Type:       events.MessageCreated,
+		Chat:       &models.Chat{ID: 4, Title: "ci"},
+		Message:    &models.Message{ID: 9, ChatID: 4, Text: "build is green"},
+		OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	received := make(chan *http.Request, 1)
+	var receivedBody []byte
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	subscription := &models.WebhookSubscription{ID: 1, URL: receiver.URL, Secret: "s3cret", Active: true}
+	delivery := &models.WebhookDelivery{ID: 12, SubscriptionID: 1, Event: events.MessageCreated, Payload: string(body)}
+
+	dispatcher := NewDispatcher(logrus.New(), nil)
+	status, err := dispatcher.send(context.Background(), subscription, delivery)
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	r := <-received
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, events.MessageCreated, r.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, "12", r.Header.Get("X-Webhook-Delivery"))
+	assert.True(t, Verify("s3cret", receivedBody, r.Header.Get(SignatureHeader)))
+	assert.False(t, Verify("other", receivedBody, r.Header.Get(SignatureHeader)))
+	assert.JSONEq(t, `{
+		"event": "message.created",
+		"occurred_at": "2026-01-02T03:04:05Z",
+		"data": {"id": 9, "chat_id": 4, "text": "build is green", "created_at": "0001-01-01T00:00:00Z"}
+	}`, string(receivedBody))
+}
+
+func TestDispatcherSend_FailsOnErrorStatus(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	subscription := &models.WebhookSubscription{URL: receiver.URL, Secret: "s3cret"}
+	delivery := &models.WebhookDelivery{ID: 1, Event: events.ChatCreated, Payload: `{}`}
+
+	status, err := NewDispatcher(logrus.New(), nil).send(context.Background(), subscription, delivery)
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadGateway, status)
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		5:  160 * time.Second,
+		10: time.Hour,
+		50: time.Hour,
+	} {
+		got := backoff(attempt)
+		assert.GreaterOrEqual(t, got, want, "attempt %d", attempt)
+		assert.Less(t, got, want+want/5, "attempt %d", attempt)
+	}
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/events"
+)
+
+const SignatureHeader = "X-Webhook-Signature"
+
+// Sign returns the value of SignatureHeader for body: a hex HMAC-SHA256
+// keyed with the subscription secret, prefixed with the algorithm name.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a SignatureHeader value in constant time.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+type payload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+type chatData struct {
+	ID          int            `json:"id"`
+	Title       string         `json:"title,omitempty"`
+	Type        string         `json:"type,omitempty"`
+	Topic       string         `json:"topic,omitempty"`
+	Description string         `json:"description,omitempty"`
+	AvatarID    *int           `json:"avatar_id,omitempty"`
+	Visibility  string         `json:"visibility,omitempty"`
+	Settings    map[string]any `json:"settings,omitempty"`
+	CreatedAt   time.Time      `json:"created_at,omitzero"`
+}
+
+type messageData struct {
+	ID          int       `json:"id"`
+	ChatID      int       `json:"chat_id"`
+	AuthorID    *int      `json:"author_id,omitempty"`
+	ClientMsgID *string   `json:"client_msg_id,omitempty"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type expiredData struct {
+	ID     int `json:"id"`
+	ChatID int `json:"chat_id"`
+}
+
+func buildPayload(event events.Event) ([]byte, error) {
+	p := payload{
+		Event:      event.Type,
+		OccurredAt: event.OccurredAt,
+	}
+
+	switch {
+	case event.Type == events.MessageExpired:
+		p.Data = expiredData{ID: event.Message.ID, ChatID: event.Message.ChatID}
+	case event.Message != nil:
+		p.Data = messageData{
+			ID:          event.Message.ID,
+			ChatID:      event.Message.ChatID,
+			AuthorID:    event.Message.AuthorID,
+			ClientMsgID: event.Message.ClientMsgID,
+			Text:        event.Message.Text,
+			CreatedAt:   event.Message.CreatedAt,
+		}
+	case event.Type == events.ChatDeleted:
+		p.Data = chatData{ID: event.Chat.ID}
+	default:
+		p.Data = chatData{
+			ID:          event.Chat.ID,
+			Title:       event.Chat.Title,
+			Type:        event.Chat.Type,
+			Topic:       event.Chat.Topic,
+			Description: event.Chat.Description,
+			AvatarID:    event.Chat.AvatarID,
+			Visibility:  event.Chat.Visibility,
+			Settings:    event.Chat.Settings,
+			CreatedAt:   event.Chat.CreatedAt,
+		}
+	}
+
+	return json.Marshal(p)
+}
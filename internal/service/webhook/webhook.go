@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/events"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Events lists the event types a subscription may ask for.
+var Events = []string{events.MessageCreated, events.MessageExpired, events.ChatCreated, events.ChatUpdated, events.ChatDeleted}
+
+var (
+	ErrNotFound      = errors.New("webhook does not exist")
+	ErrChatNotFound  = errors.New("chat does not exist")
+	ErrInvalidURL    = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidEvents = errors.New("webhook events must be a non-empty list of known events")
+)
+
+type WebhookService struct {
+	repository *repository.Repository
+	log        *logrus.Logger
+}
+
+func NewWebhookService(log *logrus.Logger, repository *repository.Repository) *WebhookService {
+	return &WebhookService{
+		repository: repository,
+		log:        log,
+	}
+}
+
+// CreateSubscription registers a webhook for the caller. A nil chatID
+// subscribes to events from every public group chat; private chats can only
+// be subscribed to one by one, by their owner and members.
+func (s *WebhookService) CreateSubscription(ctx context.Context, rawURL string, chatID *int, eventTypes []string) (*models.WebhookSubscription, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(rawURL) > 2000 {
+		return nil, ErrInvalidURL
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidEvents
+	}
+
+	for _, eventType := range eventTypes {
+		if !slices.Contains(Events, eventType) {
+			return nil, ErrInvalidEvents
+		}
+	}
+
+	if chatID != nil {
+		chat, err := s.repository.Chat.GetByID(ctx, *chatID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrChatNotFound
+			}
+			return nil, fmt.Errorf("failed to get chat with id: %d", *chatID)
+		}
+
+		// Direct chats never produce webhook events.
+		if chat.IsDirect() {
+			return nil, ErrChatNotFound
+		}
+
+		allowed, err := auth.CanAccess(ctx, s.repository.Member, chat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check chat access: %w", err)
+		}
+
+		if !allowed {
+			return nil, ErrChatNotFound
+		}
+	}
+
+	secret, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := models.WebhookSubscription{
+		UserID: user.ID,
+		ChatID: chatID,
+		URL:    rawURL,
+		Secret: secret,
+		Events: slices.Compact(slices.Sorted(slices.Values(eventTypes))),
+		Active: true,
+	}
+
+	if err := s.repository.Webhook.CreateSubscription(ctx, &subscription); err != nil {
+		s.log.WithError(err).Error("Failed to create webhook subscription in database")
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.log.Infof("Webhook created successfully (ID: %d, URL: %q)", subscription.ID, parsed.Redacted())
+	return &subscription, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	subscriptions, err := s.repository.Webhook.GetSubscriptionsByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int) error {
+	if _, err := s.ownSubscription(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repository.Webhook.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the delivery log of one of the caller's webhooks,
+// newest first.
+func (s *WebhookService) GetDeliveries(ctx context.Context, id, limit, offset int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.ownSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	if limit > 100 {
+		return nil, fmt.Errorf("limit is too big: %d", limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := s.repository.Webhook.GetDeliveries(ctx, id, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// HandleEvent queues a delivery for every subscription interested in event.
+// It is registered on the event bus and runs inside the producing request.
+func (s *WebhookService) HandleEvent(ctx context.Context, event events.Event) {
+	if event.Chat == nil || event.Chat.IsDirect() {
+		return
+	}
+
+	log := s.log.WithFields(logrus.Fields{
+		"event":   event.Type,
+		"chat_id": event.Chat.ID,
+	})
+
+	if event.Type == events.ChatDeleted {
+		defer func() {
+			if err := s.repository.Webhook.DeactivateForChat(ctx, event.Chat.ID); err != nil {
+				log.WithError(err).Error("Failed to deactivate webhooks of deleted chat")
+			}
+		}()
+	}
+
+	subscriptions, err := s.repository.Webhook.GetSubscriptionsForEvent(ctx, event.Type, event.Chat.ID)
+	if err != nil {
+		log.WithError(err).Error("Failed to load webhook subscriptions")
+		return
+	}
+
+	subscriptions, err = s.receivers(ctx, event.Chat, subscriptions)
+	if err != nil {
+		log.WithError(err).Error("Failed to check webhook subscribers' chat access")
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	body, err := buildPayload(event)
+	if err != nil {
+		log.WithError(err).Error("Failed to build webhook payload")
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			Event:          event.Type,
+			Payload:        string(body),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+		})
+	}
+
+	if err := s.repository.Webhook.CreateDeliveries(ctx, deliveries); err != nil {
+		log.WithError(err).Error("Failed to queue webhook deliveries")
+		return
+	}
+
+	log.Infof("Queued %d webhook deliveries", len(deliveries))
+}
+
+// receivers keeps the subscriptions whose owners may read chat. Global
+// subscriptions never receive events of private chats, and subscriptions to
+// a private chat stop receiving them once their owner leaves it.
+func (s *WebhookService) receivers(ctx context.Context, chat *models.Chat, subscriptions []*models.WebhookSubscription) ([]*models.WebhookSubscription, error) {
+	if chat.Visibility != models.VisibilityPrivate {
+		return subscriptions, nil
+	}
+
+	allowed := make([]*models.WebhookSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.ChatID == nil {
+			continue
+		}
+
+		ok, err := auth.CanAccess(auth.WithUser(ctx, &models.User{ID: subscription.UserID}), s.repository.Member, chat)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			allowed = append(allowed, subscription)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (s *WebhookService) ownSubscription(ctx context.Context, id int) (*models.WebhookSubscription, error) {
+	user := auth.UserFromContext(ctx)
+	if user == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	subscription, err := s.repository.Webhook.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook with id: %d", id)
+	}
+
+	if subscription.UserID != user.ID {
+		return nil, ErrNotFound
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlGrushino/chat/internal/auth"
+	"github.com/AlGrushino/chat/internal/events"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var privateChat = testutil.PrivateChat("board")
+
+type fakeWebhooks struct {
+	repository.Webhook
+
+	subscriptions []*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+}
+
+func (f *fakeWebhooks) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	subscription.ID = len(f.subscriptions) + 1
+	f.subscriptions = append(f.subscriptions, subscription)
+	return nil
+}
+
+func (f *fakeWebhooks) GetSubscriptionsForEvent(ctx context.Context, event string, chatID int) ([]*models.WebhookSubscription, error) {
+	return f.subscriptions, nil
+}
+
+func (f *fakeWebhooks) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	f.deliveries = append(f.deliveries, deliveries...)
+	return nil
+}
+
+func newService() (*WebhookService, *fakeWebhooks) {
+	webhooks := &fakeWebhooks{}
+	return NewWebhookService(logrus.New(), &repository.Repository{
+		Chat:    testutil.NewChats(privateChat),
+		Member:  testutil.NewMembers(testutil.Stranger.ID),
+		Webhook: webhooks,
+	}), webhooks
+}
+
+func TestCreateSubscription_PrivateChat(t *testing.T) {
+	s, _ := newService()
+	chatID := privateChat.ID
+
+	for _, userID := range []int{testutil.Owner.ID, testutil.Stranger.ID} {
+		ctx := auth.WithUser(context.Background(), &models.User{ID: userID})
+		_, err := s.CreateSubscription(ctx, "https://example.com/hook", &chatID, []string{events.MessageCreated})
+		require.NoError(t, err)
+	}
+
+	outsider := auth.WithUser(context.Background(), &models.User{ID: 9})
+	_, err := s.CreateSubscription(outsider, "https://example.com/hook", &chatID, []string{events.MessageCreated})
+	assert.ErrorIs(t, err, ErrChatNotFound)
+}
+
+func TestHandleEvent_PrivateChat(t *testing.T) {
+	s, webhooks := newService()
+	chatID := privateChat.ID
+
+	webhooks.subscriptions = []*models.WebhookSubscription{
+		{ID: 1, UserID: 9}, // global
+		{ID: 2, UserID: testutil.Owner.ID, ChatID: &chatID},
+		{ID: 3, UserID: testutil.Stranger.ID, ChatID: &chatID},
+		{ID: 4, UserID: 9, ChatID: &chatID}, // left the chat
+	}
+
+	s.HandleEvent(context.Background(), events.Event{
+		Type:    events.MessageCreated,
+		Chat:    privateChat,
+		Message: &models.Message{ID: 1, ChatID: chatID, Text: "salaries"},
+	})
+
+	ids := make([]int, 0, len(webhooks.deliveries))
+	for _, delivery := range webhooks.deliveries {
+		ids = append(ids, delivery.SubscriptionID)
+	}
+	assert.Equal(t, []int{2, 3}, ids)
+}
@@ -0,0 +1,160 @@
+// Package testutil holds the users and in-memory repositories that tests of
+// several packages share. Fakes embed the repository interface they stand in
+// for, so calling a method they do not implement panics.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/pkg/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	// Owner owns the chats of GroupChat and PrivateChat.
+	Owner = &models.User{ID: 7, Username: "alice"}
+	// Stranger is any other user.
+	Stranger = &models.User{ID: 8, Username: "bob"}
+)
+
+// GroupChat returns a public group chat with ID 1 owned by Owner.
+func GroupChat(title string) *models.Chat {
+	return &models.Chat{ID: 1, Title: title, Type: models.ChatTypeGroup, Visibility: models.VisibilityPublic, OwnerID: &Owner.ID}
+}
+
+// PrivateChat is GroupChat made private.
+func PrivateChat(title string) *models.Chat {
+	chat := GroupChat(title)
+	chat.Visibility = models.VisibilityPrivate
+	return chat
+}
+
+// Chats is a chat repository holding a fixed set of chats.
+type Chats struct {
+	repository.Chat
+
+	chats map[int]*models.Chat
+}
+
+func NewChats(chats ...*models.Chat) *Chats {
+	f := &Chats{chats: make(map[int]*models.Chat, len(chats))}
+	for _, chat := range chats {
+		f.chats[chat.ID] = chat
+	}
+	return f
+}
+
+// GetByID returns a copy of the chat, so callers cannot change the fixture.
+func (f *Chats) GetByID(ctx context.Context, id int) (*models.Chat, error) {
+	chat, ok := f.chats[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *chat
+	return &found, nil
+}
+
+// Members is a member repository in which the given users are members of
+// every chat.
+type Members struct {
+	repository.Member
+
+	members map[int]bool
+}
+
+func NewMembers(userIDs ...int) *Members {
+	f := &Members{members: make(map[int]bool, len(userIDs))}
+	for _, id := range userIDs {
+		f.members[id] = true
+	}
+	return f
+}
+
+func (f *Members) IsMember(ctx context.Context, chatID, userID int) (bool, error) {
+	return f.members[userID], nil
+}
+
+// Users is a user repository holding a fixed set of users.
+type Users struct {
+	repository.User
+
+	users []*models.User
+}
+
+func NewUsers(users ...*models.User) *Users {
+	return &Users{users: users}
+}
+
+func (f *Users) GetByID(ctx context.Context, id int) (*models.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *Users) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	var found []*models.User
+	for _, u := range f.users {
+		for _, name := range usernames {
+			if u.Username == name {
+				found = append(found, u)
+			}
+		}
+	}
+	return found, nil
+}
+
+// BlobStore is an in-memory storage.BlobStore. Blobs may be read and set
+// directly while no call is running.
+type BlobStore struct {
+	mu    sync.Mutex
+	Blobs map[string][]byte
+}
+
+func NewBlobStore(blobs map[string][]byte) *BlobStore {
+	if blobs == nil {
+		blobs = map[string][]byte{}
+	}
+	return &BlobStore{Blobs: blobs}
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Blobs[key] = data
+	return nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.Blobs[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.Blobs[key]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.Blobs, key)
+	return nil
+}
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chats
+    ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'group'
+        CHECK (type IN ('group', 'direct')),
+    ADD COLUMN dm_key VARCHAR(50) UNIQUE,
+    ADD CONSTRAINT chats_dm_key_check
+        CHECK ((type = 'direct') = (dm_key IS NOT NULL));
+
+CREATE TABLE chat_members (
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX idx_chat_members_user_id ON chat_members(user_id);
+
+ALTER TABLE messages
+    ADD COLUMN author_id INT REFERENCES users(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages DROP COLUMN IF EXISTS author_id;
+DROP TABLE IF EXISTS chat_members CASCADE;
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chats_dm_key_check,
+    DROP COLUMN IF EXISTS dm_key,
+    DROP COLUMN IF EXISTS type;
+-- +goose StatementEnd
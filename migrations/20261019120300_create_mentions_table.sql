@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mentions (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    message_id INT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    "offset" INT NOT NULL CHECK ("offset" >= 0),
+    length INT NOT NULL CHECK (length > 0),
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_mentions_message_id ON mentions(message_id);
+CREATE INDEX idx_mentions_user_id ON mentions(user_id, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS mentions CASCADE;
+-- +goose StatementEnd
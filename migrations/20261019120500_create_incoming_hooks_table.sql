@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE incoming_hooks (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(80) NOT NULL CHECK (LENGTH(name) BETWEEN 1 AND 80),
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    rate_limit INT NOT NULL DEFAULT 30 CHECK (rate_limit BETWEEN 1 AND 600),
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_incoming_hooks_chat_id ON incoming_hooks(chat_id);
+
+ALTER TABLE messages
+    ADD COLUMN author_name VARCHAR(80);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages DROP COLUMN IF EXISTS author_name;
+DROP TABLE IF EXISTS incoming_hooks CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN owner_id INT REFERENCES users(id) ON DELETE CASCADE,
+    ADD COLUMN callback_url VARCHAR(2000),
+    ADD COLUMN callback_secret VARCHAR(64),
+    ADD CONSTRAINT users_bot_owner_check CHECK (is_bot = (owner_id IS NOT NULL));
+
+CREATE TABLE bot_commands (
+    name VARCHAR(32) PRIMARY KEY
+        CHECK (name ~ '^[a-z0-9_-]{1,32}$'),
+    bot_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    description VARCHAR(200) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_bot_commands_bot_id ON bot_commands(bot_id);
+
+ALTER TABLE chats
+    ADD COLUMN topic VARCHAR(250) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chats DROP COLUMN IF EXISTS topic;
+DROP TABLE IF EXISTS bot_commands CASCADE;
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_bot_owner_check,
+    DROP COLUMN IF EXISTS callback_secret,
+    DROP COLUMN IF EXISTS callback_url,
+    DROP COLUMN IF EXISTS owner_id,
+    DROP COLUMN IF EXISTS is_bot;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+ALTER TABLE chats
+    ADD COLUMN owner_id INT REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN slow_mode_seconds INT NOT NULL DEFAULT 0
+        CHECK (slow_mode_seconds BETWEEN 0 AND 3600);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS slow_mode_seconds,
+    DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS rate_limit_buckets CASCADE;
+-- +goose StatementEnd
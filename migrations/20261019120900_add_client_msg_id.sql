@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages
+    ADD COLUMN client_msg_id UUID;
+
+CREATE UNIQUE INDEX uq_messages_chat_client_msg_id
+    ON messages(chat_id, client_msg_id)
+    WHERE client_msg_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS uq_messages_chat_client_msg_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS client_msg_id;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE export_jobs (
+    id SERIAL PRIMARY KEY,
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    claimed_until TIMESTAMPTZ,
+    blob_key VARCHAR(255),
+    size BIGINT NOT NULL DEFAULT 0,
+    messages INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1000),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_export_jobs_chat_id ON export_jobs(chat_id);
+CREATE INDEX idx_export_jobs_user_id ON export_jobs(user_id);
+CREATE INDEX idx_export_jobs_pending ON export_jobs(created_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS export_jobs CASCADE;
+-- +goose StatementEnd
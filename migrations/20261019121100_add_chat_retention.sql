@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chats ADD COLUMN retention_days INT CHECK (retention_days >= 0);
+
+CREATE INDEX idx_messages_chat_id_created_at ON messages(chat_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_chat_id_created_at;
+
+ALTER TABLE chats DROP COLUMN IF EXISTS retention_days;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE scheduled_messages (
+    id SERIAL PRIMARY KEY,
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    text VARCHAR(5000) NOT NULL,
+    attachment_ids JSONB NOT NULL DEFAULT '[]',
+    client_msg_id UUID NOT NULL,
+    expires_in INT NOT NULL DEFAULT 0,
+    send_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    claimed_until TIMESTAMPTZ,
+    message_id INT REFERENCES messages(id) ON DELETE SET NULL,
+    last_error VARCHAR(1000),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_scheduled_messages_chat_id ON scheduled_messages(chat_id);
+CREATE INDEX idx_scheduled_messages_user_id ON scheduled_messages(user_id);
+CREATE INDEX idx_scheduled_messages_pending ON scheduled_messages(send_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scheduled_messages CASCADE;
+-- +goose StatementEnd
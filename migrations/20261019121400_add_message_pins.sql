@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE messages
+    ADD COLUMN pinned_at TIMESTAMPTZ,
+    ADD COLUMN pinned_by_id INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_messages_pinned ON messages(chat_id, pinned_at) WHERE pinned_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_pinned;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS pinned_by_id,
+    DROP COLUMN IF EXISTS pinned_at;
+-- +goose StatementEnd
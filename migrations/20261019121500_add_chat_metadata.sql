@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chats
+    ADD COLUMN description VARCHAR(1000) NOT NULL DEFAULT '',
+    ADD COLUMN avatar_id INT REFERENCES attachments(id) ON DELETE SET NULL,
+    ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'private')),
+    ADD COLUMN settings JSONB NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS settings,
+    DROP COLUMN IF EXISTS visibility,
+    DROP COLUMN IF EXISTS avatar_id,
+    DROP COLUMN IF EXISTS description;
+-- +goose StatementEnd
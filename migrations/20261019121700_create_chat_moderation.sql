@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE chat_restrictions (
+    id SERIAL PRIMARY KEY,
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(8) NOT NULL CHECK (kind IN ('ban', 'mute')),
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    moderator_id INT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT idx_chat_restrictions_chat_user_kind UNIQUE (chat_id, user_id, kind)
+);
+
+CREATE TABLE moderation_actions (
+    id SERIAL PRIMARY KEY,
+    chat_id INT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    moderator_id INT REFERENCES users(id) ON DELETE SET NULL,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(8) NOT NULL CHECK (action IN ('kick', 'ban', 'unban', 'mute', 'unmute')),
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_moderation_actions_chat_id ON moderation_actions(chat_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS moderation_actions CASCADE;
+DROP TABLE IF EXISTS chat_restrictions CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+)
+
+func (c *Client) CreateChat(ctx context.Context, title string) (*CreateChatResponse, error) {
+	var resp CreateChatResponse
+	if err := c.do(ctx, http.MethodPost, "/chats", models.CreateChat{Title: title}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListChats lists group chats and the caller's direct chats, newest first. A
+// zero limit uses the server default.
+func (c *Client) ListChats(ctx context.Context, limit, offset int) ([]Chat, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	path := "/chats"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp models.ListChatsResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Chats, nil
+}
+
+func (c *Client) DeleteChat(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/chats/%d/delete", id), nil, nil)
+}
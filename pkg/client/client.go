@@ -0,0 +1,229 @@
+// Package client is a Go client for the chat HTTP API. Requests and responses
+// use the same DTOs as the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
+	"github.com/AlGrushino/chat/internal/handlers/models"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 200 * time.Millisecond
+
+	// maxRetryAfter caps how long a retry waits for a Retry-After header.
+	maxRetryAfter = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken authenticates requests with a user or bot token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient replaces http.DefaultClient. Subscribe keeps its request
+// open, so the client should not set an overall Timeout; use contexts
+// instead.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the
+// initial delay between attempts, which doubles after every attempt. Zero
+// retries disables retrying.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(parsed.String(), "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+type idempotencyKeyContext struct{}
+
+// WithIdempotencyKey makes the create call made with ctx use key instead of a
+// generated one, so that it is also safe to repeat across processes.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContext{}, key)
+}
+
+// do sends a request to an endpoint of the current API version and decodes a
+// JSON response into out, if out is not nil. Failed requests are retried, so
+// POST endpoints used with do must honor the Idempotency-Key header.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var header http.Header
+	if method == http.MethodPost {
+		key, ok := ctx.Value(idempotencyKeyContext{}).(string)
+		if !ok {
+			key = newIdempotencyKey()
+		}
+		header = http.Header{middleware.IdempotencyKeyHeader: []string{key}}
+	}
+
+	resp, err := c.request(ctx, method, models.APIPrefix+path, body, header, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// request sends a request and returns the response if its status is
+// successful. The caller closes the body. With retry set, network errors,
+// server errors, rate limiting and requests still in progress are retried.
+func (c *Client) request(ctx context.Context, method, path string, body any, header http.Header, retry bool) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload, header)
+
+		var apiErr *APIError
+		retryable := err != nil && ctx.Err() == nil
+		if errors.As(err, &apiErr) {
+			retryable = apiErr.StatusCode == http.StatusTooManyRequests ||
+				apiErr.StatusCode >= http.StatusInternalServerError ||
+				errors.Is(apiErr, ErrRequestInProgress)
+		}
+
+		if !retry || !retryable || attempt >= c.maxRetries {
+			return resp, err
+		}
+
+		delay := c.backoff << attempt
+		delay += rand.N(delay/2 + 1)
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			delay = min(apiErr.RetryAfter, maxRetryAfter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, newAPIError(resp.StatusCode, strings.TrimSpace(string(message)), retryAfter(resp.Header))
+	}
+
+	return resp, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date.
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
+
+// newIdempotencyKey returns a random UUID.
+func newIdempotencyKey() string {
+	var b [16]byte
+	_, _ = cryptorand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
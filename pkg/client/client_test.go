@@ -0,0 +1,305 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers"
+	"github.com/AlGrushino/chat/internal/handlers/middleware"
+	"github.com/AlGrushino/chat/internal/repository"
+	"github.com/AlGrushino/chat/internal/repository/models"
+	"github.com/AlGrushino/chat/internal/service"
+	chatService "github.com/AlGrushino/chat/internal/service/chat"
+	"github.com/AlGrushino/chat/internal/service/idempotency"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+	"github.com/AlGrushino/chat/internal/testutil"
+	"github.com/AlGrushino/chat/pkg/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeChats struct {
+	service.Chat
+
+	mu      sync.Mutex
+	created []string
+	deleted []int
+}
+
+func (f *fakeChats) CreateChat(ctx context.Context, title string) (*models.Chat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existing := range f.created {
+		if existing == title {
+			return nil, chatService.ErrChatExists
+		}
+	}
+	f.created = append(f.created, title)
+	return &models.Chat{ID: len(f.created), Title: title}, nil
+}
+
+func (f *fakeChats) DeleteChat(ctx context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id > len(f.created) {
+		return errors.New("chat does not exist")
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeMessages struct {
+	service.Message
+}
+
+func (f *fakeMessages) AddMessage(ctx context.Context, id int, msg messageService.NewMessage) (*models.Message, error) {
+	if id != 1 {
+		return nil, errors.New("chat does not exist")
+	}
+	return &models.Message{ID: 10, ChatID: id, Text: msg.Text}, nil
+}
+
+func (f *fakeMessages) ListMessages(ctx context.Context, id, limit int) ([]*models.Message, error) {
+	if id != 1 {
+		return nil, errors.New("chat does not exist")
+	}
+	return []*models.Message{{ID: 10, ChatID: id, Text: "hello"}}, nil
+}
+
+type fakeUsers struct {
+	service.User
+}
+
+func (f *fakeUsers) Authenticate(ctx context.Context, token string) (*models.User, error) {
+	return testutil.Owner, nil
+}
+
+// memoryKeys is an in-memory idempotency key repository.
+type memoryKeys struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyKey
+}
+
+func (m *memoryKeys) Reserve(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.records[record.Scope+record.Key]; ok {
+		return false, nil
+	}
+	m.records[record.Scope+record.Key] = *record
+	return true, nil
+}
+
+func (m *memoryKeys) Get(ctx context.Context, scope, key string) (*models.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[scope+key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (m *memoryKeys) Complete(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := m.records[scope+key]
+	record.Status, record.ContentType, record.Body = status, contentType, body
+	m.records[scope+key] = record
+	return nil
+}
+
+func (m *memoryKeys) Release(ctx context.Context, scope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, scope+key)
+	return nil
+}
+
+func (m *memoryKeys) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// newServer serves the real router on top of fake services. wrap, if set,
+// sees every request before the router.
+func newServer(t *testing.T, chats *fakeChats, wrap func(http.Handler) http.Handler) *httptest.Server {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	keys := &repository.Repository{Idempotency: &memoryKeys{records: map[string]models.IdempotencyKey{}}}
+	h := handlers.NewHandler(&service.Service{
+		Chat:        chats,
+		Message:     &fakeMessages{},
+		User:        &fakeUsers{},
+		Idempotency: idempotency.NewIdempotencyService(log, keys, time.Hour),
+	}, log)
+	h.InitRoutes()
+
+	router := h.Router()
+	if wrap != nil {
+		router = wrap(router)
+	}
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(t *testing.T, server *httptest.Server) *client.Client {
+	c, err := client.New(server.URL, client.WithToken("secret"), client.WithRetries(3, time.Millisecond))
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_RoundTrip(t *testing.T) {
+	chats := &fakeChats{}
+	c := newClient(t, newServer(t, chats, nil))
+	ctx := context.Background()
+
+	created, err := c.CreateChat(ctx, "general")
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.ID)
+
+	msg, err := c.AddMessage(ctx, created.ID, client.NewMessage{Text: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", msg.Text)
+
+	texts, err := c.GetMessages(ctx, created.ID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, texts)
+
+	require.NoError(t, c.DeleteChat(ctx, created.ID))
+	assert.Equal(t, []int{1}, chats.deleted)
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	c := newClient(t, newServer(t, &fakeChats{}, nil))
+	ctx := context.Background()
+
+	_, err := c.AddMessage(ctx, 2, client.NewMessage{Text: "hello"})
+	assert.ErrorIs(t, err, client.ErrChatNotFound)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	_, err = c.GetMessages(ctx, 2, 10)
+	assert.ErrorIs(t, err, client.ErrChatNotFound)
+
+	assert.ErrorIs(t, c.DeleteChat(ctx, 2), client.ErrChatNotFound)
+
+	_, err = c.CreateChat(ctx, "general")
+	require.NoError(t, err)
+	_, err = c.CreateChat(ctx, "general")
+	assert.ErrorIs(t, err, client.ErrChatExists)
+	assert.ErrorIs(t, err, client.ErrConflict)
+}
+
+func TestClient_RetriesWithSameIdempotencyKey(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+
+	// The first request fails before reaching the router, the second succeeds
+	// but its response is lost, the third gets the stored response.
+	chats := &fakeChats{}
+	server := newServer(t, chats, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			keys = append(keys, r.Header.Get(middleware.IdempotencyKeyHeader))
+			attempt := len(keys)
+			mu.Unlock()
+
+			switch attempt {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			case 2:
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				conn, _, err := http.NewResponseController(w).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+
+	created, err := newClient(t, server).CreateChat(context.Background(), "general")
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.ID)
+
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+	assert.Equal(t, []string{"general"}, chats.created)
+}
+
+func TestClient_CallerIdempotencyKey(t *testing.T) {
+	var key string
+	server := newServer(t, &fakeChats{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key = r.Header.Get(middleware.IdempotencyKeyHeader)
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(t, server)
+	ctx := client.WithIdempotencyKey(context.Background(), "create-general")
+
+	first, err := c.CreateChat(ctx, "general")
+	require.NoError(t, err)
+	assert.Equal(t, "create-general", key)
+
+	// Repeating the call replays the first response instead of conflicting.
+	again, err := c.CreateChat(ctx, "general")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+}
+
+func TestClient_GivesUpAfterRetries(t *testing.T) {
+	attempts := 0
+	server := newServer(t, &fakeChats{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		})
+	})
+
+	_, err := newClient(t, server).ListChats(context.Background(), 0, 0)
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestClient_ContextCanceled(t *testing.T) {
+	server := newServer(t, &fakeChats{}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		})
+	})
+
+	c, err := client.New(server.URL, client.WithRetries(5, time.Hour))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.CreateChat(ctx, "general")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
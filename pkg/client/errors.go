@@ -0,0 +1,99 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	idempotencyService "github.com/AlGrushino/chat/internal/service/idempotency"
+	messageService "github.com/AlGrushino/chat/internal/service/message"
+)
+
+// Errors by response status. Every *APIError matches one of them with
+// errors.Is.
+var (
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrUnprocessable   = errors.New("unprocessable request")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrServer          = errors.New("server error")
+)
+
+// Domain errors of the server. Each also matches its status error, e.g.
+// errors.Is(ErrChatNotFound, ErrNotFound) holds.
+var (
+	ErrChatNotFound          = fmt.Errorf("%w: chat does not exist", ErrNotFound)
+	ErrChatExists            = fmt.Errorf("%w: chat already exists", ErrConflict)
+	ErrClientMsgIDConflict   = fmt.Errorf("%w: %s", ErrConflict, messageService.ErrClientMsgIDConflict)
+	ErrAttachmentUnavailable = fmt.Errorf("%w: %s", ErrUnprocessable, messageService.ErrAttachmentUnavailable)
+	ErrIdempotencyKeyReused  = fmt.Errorf("%w: %s", ErrUnprocessable, idempotencyService.ErrKeyReused)
+	ErrRequestInProgress     = fmt.Errorf("%w: %s", ErrConflict, idempotencyService.ErrInProgress)
+)
+
+// domainErrors maps the status and body of error responses to domain errors.
+var domainErrors = map[int]map[string]error{
+	http.StatusNotFound: {
+		"Chat does not exist": ErrChatNotFound,
+	},
+	http.StatusConflict: {
+		"Chat already exists":                         ErrChatExists,
+		messageService.ErrClientMsgIDConflict.Error(): ErrClientMsgIDConflict,
+		idempotencyService.ErrInProgress.Error():      ErrRequestInProgress,
+	},
+	http.StatusUnprocessableEntity: {
+		"Attachment does not exist or is already used": ErrAttachmentUnavailable,
+		idempotencyService.ErrKeyReused.Error():        ErrIdempotencyKeyReused,
+	},
+}
+
+// APIError is a response with an error status. Message is the body the
+// server sent; RetryAfter is set when the server asked to wait.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+
+	err error
+}
+
+func newAPIError(status int, message string, retryAfter time.Duration) *APIError {
+	e := &APIError{StatusCode: status, Message: message, RetryAfter: retryAfter}
+
+	if err, ok := domainErrors[status][message]; ok {
+		e.err = err
+		return e
+	}
+
+	switch {
+	case status == http.StatusUnauthorized:
+		e.err = ErrUnauthenticated
+	case status == http.StatusForbidden:
+		e.err = ErrForbidden
+	case status == http.StatusNotFound:
+		e.err = ErrNotFound
+	case status == http.StatusConflict:
+		e.err = ErrConflict
+	case status == http.StatusUnprocessableEntity:
+		e.err = ErrUnprocessable
+	case status == http.StatusTooManyRequests:
+		e.err = ErrRateLimited
+	case status >= http.StatusInternalServerError:
+		e.err = ErrServer
+	default:
+		e.err = ErrInvalidRequest
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("chat API: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
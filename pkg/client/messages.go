@@ -0,0 +1,138 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+)
+
+// AddMessage posts a message. Slash command replies that are only shown to
+// the sender come back with Ephemeral set and no ID.
+func (c *Client) AddMessage(ctx context.Context, chatID int, msg NewMessage) (*Message, error) {
+	var resp Message
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/chats/%d/messages", chatID), msg, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMessages returns the texts of the latest limit messages, oldest first.
+func (c *Client) GetMessages(ctx context.Context, chatID, limit int) ([]string, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var resp models.GetMessagesResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/chats/%d?limit=%d", chatID, limit), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+const subscribeQuery = `subscription($chatId: ID!) {
+	messageAdded(chatId: $chatId) { id chatId text authorName createdAt author { id username } }
+}`
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type liveMessage struct {
+	ID         string    `json:"id"`
+	ChatID     string    `json:"chatId"`
+	Text       string    `json:"text"`
+	AuthorName *string   `json:"authorName"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Author     *struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// Subscribe calls handle for every message posted to the chat until ctx is
+// done or the server ends the stream, e.g. because the chat was deleted. It
+// uses the GraphQL endpoint's event stream.
+func (c *Client) Subscribe(ctx context.Context, chatID int, handle func(LiveMessage)) error {
+	body := graphQLRequest{
+		Query:     subscribeQuery,
+		Variables: map[string]any{"chatId": strconv.Itoa(chatID)},
+	}
+	header := http.Header{"Accept": []string{"text/event-stream"}}
+
+	resp, err := c.request(ctx, http.MethodPost, "/graphql", body, header, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			continue
+		}
+
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || event != "next" {
+			continue
+		}
+
+		var result struct {
+			Data struct {
+				MessageAdded *liveMessage `json:"messageAdded"`
+			} `json:"data"`
+			Errors []graphQLError `json:"errors"`
+		}
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			return errors.New("chat API: " + result.Errors[0].Message)
+		}
+		if result.Data.MessageAdded != nil {
+			handle(result.Data.MessageAdded.convert())
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (m *liveMessage) convert() LiveMessage {
+	msg := LiveMessage{
+		Text:      m.Text,
+		CreatedAt: m.CreatedAt,
+	}
+	msg.ID, _ = strconv.Atoi(m.ID)
+	msg.ChatID, _ = strconv.Atoi(m.ChatID)
+
+	if m.AuthorName != nil {
+		msg.AuthorName = *m.AuthorName
+	}
+	if m.Author != nil {
+		id, _ := strconv.Atoi(m.Author.ID)
+		msg.Author = &User{ID: id, Username: m.Author.Username}
+	}
+
+	return msg
+}
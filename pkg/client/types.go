@@ -0,0 +1,38 @@
+package client
+
+import (
+	"time"
+
+	"github.com/AlGrushino/chat/internal/handlers/models"
+)
+
+// The request and response types are the server's DTOs, re-exported so that
+// code outside this module can name them.
+type (
+	Chat               = models.ChatInfo
+	User               = models.UserInfo
+	CreateChatResponse = models.CreateChatReposnse
+	NewMessage         = models.CreateMessage
+	Message            = models.CreateMessageResponse
+	Attachment         = models.Attachment
+	Mention            = models.Mention
+)
+
+// LiveMessage is a message delivered by Subscribe.
+type LiveMessage struct {
+	ID         int       `json:"id"`
+	ChatID     int       `json:"chat_id"`
+	Text       string    `json:"text"`
+	Author     *User     `json:"author,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Sender returns the username of the author, or the display name of authors
+// without an account.
+func (m *LiveMessage) Sender() string {
+	if m.Author != nil {
+		return m.Author.Username
+	}
+	return m.AuthorName
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+
+	// DefaultRoute is the key of the limit applied to routes without one.
+	DefaultRoute = "default"
+)
+
+// DefaultRoutes are used when RATE_LIMITS is not set.
+var DefaultRoutes = map[string]Limit{
+	DefaultRoute:                   {Requests: 300, Per: time.Minute},
+	"POST /users":                  {Requests: 5, Per: time.Hour},
+	"POST /chats":                  {Requests: 10, Per: time.Minute},
+	"POST /chats/{id}/messages":    {Requests: 30, Per: time.Minute, Burst: 10},
+	"POST /chats/{id}/attachments": {Requests: 10, Per: time.Minute},
+	"GET /chats/{id}/export":       {Requests: 10, Per: time.Minute},
+	"POST /chats/{id}/exports":     {Requests: 10, Per: time.Hour},
+	"POST /invites/{token}/accept": {Requests: 10, Per: time.Minute},
+}
+
+// Config selects the limiter backend and the per-route limits. Routes are
+// keyed by their mux pattern, e.g. "POST /chats/{id}/messages".
+type Config struct {
+	Backend string
+	Routes  map[string]Limit
+}
+
+// GetConfig reads RATE_LIMIT_BACKEND and RATE_LIMITS. RATE_LIMITS is a
+// comma-separated list of "<pattern>=<requests>/<period>[:<burst>]", e.g.
+// "POST /chats/{id}/messages=30/1m:10,default=300/1m". Invalid entries are
+// logged and skipped.
+func GetConfig(log *logrus.Logger) *Config {
+	log.Info("Getting rate limit config from env")
+
+	cfg := Config{
+		Backend: os.Getenv("RATE_LIMIT_BACKEND"),
+		Routes:  DefaultRoutes,
+	}
+
+	if cfg.Backend == "" {
+		cfg.Backend = BackendMemory
+	}
+
+	raw := os.Getenv("RATE_LIMITS")
+	if raw == "" {
+		return &cfg
+	}
+
+	cfg.Routes = make(map[string]Limit)
+	for entry := range strings.SplitSeq(raw, ",") {
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			log.Warnf("Ignoring rate limit %q: expected <pattern>=<limit>", entry)
+			continue
+		}
+
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			log.WithError(err).Warnf("Ignoring rate limit for %q", route)
+			continue
+		}
+
+		cfg.Routes[strings.TrimSpace(route)] = limit
+	}
+
+	return &cfg
+}
+
+// ParseLimit parses "<requests>/<period>[:<burst>]", e.g. "30/1m:10".
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+
+	rate, burstStr, hasBurst := strings.Cut(spec, ":")
+	requestsStr, perStr, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected <requests>/<period>", spec)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("invalid request count in %q", spec)
+	}
+
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in %q", spec)
+	}
+
+	limit := Limit{Requests: requests, Per: per}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstStr)
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("invalid burst in %q", spec)
+		}
+	}
+
+	return limit, nil
+}
+
+// NewLimiter builds the limiter selected by cfg.Backend.
+func NewLimiter(log *logrus.Logger, cfg *Config, db *gorm.DB) (Limiter, error) {
+	switch cfg.Backend {
+	case BackendMemory:
+		log.Info("Using in-memory rate limiter")
+		return NewMemoryLimiter(), nil
+	case BackendPostgres:
+		log.Info("Using Postgres rate limiter")
+		return NewPostgresLimiter(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,24 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("30/1m:10")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 30, Per: time.Minute, Burst: 10}, limit)
+
+	limit, err = ParseLimit(" 5/1h ")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 5, Per: time.Hour}, limit)
+
+	for _, spec := range []string{"", "30", "0/1m", "30/x", "30/0s", "30/1m:0", "30/1m:x"} {
+		_, err := ParseLimit(spec)
+		assert.Error(t, err, spec)
+	}
+}
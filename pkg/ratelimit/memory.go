@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memorySweepEvery = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryLimiter keeps buckets in process memory. It is exact for a single
+// instance; each instance of a multi-instance deployment counts separately.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.burst(), last: now}
+		l.buckets[key] = b
+	}
+
+	var result Result
+	b.tokens, result = take(limit, b.tokens, b.last, now)
+	b.last = now
+	b.limit = limit
+
+	l.calls++
+	if l.calls%memorySweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely; they are
+// indistinguishable from fresh ones.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		full := b.tokens + now.Sub(b.last).Seconds()*b.limit.rate()
+		if full >= b.limit.burst() {
+			delete(l.buckets, key)
+		}
+	}
+}
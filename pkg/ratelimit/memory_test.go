@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 2, Per: time.Second}
+	ctx := context.Background()
+
+	for i := range 2 {
+		result, err := limiter.Allow(ctx, "client", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d", i)
+	}
+
+	result, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	other, err := limiter.Allow(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+
+	result, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	postgresSweepEvery = 1000
+	postgresIdleAfter  = 24 * time.Hour
+)
+
+type bucketRow struct {
+	Key       string `gorm:"primaryKey"`
+	Tokens    float64
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func (bucketRow) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresLimiter keeps buckets in the rate_limit_buckets table so that all
+// instances of a deployment share them. Each call locks a single row.
+type PostgresLimiter struct {
+	db    *gorm.DB
+	calls atomic.Uint64
+	now   func() time.Time
+}
+
+func NewPostgresLimiter(db *gorm.DB) *PostgresLimiter {
+	return &PostgresLimiter{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (l *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	var result Result
+
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := l.now()
+
+		fresh := bucketRow{Key: key, Tokens: limit.burst(), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+			return err
+		}
+
+		var row bucketRow
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).
+			First(&row).Error; err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, result = take(limit, row.Tokens, row.UpdatedAt, now)
+
+		return tx.Model(&bucketRow{}).
+			Where("key = ?", key).
+			Updates(map[string]any{"tokens": tokens, "updated_at": now}).Error
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	if l.calls.Add(1)%postgresSweepEvery == 0 {
+		l.sweep(ctx)
+	}
+
+	return result, nil
+}
+
+// sweep deletes buckets that have not been touched for a day. Limits are far
+// shorter than that, so those buckets are full anyway.
+func (l *PostgresLimiter) sweep(ctx context.Context) {
+	l.db.WithContext(ctx).
+		Where("updated_at < ?", l.now().Add(-postgresIdleAfter)).
+		Delete(&bucketRow{})
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it refills Requests tokens every Per and
+// holds at most Burst of them. A zero Burst means Burst == Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take applies one request to a bucket holding tokens as of last and returns
+// the new token count together with the verdict.
+func take(limit Limit, tokens float64, last, now time.Time) (float64, Result) {
+	burst := limit.burst()
+
+	elapsed := now.Sub(last).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed*limit.rate())
+	}
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+
+	wait := time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	return tokens, Result{Allowed: false, RetryAfter: wait}
+}